@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"io"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/core"
+	"github.com/spf13/cobra"
+)
+
+// cancelCmd represents the cancel command.
+var cancelCmd = &cobra.Command{
+	Use:   "cancel [<query-execution-id>...]",
+	Short: "Stops running query executions",
+	Long: `Stops the query executions with the given IDs, or all the queued or running ones among the latest
+query executions with --all-running flag, which can be narrowed down with --match flag.
+The query executions to stop are listed and confirmed before stopping them unless --yes flag is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCancel(newRetryClient(config), config, args, cancelAllRunning, cancelMatch, cancelYes, stdout)
+	},
+	Example: `  # Stop a query execution
+  $ athenai cancel 5ee2b5a2-7e72-4b03-8a1d-1e2cb6f2e1e4
+
+  # Stop all the running query executions on cloudfront_logs without confirmation
+  $ athenai cancel --all-running --match 'FROM cloudfront_logs' --yes`,
+}
+
+var (
+	cancelAllRunning bool
+	cancelMatch      string
+	cancelYes        bool
+)
+
+func init() {
+	RootCmd.AddCommand(cancelCmd)
+
+	// Define flags
+	f := cancelCmd.Flags()
+	f.BoolVar(&cancelAllRunning, "all-running", false, "Stop all the queued or running query executions among the latest ones")
+	f.StringVar(&cancelMatch, "match", "", "The regular expression matched against queries to stop with --all-running")
+	f.UintVarP(&config.Count, "count", "c", 50, "The maximum possible number of the latest query executions to look for running ones with --all-running")
+	f.BoolVarP(&cancelYes, "yes", "y", false, "Stop query executions without confirmation")
+}
+
+func runCancel(client athenaiface.AthenaAPI, cfg *core.Config, ids []string, allRunning bool, match string, yes bool, out io.Writer) error {
+	switch {
+	case len(ids) > 0 && allRunning:
+		return errors.New("query execution IDs and --all-running flag cannot be specified at the same time")
+	case len(ids) == 0 && !allRunning:
+		return errors.New("specify query execution IDs to stop or --all-running flag")
+	case match != "" && !allRunning:
+		return errors.New("--match flag can only be specified with --all-running flag")
+	}
+
+	var re *regexp.Regexp
+	if match != "" {
+		var err error
+		if re, err = regexp.Compile(match); err != nil {
+			return errors.Wrap(err, "invalid --match")
+		}
+	}
+
+	return core.New(client, cfg, out).CancelQueryExecutions(ids, re, yes)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCancel(t *testing.T) {
+	r := &stub.Result{ID: "TestRunCancel", Query: "SELECT * FROM cloudfront_logs", Running: true, RunningTime: time.Minute}
+	client := stub.NewClient(r)
+
+	var out bytes.Buffer
+	err := runCancel(client, &core.Config{Silent: true}, nil, true, "cloudfront", true, &out)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Stopped TestRunCancel")
+	assert.Equal(t, stub.Cancelled, r.FinalState)
+}
+
+func TestRunCancelError(t *testing.T) {
+	tests := []struct {
+		ids        []string
+		allRunning bool
+		match      string
+		wantErr    string
+	}{
+		{wantErr: "specify query execution IDs to stop or --all-running flag"},
+		{ids: []string{"id"}, allRunning: true, wantErr: "cannot be specified at the same time"},
+		{ids: []string{"id"}, match: "SELECT", wantErr: "--match flag can only be specified with --all-running flag"},
+		{allRunning: true, match: "(", wantErr: "invalid --match"},
+	}
+
+	for _, tt := range tests {
+		err := runCancel(stub.NewClient(), &core.Config{Silent: true}, tt.ids, tt.allRunning, tt.match, true, &bytes.Buffer{})
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/print"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command.
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Lists past query executions",
+	Long: `Lists past query executions with their IDs, submission times, states, run times, data scanned,
+databases and output locations, from the newest to the oldest, without user interaction.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runHistory(newRetryClient(config), config, historySince, historyStates, time.Now(), stdout)
+	},
+	Example: `  # List the latest 50 query executions
+  $ athenai history
+
+  # List the failed query executions in the last 7 days as JSON
+  $ athenai history --since 168h --state failed --format json
+
+  # List all of the query executions in CSV format (may be very slow)
+  $ athenai history --count 0 --format csv`,
+}
+
+var (
+	historySince  string
+	historyStates []string
+)
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+
+	// Define flags
+	f := historyCmd.Flags()
+	f.StringVarP(&config.Format, "format", "f", "table", "The formatting style for command output. Valid values: "+strings.Join(print.Formats, ", "))
+	f.UintVarP(&config.Count, "count", "c", 50, "The maximum possible number of query executions to list. Zero means no limit")
+	f.StringVar(&historySince, "since", "", `List query executions submitted at or after the time, e.g. "2017-07-01", "2017-07-01T09:00:00+09:00" or "24h" (24 hours ago)`)
+	f.StringSliceVar(&historyStates, "state", []string{"all"}, "The states of query executions to list. Valid values: "+strings.Join(core.StateNames, ", "))
+}
+
+func runHistory(client athenaiface.AthenaAPI, cfg *core.Config, since string, stateNames []string, now time.Time, out io.Writer) error {
+	if !print.IsValid(cfg.Format) {
+		return errors.Errorf("invalid --format %q. Valid values: %s", cfg.Format, strings.Join(print.Formats, ", "))
+	}
+	t, err := parseTime(since, now)
+	if err != nil {
+		return errors.Wrap(err, "invalid --since")
+	}
+	states, err := core.ParseStates(stateNames)
+	if err != nil {
+		return errors.Wrap(err, "invalid --state")
+	}
+
+	return core.New(client, cfg, out).WithStates(states).ShowHistory(t)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunHistory(t *testing.T) {
+	client := stub.NewClient(&stub.Result{
+		ID:         "TestRunHistory",
+		Query:      "SHOW DATABASES",
+		SubmitTime: time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC),
+	})
+	now := time.Date(2017, 7, 2, 0, 0, 0, 0, time.UTC)
+
+	var out bytes.Buffer
+	cfg := &core.Config{Format: "csv", Silent: true}
+	err := runHistory(client, cfg, "48h", []string{"succeeded"}, now, &out)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "TestRunHistory,2017-07-01T00:00:00Z,SUCCEEDED")
+
+	out.Reset()
+	err = runHistory(client, cfg, "12h", []string{"all"}, now, &out)
+	assert.NoError(t, err)
+	assert.NotContains(t, out.String(), "TestRunHistory")
+}
+
+func TestRunHistoryError(t *testing.T) {
+	tests := []struct {
+		format  string
+		since   string
+		states  []string
+		wantErr string
+	}{
+		{format: "xml", states: []string{"all"}, wantErr: "invalid --format"},
+		{format: "table", since: "yesterday", states: []string{"all"}, wantErr: "invalid --since"},
+		{format: "table", states: []string{"done"}, wantErr: "invalid --state"},
+	}
+
+	for _, tt := range tests {
+		cfg := &core.Config{Format: tt.format, Silent: true}
+		err := runHistory(stub.NewClient(), cfg, tt.since, tt.states, time.Now(), &bytes.Buffer{})
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+	}
+}
@@ -5,15 +5,21 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
 	"github.com/pkg/errors"
 	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/exec"
 	"github.com/spf13/cobra"
 )
 
+// fetchUsage is the usage of --fetch flag shared by the commands which show query results.
+const fetchUsage = `How to fetch query results: "auto" (directly from the output location in S3 if possible, otherwise with GetQueryResults API), "s3" or "api"`
+
 var (
 	showVersion bool
 	cfgFile     string
@@ -82,6 +88,11 @@ func init() {
 	f.StringVarP(&config.Profile, "profile", "p", "default", "Use a specific profile from your credential file")
 	f.StringVarP(&config.Region, "region", "r", "us-east-1", "The AWS region to use")
 	f.StringVarP(&config.Output, "output", "o", "", "Output query results to a given file path instead of stdout")
+	f.IntVar(&config.RetryMaxAttempts, "retry-max-attempts", exec.DefaultRetryPolicy.MaxAttempts, "The maximum number of attempts of each API call, including the first one. To disable retries, specify 1")
+	f.DurationVar(&config.RetryInterval, "retry-interval", exec.DefaultRetryPolicy.Backoff.Initial, "The initial interval between retries of API calls, which is doubled every time")
+	f.DurationVar(&config.RetryMaxInterval, "retry-max-interval", exec.DefaultRetryPolicy.Backoff.Max, "The maximum interval between retries of API calls")
+	f.StringVar(&config.S3Endpoint, "s3-endpoint", "", "The endpoint URL of Amazon S3 or an S3-compatible storage to fetch query results from, e.g. http://localhost:9000")
+	f.StringSliceVar(&config.RetryCodes, "retry-codes", exec.DefaultRetryableCodes, "The error codes of API calls to retry")
 
 	// Define local flags
 	RootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Show version")
@@ -117,16 +128,46 @@ func initConfig(cfg *core.Config, cfgFile string, cmd *cobra.Command, rawArgs []
 	cmd.ParseFlags(rawArgs)
 }
 
-// newClient creates a new Athena client.
-func newClient(cfg *core.Config) *athena.Athena {
-	log.Printf("Creating Athena client: region = %s, profile = %s\n", cfg.Region, cfg.Profile)
+// newSession creates a new AWS session.
+func newSession(cfg *core.Config) *session.Session {
 	c := aws.NewConfig().WithRegion(cfg.Region)
 	if cfg.Debug {
 		log.Println("Debug mode is enabled. Setting log level for AWS SDK to debug")
 		c = c.WithLogLevel(aws.LogDebugWithHTTPBody | aws.LogDebugWithRequestErrors)
 	}
-	return athena.New(session.Must(session.NewSessionWithOptions(session.Options{
+	return session.Must(session.NewSessionWithOptions(session.Options{
 		Config:  *c,
 		Profile: cfg.Profile,
-	})))
+	}))
+}
+
+// newClient creates a new Athena client.
+func newClient(cfg *core.Config) *athena.Athena {
+	log.Printf("Creating Athena client: region = %s, profile = %s\n", cfg.Region, cfg.Profile)
+	// Disable retries by AWS SDK since newRetryClient retries API calls
+	return athena.New(newSession(cfg), aws.NewConfig().WithMaxRetries(0))
+}
+
+// newS3Client creates a new client to fetch query results from Amazon S3.
+// It returns nil if they are fetched only with GetQueryResults API.
+func newS3Client(cfg *core.Config) (exec.ObjectGetter, error) {
+	if !exec.IsValidFetchMode(cfg.Fetch) {
+		return nil, errors.Errorf("invalid --fetch %q. Valid values: %s", cfg.Fetch, strings.Join(exec.FetchModes, ", "))
+	}
+	if cfg.Fetch == exec.FetchAPI {
+		return nil, nil
+	}
+	log.Printf("Creating S3 client: region = %s, profile = %s, endpoint = %q\n", cfg.Region, cfg.Profile, cfg.S3Endpoint)
+	c, err := exec.NewS3Client(newSession(cfg), cfg.S3Endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create S3 client")
+	}
+	return c, nil
+}
+
+// newRetryClient creates a new Athena client which retries throttled and transient API errors.
+func newRetryClient(cfg *core.Config) athenaiface.AthenaAPI {
+	policy := cfg.RetryPolicy()
+	log.Printf("Retry policy: %#v\n", policy)
+	return exec.NewRetryClient(newClient(cfg), policy)
 }
@@ -177,5 +177,30 @@ func TestNewClient(t *testing.T) {
 
 		assert.Equal(t, tt.cfg.Region, *client.Client.Config.Region)
 		assert.Equal(t, tt.logLevel, *client.Client.Config.LogLevel)
+		assert.Equal(t, 0, *client.Client.Config.MaxRetries)
+	}
+}
+
+func TestNewS3Client(t *testing.T) {
+	tests := []struct {
+		cfg     *core.Config
+		wantNil bool
+		wantErr bool
+	}{
+		{cfg: &core.Config{Region: "us-east-1", Fetch: "auto"}},
+		{cfg: &core.Config{Region: "us-east-1", Fetch: "s3", S3Endpoint: "http://localhost:9000"}},
+		{cfg: &core.Config{Region: "us-east-1", Fetch: "api"}, wantNil: true},
+		{cfg: &core.Config{Region: "us-east-1", Fetch: "http"}, wantErr: true},
+		{cfg: &core.Config{Region: "us-east-1", Fetch: "s3", S3Endpoint: "localhost:9000"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		client, err := newS3Client(tt.cfg)
+		if tt.wantErr {
+			assert.Error(t, err, "Config: %#v", tt.cfg)
+			continue
+		}
+		assert.NoError(t, err, "Config: %#v", tt.cfg)
+		assert.Equal(t, tt.wantNil, client == nil, "Config: %#v", tt.cfg)
 	}
 }
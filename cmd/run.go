@@ -7,10 +7,15 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
 	"github.com/pkg/errors"
 	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/exec"
+	"github.com/skatsuta/athenai/export"
+	"github.com/skatsuta/athenai/params"
+	"github.com/skatsuta/athenai/print"
 	"github.com/spf13/cobra"
 )
 
@@ -22,7 +27,17 @@ var runCmd = &cobra.Command{
 from command line arguments or from an SQL file. Athenai waits for the query executions and shows
 the query results in table or CSV format once the executions have finished.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runRun(cmd, args, newClient(config), config, os.Stdin, stdout)
+		if ordered {
+			if cmd.Flags().Changed("stream") && config.Stream {
+				return errors.New("--stream and --ordered flags cannot be specified at the same time")
+			}
+			config.Stream = false
+		}
+		s3Client, err := newS3Client(config)
+		if err != nil {
+			return err
+		}
+		return runRun(cmd, args, newRetryClient(config), s3Client, config, os.Stdin, stdout)
 	},
 	Example: `  # Start interactive (REPL) mode
   $ atheani run
@@ -40,8 +55,32 @@ the query results in table or CSV format once the executions have finished.`,
   # Run DDL statements
   $ athenai run "CREATE DATABASE IF NOT EXISTS testdb;"
 
+  # Print each result as soon as its query execution completes
+  $ athenai run --stream "SELECT * FROM large_table LIMIT 1000; SHOW TABLES;"
+
+  # Run DDL statements and queries depending on them (DDL statements wait for prior statements and vice versa)
+  $ athenai run "CREATE DATABASE testdb; CREATE TABLE testdb.testtable (...); SELECT * FROM testdb.testtable;"
+
   # Run multiple statements sequentially
-  $ athenai run --concurrent 1 "CREATE DATABASE testdb; CREATE TABLE testdb.testtable (...); SELECT * FROM testdb.testtable;"
+  $ athenai run --concurrent 1 "SELECT * FROM testdb.testtable LIMIT 5; SELECT count(*) FROM testdb.testtable;"
+
+  # Stop query executions which do not complete within 10 minutes
+  $ athenai run --timeout 10m "SELECT * FROM large_table;"
+
+  # Stop queries once they have scanned more than 100 GB
+  $ athenai run --max-scan 100GB "SELECT * FROM large_table;"
+
+  # Replace placeholders in queries with typed variables
+  $ athenai run --var table:ident=elb_logs --var start:date=2017-07-01 "SELECT * FROM ${table} WHERE day >= :start;"
+
+  # Read variables from a YAML file
+  $ athenai run --vars-file vars.yaml file://report.sql
+
+  # Resubmit queries up to 2 times if they have failed for transient reasons such as "Query exhausted resources"
+  $ athenai run --retry-failed 2 "SELECT * FROM large_table;"
+
+  # Fetch large results directly from the S3 location instead of paging through GetQueryResults API
+  $ athenai run --fetch s3 --format csv "SELECT * FROM large_table;"
 
   # Specify the database and S3 location to use
   $ athenai run --database sampledb --location s3://sample-bucket/ "SELECT date, time, requestip FROM cloudfront_logs LIMIT 5;"
@@ -52,10 +91,24 @@ the query results in table or CSV format once the executions have finished.`,
   # Print results in CSV format
   $ athenai run --format csv "SELECT date, time, requestip FROM cloudfront_logs LIMIT 5;"
 
+  # Export the rows of each result to its own Parquet file in the out directory, with out/manifest.json listing them
+  $ athenai run --export out/ --export-format parquet "SELECT * FROM cloudfront_logs; SELECT * FROM elb_logs;"
+
+  # Export the rows to gzip-compressed CSV files named after the index of each statement
+  $ athenai run --export-file "out/q{index}.csv.gz" file://report.sql
+
+  # Print each row as a vertical record, or only when the table is wider than the terminal with auto
+  $ athenai run --format vertical "SELECT * FROM cloudfront_logs LIMIT 5;"
+
+  # Print results as a Markdown table to paste into an issue, or in html or tsv format
+  $ athenai run --format markdown "SELECT date, time, requestip FROM cloudfront_logs LIMIT 5;"
+
   # Output (save) results to a file
   $ athenai run --output /path/to/file "SELECT date, time, requestip FROM cloudfront_logs LIMIT 5;"`,
 }
 
+var ordered bool
+
 type stater interface {
 	Stat() (os.FileInfo, error)
 }
@@ -75,8 +128,26 @@ func init() {
 	f.StringVarP(&config.Location, "location", "l", "", `The location in S3 where query results are stored. For example, "s3://bucket_name/prefix/"`)
 	f.StringVarP(&config.Encrypt, "encrypt", "e", "", "The encryption type for encrypting query results in Amazon S3. Valid values: SSE_S3, SSE_KMS, CSE_KMS")
 	f.StringVarP(&config.KMS, "kms", "k", "", `The KMS key ARN or ID used when "SSE_KMS" or "CSE_KMS" is specified in the encryption type`)
-	f.StringVarP(&config.Format, "format", "f", "table", "The formatting style for command output. Valid values: table, csv")
+	f.StringVarP(&config.Format, "format", "f", "table", "The formatting style for command output. Valid values: "+strings.Join(print.Formats, ", "))
 	f.UintVarP(&config.Concurrent, "concurrent", "c", 5, "The maximum number of concurrent query executions at a time. To run multiple queries sequentially, specify 1")
+	f.BoolVar(&config.Stream, "stream", false, "Print each result as soon as its query execution completes, with the index of its statement")
+	f.BoolVar(&ordered, "ordered", false, `Print results in the order of the statements (default). Overrides "stream" setting in config file`)
+	f.DurationVar(&config.Timeout, "timeout", 0, "The maximum time to wait for each query execution before stopping it, e.g. 30s or 10m. Zero means no timeout")
+	f.IntVar(&config.RetryFailed, "retry-failed", 0, "The maximum number of times to resubmit each query whose execution has failed for a transient reason")
+	f.StringSliceVar(&config.RetryFailedPatterns, "retry-failed-patterns", exec.DefaultRetryFailedPatterns, "The regular expressions matched case-insensitively against the reasons of failed query executions to resubmit")
+	f.DurationVar(&config.PollInterval, "poll-interval", exec.DefaultBackoff.Initial, "The initial interval to poll the state of query executions")
+	f.DurationVar(&config.PollMaxInterval, "poll-max-interval", exec.DefaultBackoff.Max, "The maximum interval to poll the state of query executions")
+	f.Float64Var(&config.PollMultiplier, "poll-multiplier", exec.DefaultBackoff.Multiplier, "The factor by which the poll interval is multiplied every time. 1 means a constant interval")
+	f.Float64Var(&config.PollJitter, "poll-jitter", exec.DefaultBackoff.Jitter, "The fraction between 0 and 1 by which each poll interval is randomized")
+	f.Float64Var(&config.PricePerTB, "price-per-tb", print.DefaultPricePerTB, "The price in US dollars per TB of data scanned to estimate costs of queries. Zero hides costs")
+	f.StringVar(&config.MaxScan, "max-scan", "", `The maximum data for each query execution to scan before stopping it, e.g. "10GB" or "1TB"`)
+	f.StringArrayVar(&config.Vars, "var", nil, `The variable to replace placeholders ${name} and :name in queries with, in "name=value" or "name:type=value" format. Valid types: `+strings.Join(params.Types, ", ")+". Can be specified multiple times")
+	f.StringVar(&config.VarsFile, "vars-file", "", "The YAML file of variables to replace placeholders in queries with. Variables given with --var override them")
+	f.StringVar(&config.Fetch, "fetch", exec.FetchAuto, fetchUsage)
+	f.StringVar(&config.Export, "export", "", "The directory to export the rows of each result to its own file in instead of printing them, along with a manifest.json listing the files")
+	f.StringVar(&config.ExportFile, "export-file", "", "The path of each exported file, in which {index} is replaced with the index of the statement and {id} with the query execution ID, e.g. out/q{index}.parquet")
+	f.StringVar(&config.ExportFormat, "export-format", "", "The format of exported files. Guessed from the extension of --export-file, and defaults to csv.gz. Valid values: "+strings.Join(export.Formats, ", "))
+	f.DurationVar(&config.CatalogTTL, "catalog-ttl", 24*time.Hour, "The duration for which the cache of databases, tables and columns used for completion in REPL is fresh")
 }
 
 func validateConfigForRun(cfg *core.Config) error {
@@ -133,12 +204,29 @@ func appendStdinData(args []string, stdin io.Reader) []string {
 	return append(args, data)
 }
 
-func runRun(cmd *cobra.Command, args []string, client athenaiface.AthenaAPI, cfg *core.Config, stdin statReader, out io.Writer) (err error) {
+func runRun(cmd *cobra.Command, args []string, client athenaiface.AthenaAPI, s3Client exec.ObjectGetter, cfg *core.Config, stdin statReader, out io.Writer) (err error) {
 	if e := validateConfigForRun(cfg); e != nil {
 		return errors.Wrap(e, "validation for run command failed")
 	}
 
-	a := core.New(client, cfg, out)
+	policy, err := cfg.RetryFailedPolicy()
+	if err != nil {
+		return errors.Wrap(err, "invalid retry-failed-patterns setting")
+	}
+	maxScan, err := cfg.MaxScanBytes()
+	if err != nil {
+		return errors.Wrap(err, "invalid max-scan setting")
+	}
+	vars, err := cfg.Variables()
+	if err != nil {
+		return errors.Wrap(err, "invalid variables")
+	}
+	exporter, err := cfg.Exporter()
+	if err != nil {
+		return errors.Wrap(err, "invalid export settings")
+	}
+	a := core.New(client, cfg, out).WithRetryFailed(policy).WithMaxScan(maxScan).WithVars(vars).WithS3(s3Client).
+		WithExporter(exporter)
 
 	// Read data on stdin and add it to args
 	if hasDataOn(stdin) {
@@ -150,8 +238,7 @@ func runRun(cmd *cobra.Command, args []string, client athenaiface.AthenaAPI, cfg
 	l := len(args)
 	if l > 0 {
 		log.Printf("%d args provided: %#v\n", l, args)
-		a.RunQuery(args...)
-		return nil
+		return a.RunQuery(args...)
 	}
 
 	// Run REPL mode
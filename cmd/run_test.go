@@ -192,7 +192,7 @@ func TestRunRun(t *testing.T) {
 			ResultSet:    tt.rs,
 		})
 		var out bytes.Buffer
-		err := runRun(runCmd, tt.args, client, cfg, tt.stdin, &out)
+		err := runRun(runCmd, tt.args, client, nil, cfg, tt.stdin, &out)
 		got := out.String()
 
 		assert.NoError(t, err, "Args: %#v, Id: %#v, ResultSet: %#v", tt.args, tt.id, tt.rs)
@@ -258,7 +258,7 @@ func TestRunRunOutputFile(t *testing.T) {
 			Location: "s3://bucket/",
 			Output:   tmpFile.Name(),
 		}
-		err = runRun(runCmd, tt.args, client, cfg, os.Stdin, tmpFile)
+		err = runRun(runCmd, tt.args, client, nil, cfg, os.Stdin, tmpFile)
 		assert.NoError(t, err)
 
 		b, err := ioutil.ReadFile(tmpFile.Name()) // Somehow ioutil.ReadAll does not work
@@ -275,6 +275,17 @@ func TestRunRunOutputFile(t *testing.T) {
 	}
 }
 
+func TestRunRunUndefinedVarsError(t *testing.T) {
+	var out bytes.Buffer
+	client := stub.NewClient(&stub.Result{ID: "TestRunRunUndefinedVarsError"})
+	cfg := &core.Config{Location: "s3://bucket/", Vars: []string{"day:date=2017-07-01"}}
+	err := runRun(runCmd, []string{"SELECT * FROM elb_logs WHERE day = :day AND status = :status"}, client, nil, cfg, &stubStatReader{}, &out)
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "undefined variables: status")
+	}
+}
+
 func TestRunRunValidationError(t *testing.T) {
 	tests := []struct {
 		id   string
@@ -291,12 +302,32 @@ func TestRunRunValidationError(t *testing.T) {
 			cfg:  &core.Config{Location: "s3://bucket/", Encrypt: "SSE_KMS"},
 			want: "KMS key",
 		},
+		{
+			id:   "TestRunRunInvalidVarError",
+			cfg:  &core.Config{Location: "s3://bucket/", Vars: []string{"day:date=yesterday"}},
+			want: "invalid date",
+		},
+		{
+			id:   "TestRunRunNoVarsFileError",
+			cfg:  &core.Config{Location: "s3://bucket/", VarsFile: "/path/to/nonexistent.yaml"},
+			want: "variables file",
+		},
+		{
+			id:   "TestRunRunInvalidExportFormatError",
+			cfg:  &core.Config{Location: "s3://bucket/", Export: "out", ExportFormat: "xml"},
+			want: "invalid export settings",
+		},
+		{
+			id:   "TestRunRunExportConflictError",
+			cfg:  &core.Config{Location: "s3://bucket/", Export: "out", ExportFile: "out/{index}.csv.gz"},
+			want: "cannot be specified at the same time",
+		},
 	}
 
 	for _, tt := range tests {
 		var out bytes.Buffer
 		client := stub.NewClient(&stub.Result{ID: tt.id})
-		err := runRun(runCmd, []string{}, client, tt.cfg, os.Stdin, &out)
+		err := runRun(runCmd, []string{}, client, nil, tt.cfg, os.Stdin, &out)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), tt.want, "Id: %#v, Config: %#v", tt.id, tt.cfg)
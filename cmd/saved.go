@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/exec"
+	"github.com/skatsuta/athenai/filter"
+	"github.com/skatsuta/athenai/params"
+	"github.com/skatsuta/athenai/print"
+	"github.com/spf13/cobra"
+)
+
+// filePrefix is a prefix of arguments which are paths to files to read queries from.
+const filePrefix = "file://"
+
+// savedCmd represents the saved command.
+var savedCmd = &cobra.Command{
+	Use:   "saved",
+	Short: "Manages saved queries",
+	Long: `Manages saved queries, which are named queries shared in Amazon Athena, e.g. by your team.
+Saved queries can be referred to by their names or IDs.`,
+	Example: `  # List saved queries
+  $ athenai saved list
+
+  # Save a query
+  $ athenai saved create daily_requests --database sampledb "SELECT date, count(*) FROM cloudfront_logs GROUP BY date"
+
+  # Print a saved query
+  $ athenai saved get daily_requests
+
+  # Run a saved query on its database
+  $ athenai saved run daily_requests
+
+  # Run a saved query with placeholders replaced with variables
+  $ athenai saved run requests_by_day --var day:date=2017-07-01
+
+  # Select saved queries to run interactively
+  $ athenai saved run
+
+  # Delete a saved query
+  $ athenai saved delete daily_requests`,
+}
+
+// savedListCmd represents the saved list command.
+var savedListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists saved queries",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSavedList(newRetryClient(config), config, stdout)
+	},
+}
+
+// savedGetCmd represents the saved get command.
+var savedGetCmd = &cobra.Command{
+	Use:   "get <name|id>",
+	Short: "Prints a saved query",
+	Long: `Prints a saved query with its name, database and description in SQL comments,
+so that the output can be run with the run command as it is.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSavedGet(newRetryClient(config), config, args, stdout)
+	},
+}
+
+// savedCreateCmd represents the saved create command.
+var savedCreateCmd = &cobra.Command{
+	Use:   "create <name> [query]",
+	Short: "Saves a query",
+	Long: `Saves a query as a new saved query with a name and the database to run it on.
+The query can be given as an argument, from a file with file:// prefix or via stdin.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSavedCreate(newRetryClient(config), config, args, savedDescription, os.Stdin, stdout)
+	},
+}
+
+// savedDeleteCmd represents the saved delete command.
+var savedDeleteCmd = &cobra.Command{
+	Use:   "delete <name|id>",
+	Short: "Deletes a saved query",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSavedDelete(newRetryClient(config), config, args, savedYes, stdout)
+	},
+}
+
+// savedRunCmd represents the saved run command.
+var savedRunCmd = &cobra.Command{
+	Use:   "run [name|id]",
+	Short: "Runs a saved query",
+	Long: `Runs a saved query on its database in the same way as the run command.
+If no name is given, saved queries to run are selected interactively in the same way as
+the show command.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s3Client, err := newS3Client(config)
+		if err != nil {
+			return err
+		}
+		return runSavedRun(newRetryClient(config), s3Client, config, args, stdout)
+	},
+}
+
+var (
+	savedDescription string
+	savedYes         bool
+)
+
+func init() {
+	RootCmd.AddCommand(savedCmd)
+	savedCmd.AddCommand(savedListCmd, savedGetCmd, savedCreateCmd, savedDeleteCmd, savedRunCmd)
+
+	// Define flags
+	formatUsage := "The formatting style for command output. Valid values: " + strings.Join(print.Formats, ", ")
+	savedListCmd.Flags().StringVarP(&config.Format, "format", "f", "table", formatUsage)
+	savedGetCmd.Flags().StringVarP(&config.Format, "format", "f", "table", formatUsage)
+
+	f := savedCreateCmd.Flags()
+	f.StringVarP(&config.Database, "database", "d", "", "The name of the database to run the query on")
+	f.StringVar(&savedDescription, "description", "", "The description of the query")
+
+	savedDeleteCmd.Flags().BoolVarP(&savedYes, "yes", "y", false, "Delete the saved query without confirmation")
+
+	f = savedRunCmd.Flags()
+	f.StringVarP(&config.Location, "location", "l", "", `The location in S3 where query results are stored. For example, "s3://bucket_name/prefix/"`)
+	f.StringVarP(&config.Encrypt, "encrypt", "e", "", "The encryption type for encrypting query results in Amazon S3. Valid values: SSE_S3, SSE_KMS, CSE_KMS")
+	f.StringVarP(&config.KMS, "kms", "k", "", `The KMS key ARN or ID used when "SSE_KMS" or "CSE_KMS" is specified in the encryption type`)
+	f.StringVarP(&config.Format, "format", "f", "table", formatUsage)
+	f.DurationVar(&config.Timeout, "timeout", 0, "The maximum time to wait for each query execution before stopping it, e.g. 30s or 10m. Zero means no timeout")
+	f.Float64Var(&config.PricePerTB, "price-per-tb", print.DefaultPricePerTB, "The price in US dollars per TB of data scanned to estimate costs of queries. Zero hides costs")
+	f.StringVar(&config.MaxScan, "max-scan", "", `The maximum data for each query execution to scan before stopping it, e.g. "10GB" or "1TB"`)
+	f.StringArrayVar(&config.Vars, "var", nil, `The variable to replace placeholders ${name} and :name in queries with, in "name=value" or "name:type=value" format. Valid types: `+strings.Join(params.Types, ", ")+". Can be specified multiple times")
+	f.StringVar(&config.VarsFile, "vars-file", "", "The YAML file of variables to replace placeholders in queries with. Variables given with --var override them")
+	f.StringVar(&config.Fetch, "fetch", exec.FetchAuto, fetchUsage)
+	f.StringVar(&config.Finder, "finder", filter.FinderPeco, `The finder to select saved queries interactively: "peco" (built-in), "fzf", "sk" or any command reading lines from stdin and writing selected ones to stdout`)
+}
+
+func validateFormat(format string) error {
+	if !print.IsValid(format) {
+		return errors.Errorf("invalid --format %q. Valid values: %s", format, strings.Join(print.Formats, ", "))
+	}
+	return nil
+}
+
+func runSavedList(client athenaiface.AthenaAPI, cfg *core.Config, out io.Writer) error {
+	if err := validateFormat(cfg.Format); err != nil {
+		return err
+	}
+	return core.New(client, cfg, out).ListSavedQueries()
+}
+
+func runSavedGet(client athenaiface.AthenaAPI, cfg *core.Config, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("specify the name or ID of a saved query")
+	}
+	if err := validateFormat(cfg.Format); err != nil {
+		return err
+	}
+	return core.New(client, cfg, out).GetSavedQuery(args[0])
+}
+
+func runSavedCreate(client athenaiface.AthenaAPI, cfg *core.Config, args []string, description string, stdin statReader, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("specify the name of a saved query")
+	}
+	if cfg.Database == "" {
+		return errors.New("specify the database to run the query on with --database/-d flag or `database` setting in your config file")
+	}
+
+	name, queries := args[0], args[1:]
+	if hasDataOn(stdin) {
+		log.Println("Stdin seems to have some data. Reading it as a query")
+		queries = appendStdinData(queries, stdin)
+	}
+	if len(queries) != 1 {
+		return errors.New("specify exactly one query to save as an argument, from a file or via stdin")
+	}
+	query := queries[0]
+	if path := strings.TrimPrefix(query, filePrefix); path != query {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to read query from file")
+		}
+		query = string(b)
+	}
+	if strings.TrimSpace(query) == "" {
+		return errors.New("query to save is empty")
+	}
+
+	return core.New(client, cfg, out).CreateSavedQuery(name, cfg.Database, description, query)
+}
+
+func runSavedDelete(client athenaiface.AthenaAPI, cfg *core.Config, args []string, yes bool, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("specify the name or ID of a saved query")
+	}
+	return core.New(client, cfg, out).DeleteSavedQuery(args[0], yes)
+}
+
+func runSavedRun(client athenaiface.AthenaAPI, s3Client exec.ObjectGetter, cfg *core.Config, args []string, out io.Writer) error {
+	if len(args) > 1 {
+		return errors.New("specify at most one name or ID of a saved query")
+	}
+	if err := validateConfigForRun(cfg); err != nil {
+		return errors.Wrap(err, "validation for saved run command failed")
+	}
+	maxScan, err := cfg.MaxScanBytes()
+	if err != nil {
+		return errors.Wrap(err, "invalid max-scan setting")
+	}
+	vars, err := cfg.Variables()
+	if err != nil {
+		return errors.Wrap(err, "invalid variables")
+	}
+
+	var nameOrID string
+	if len(args) == 1 {
+		nameOrID = args[0]
+	}
+	return core.New(client, cfg, out).WithMaxScan(maxScan).WithVars(vars).WithS3(s3Client).RunSavedQuery(nameOrID)
+}
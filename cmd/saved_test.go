@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSavedTestClient() *stub.Client {
+	return stub.NewClient(&stub.Result{ID: "TestSaved", Query: "SHOW DATABASES"}).WithNamedQueries(&athena.NamedQuery{
+		NamedQueryId: aws.String("nq-dbs"),
+		Name:         aws.String("databases"),
+		Database:     aws.String("default"),
+		QueryString:  aws.String("SHOW DATABASES"),
+	})
+}
+
+func TestRunSavedList(t *testing.T) {
+	var out bytes.Buffer
+	err := runSavedList(newSavedTestClient(), &core.Config{Format: "csv", Silent: true}, &out)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "databases,default,,nq-dbs,SHOW DATABASES")
+
+	err = runSavedList(newSavedTestClient(), &core.Config{Format: "xml", Silent: true}, &out)
+	assert.Error(t, err)
+}
+
+func TestRunSavedGet(t *testing.T) {
+	var out bytes.Buffer
+	err := runSavedGet(newSavedTestClient(), &core.Config{Format: "table", Silent: true}, []string{"databases"}, &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "-- Name: databases\n-- Database: default\nSHOW DATABASES\n", out.String())
+
+	err = runSavedGet(newSavedTestClient(), &core.Config{Format: "table", Silent: true}, nil, &out)
+	assert.Error(t, err)
+}
+
+func TestRunSavedCreate(t *testing.T) {
+	file, err := ioutil.TempFile("", "TestRunSavedCreate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("SHOW TABLES\n")
+	file.Close()
+
+	tests := []struct {
+		args    []string
+		stdin   statReader
+		want    string
+		wantErr string
+	}{
+		{
+			args:  []string{"tables", "SHOW TABLES"},
+			stdin: &stubStatReader{},
+			want:  `Saved query "tables" has been created`,
+		},
+		{
+			args:  []string{"tables", filePrefix + file.Name()},
+			stdin: &stubStatReader{},
+			want:  `Saved query "tables" has been created`,
+		},
+		{
+			args:  []string{"tables"},
+			stdin: &stubStatReader{Reader: strings.NewReader("SHOW TABLES"), isDataExist: true},
+			want:  `Saved query "tables" has been created`,
+		},
+		{
+			args:    []string{"tables"},
+			stdin:   &stubStatReader{},
+			wantErr: "specify exactly one query",
+		},
+		{
+			args:    []string{"tables", "  "},
+			stdin:   &stubStatReader{},
+			wantErr: "query to save is empty",
+		},
+		{
+			args:    []string{"databases", "SHOW DATABASES"},
+			stdin:   &stubStatReader{},
+			wantErr: "already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		cfg := &core.Config{Database: "sampledb", Silent: true}
+		err := runSavedCreate(newSavedTestClient(), cfg, tt.args, "", tt.stdin, &out)
+
+		if tt.wantErr != "" {
+			if assert.Error(t, err, "Args: %q", tt.args) {
+				assert.Contains(t, err.Error(), tt.wantErr, "Args: %q", tt.args)
+			}
+			continue
+		}
+		assert.NoError(t, err, "Args: %q", tt.args)
+		assert.Contains(t, out.String(), tt.want, "Args: %q", tt.args)
+	}
+
+	err = runSavedCreate(newSavedTestClient(), &core.Config{Silent: true}, []string{"tables", "SHOW TABLES"}, "", &stubStatReader{}, &bytes.Buffer{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "specify the database")
+	}
+}
+
+func TestRunSavedDelete(t *testing.T) {
+	var out bytes.Buffer
+	err := runSavedDelete(newSavedTestClient(), &core.Config{Silent: true}, []string{"databases"}, true, &out)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `Saved query "databases" has been deleted`)
+}
+
+func TestRunSavedRun(t *testing.T) {
+	var out bytes.Buffer
+	cfg := &core.Config{Location: "s3://TestRunSavedRunBucket/", Silent: true}
+	err := runSavedRun(newSavedTestClient(), nil, cfg, []string{"databases"}, &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "default", cfg.Database)
+
+	err = runSavedRun(newSavedTestClient(), nil, &core.Config{Silent: true}, []string{"databases"}, &out)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "validation for saved run command failed")
+	}
+
+	err = runSavedRun(newSavedTestClient(), nil, &core.Config{Location: "s3://TestRunSavedRunBucket/", Vars: []string{"day:date=yesterday"}}, []string{"databases"}, &out)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid variables")
+	}
+}
+
+func TestRunSavedRunVars(t *testing.T) {
+	query := "SELECT * FROM elb_logs WHERE day = DATE '2017-07-01'"
+	client := stub.NewClient(&stub.Result{ID: "TestRunSavedRunVars", Query: query}).WithNamedQueries(&athena.NamedQuery{
+		NamedQueryId: aws.String("nq-by-day"),
+		Name:         aws.String("requests_by_day"),
+		Database:     aws.String("default"),
+		QueryString:  aws.String("SELECT * FROM elb_logs WHERE day = :day"),
+	})
+
+	var out bytes.Buffer
+	cfg := &core.Config{Location: "s3://TestRunSavedRunBucket/", Silent: true, Vars: []string{"day:date=2017-07-01"}}
+	err := runSavedRun(client, nil, cfg, []string{"requests_by_day"}, &out)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), query)
+
+	// The error is returned if any variables are undefined
+	out.Reset()
+	cfg.Vars = nil
+	err = runSavedRun(client, nil, cfg, []string{"requests_by_day"}, &out)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "undefined variables: day")
+	}
+	assert.NotContains(t, out.String(), query)
+}
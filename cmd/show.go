@@ -1,9 +1,18 @@
 package cmd
 
 import (
+	"io"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/pkg/errors"
 	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/exec"
+	"github.com/skatsuta/athenai/filter"
+	"github.com/skatsuta/athenai/print"
 	"github.com/spf13/cobra"
 )
 
@@ -12,9 +21,19 @@ var showCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Shows the results of selected query executions",
 	Long: `Shows the results of selected query executions that are complete.
-You can filter entries interactively, and select multiple query executions to show at a time.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		core.New(newClient(config), config, os.Stdout).ShowResults()
+You can filter entries interactively, and select multiple query executions to show at a time.
+If any of --id, --match, --since, --until or --latest flags is given, the query executions
+matching all of them are selected without user interaction, which is useful in scripts.
+In that case, past query executions are searched until --count matching ones are found or
+--since is passed, and it is an error if none of them matches.
+For failed or cancelled query executions, their states and the reasons are shown instead of results.
+Selecting running ones waits for them to complete.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s3Client, err := newS3Client(config)
+		if err != nil {
+			return err
+		}
+		return runShow(newRetryClient(config), s3Client, config, &selection, showStates, time.Now(), os.Stdout)
 	},
 	Example: `  # Show the results of query executions
   $ athenai show
@@ -26,14 +45,114 @@ You can filter entries interactively, and select multiple query executions to sh
   $ athenai show --count 0
 
   # Print the results in CSV format
-  $ athenai show --format csv`,
+  $ athenai show --format csv
+
+  # Select query executions with fzf, which shows the full query under the cursor in a preview pane
+  $ athenai show --finder fzf
+
+  # Show the results of the given query executions without user interaction
+  $ athenai show --id 5ee2b5a2-7e72-4b03-8a1d-1e2cb6f2e1e4 --id 0f8b3a0c-2f5c-4a55-9f0e-8c0c5d2a8b7e
+
+  # Show why query executions have failed or been cancelled
+  $ athenai show --state failed,cancelled
+
+  # Show the results of the latest 3 query executions on cloudfront_logs in the last 24 hours
+  $ athenai show --match 'FROM cloudfront_logs' --since 24h --latest 3`,
+}
+
+// selectionFlags are flags to select query executions without user interaction.
+type selectionFlags struct {
+	ids    []string
+	match  string
+	since  string
+	until  string
+	latest int
 }
 
+var (
+	selection  selectionFlags
+	showStates []string
+)
+
 func init() {
 	RootCmd.AddCommand(showCmd)
 
 	// Define flags
 	f := showCmd.Flags()
-	f.StringVarP(&config.Format, "format", "f", "table", "The formatting style for command output. Valid values: table, csv")
-	f.UintVarP(&config.Count, "count", "c", 50, "The maximum possible number of SUCCEEDED query executions to list")
+	f.StringVarP(&config.Format, "format", "f", "table", "The formatting style for command output. Valid values: "+strings.Join(print.Formats, ", "))
+	f.UintVarP(&config.Count, "count", "c", 50, "The maximum possible number of query executions to list")
+	f.StringVar(&config.Fetch, "fetch", exec.FetchAuto, fetchUsage)
+	f.StringVar(&config.Finder, "finder", filter.FinderPeco, `The finder to select query executions interactively: "peco" (built-in), "fzf", "sk" or any command reading lines from stdin and writing selected ones to stdout`)
+	f.StringSliceVar(&selection.ids, "id", nil, "The IDs of query executions to select")
+	f.StringVar(&selection.match, "match", "", "The regular expression matched against queries to select")
+	f.StringVar(&selection.since, "since", "", `Select query executions submitted at or after the time, e.g. "2017-07-01", "2017-07-01T09:00:00+09:00" or "24h" (24 hours ago)`)
+	f.StringVar(&selection.until, "until", "", "Select query executions submitted before the time, in the same format as --since")
+	f.IntVar(&selection.latest, "latest", 0, "Select the newest N query executions among the matching ones")
+	f.StringSliceVar(&showStates, "state", []string{"succeeded"}, "The states of query executions to list. Valid values: "+strings.Join(core.StateNames, ", "))
+}
+
+// criteria builds criteria to select query executions from s. Relative times are based on now.
+func (s *selectionFlags) criteria(now time.Time) (filter.Criteria, error) {
+	c := filter.Criteria{
+		IDs:    s.ids,
+		Latest: s.latest,
+	}
+
+	if s.latest < 0 {
+		return c, errors.Errorf("invalid --latest %d: it must not be negative", s.latest)
+	}
+
+	if s.match != "" {
+		re, err := regexp.Compile(s.match)
+		if err != nil {
+			return c, errors.Wrap(err, "invalid --match")
+		}
+		c.Match = re
+	}
+
+	var err error
+	if c.Since, err = parseTime(s.since, now); err != nil {
+		return c, errors.Wrap(err, "invalid --since")
+	}
+	if c.Until, err = parseTime(s.until, now); err != nil {
+		return c, errors.Wrap(err, "invalid --until")
+	}
+	return c, nil
+}
+
+// timeLayouts are layouts of absolute times accepted by parseTime.
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// parseTime parses s as an absolute time, or as a duration before now. It returns the zero time
+// if s is empty. Times without a time zone are interpreted as local time.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("%q is neither a time nor a duration", s)
+}
+
+func runShow(client athenaiface.AthenaAPI, s3Client exec.ObjectGetter, cfg *core.Config, s *selectionFlags, stateNames []string, now time.Time, out io.Writer) error {
+	c, err := s.criteria(now)
+	if err != nil {
+		return err
+	}
+	states, err := core.ParseStates(stateNames)
+	if err != nil {
+		return errors.Wrap(err, "invalid --state")
+	}
+
+	a := core.New(client, cfg, out).WithStates(states).WithS3(s3Client)
+	if !c.IsZero() {
+		a.WithCriteria(c)
+	}
+	return a.ShowResults()
 }
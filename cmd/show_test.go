@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2017, 7, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{s: "", want: time.Time{}},
+		{s: "24h", want: time.Date(2017, 7, 1, 12, 0, 0, 0, time.UTC)},
+		{s: "90m", want: time.Date(2017, 7, 2, 10, 30, 0, 0, time.UTC)},
+		{s: "2017-07-01T09:00:00+09:00", want: time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{s: "2017-07-01T09:00:00", want: time.Date(2017, 7, 1, 9, 0, 0, 0, time.Local)},
+		{s: "2017-07-01 09:00:00", want: time.Date(2017, 7, 1, 9, 0, 0, 0, time.Local)},
+		{s: "2017-07-01", want: time.Date(2017, 7, 1, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTime(tt.s, now)
+		assert.NoError(t, err, "Input: %q", tt.s)
+		assert.True(t, tt.want.Equal(got), "Input: %q, Got: %s", tt.s, got)
+	}
+
+	_, err := parseTime("yesterday", now)
+	assert.Error(t, err)
+}
+
+func TestSelectionCriteria(t *testing.T) {
+	now := time.Date(2017, 7, 2, 12, 0, 0, 0, time.UTC)
+	s := &selectionFlags{
+		ids:    []string{"id1"},
+		match:  "^SELECT",
+		since:  "1h",
+		latest: 3,
+	}
+
+	c, err := s.criteria(now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id1"}, c.IDs)
+	assert.True(t, c.Match.MatchString("SELECT 1"))
+	assert.Equal(t, now.Add(-time.Hour), c.Since)
+	assert.True(t, c.Until.IsZero())
+	assert.Equal(t, 3, c.Latest)
+
+	c, err = (&selectionFlags{}).criteria(now)
+	assert.NoError(t, err)
+	assert.True(t, c.IsZero())
+
+	errTests := []struct {
+		flags   selectionFlags
+		wantErr string
+	}{
+		{flags: selectionFlags{match: "("}, wantErr: "invalid --match"},
+		{flags: selectionFlags{since: "yesterday"}, wantErr: "invalid --since"},
+		{flags: selectionFlags{until: "tomorrow"}, wantErr: "invalid --until"},
+		{flags: selectionFlags{latest: -1}, wantErr: "invalid --latest"},
+	}
+	for _, tt := range errTests {
+		_, err := tt.flags.criteria(now)
+		if assert.Error(t, err, "Flags: %#v", tt.flags) {
+			assert.Contains(t, err.Error(), tt.wantErr, "Flags: %#v", tt.flags)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/exec"
+	"github.com/skatsuta/athenai/print"
+	"github.com/spf13/cobra"
+)
+
+// waitCmd represents the wait command.
+var waitCmd = &cobra.Command{
+	Use:   "wait <query-execution-id>...",
+	Short: "Waits for query executions to complete and shows their results",
+	Long: `Waits for the query executions with the given IDs, which may have been started by other tools,
+to complete, and then shows their results, or their states and the reasons if they have failed or
+been cancelled. Pressing Ctrl-C stops the query executions as the run command does, unless --detach
+flag is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s3Client, err := newS3Client(config)
+		if err != nil {
+			return err
+		}
+		return runWait(newRetryClient(config), s3Client, config, waitDetach, args, stdout)
+	},
+	Example: `  # Wait for a query execution and show its results
+  $ athenai wait 5ee2b5a2-7e72-4b03-8a1d-1e2cb6f2e1e4
+
+  # Wait for multiple query executions, leaving them running if interrupted by Ctrl-C
+  $ athenai wait --detach 5ee2b5a2-7e72-4b03-8a1d-1e2cb6f2e1e4 0f8b3a0c-2f5c-4a55-9f0e-8c0c5d2a8b7e`,
+}
+
+var waitDetach bool
+
+func init() {
+	RootCmd.AddCommand(waitCmd)
+
+	// Define flags
+	f := waitCmd.Flags()
+	f.StringVarP(&config.Format, "format", "f", "table", "The formatting style for command output. Valid values: "+strings.Join(print.Formats, ", "))
+	f.StringVar(&config.Fetch, "fetch", exec.FetchAuto, fetchUsage)
+	f.BoolVar(&waitDetach, "detach", false, "Stop waiting without stopping the query executions when interrupted by Ctrl-C")
+}
+
+func runWait(client athenaiface.AthenaAPI, s3Client exec.ObjectGetter, cfg *core.Config, detach bool, ids []string, out io.Writer) error {
+	if len(ids) == 0 {
+		return errors.New("no query execution IDs are given")
+	}
+	if !print.IsValid(cfg.Format) {
+		return errors.Errorf("invalid --format %q. Valid values: %s", cfg.Format, strings.Join(print.Formats, ", "))
+	}
+	core.New(client, cfg, out).WithS3(s3Client).WaitQueryExecutions(detach, ids...)
+	return nil
+}
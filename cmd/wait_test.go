@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/skatsuta/athenai/core"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWait(t *testing.T) {
+	client := stub.NewClient(&stub.Result{ID: "TestRunWait", Query: "SHOW DATABASES"})
+
+	var out bytes.Buffer
+	err := runWait(client, nil, &core.Config{Format: "table", Silent: true}, false, []string{"TestRunWait"}, &out)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Query: SHOW DATABASES;")
+
+	err = runWait(client, nil, &core.Config{Format: "xml", Silent: true}, false, []string{"TestRunWait"}, &out)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid --format")
+	}
+
+	err = runWait(client, nil, &core.Config{Format: "table", Silent: true}, false, nil, &out)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no query execution IDs")
+	}
+}
@@ -21,8 +21,11 @@ import (
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/skatsuta/athenai/exec"
+	"github.com/skatsuta/athenai/export"
 	"github.com/skatsuta/athenai/filter"
+	"github.com/skatsuta/athenai/params"
 	"github.com/skatsuta/athenai/print"
+	"github.com/skatsuta/athenai/splitter"
 	"github.com/skatsuta/readline"
 	"github.com/skatsuta/spinner"
 )
@@ -37,10 +40,17 @@ const (
 	runningQueryMsg    = "Running query..."
 	loadingHistoryMsg  = "Loading history..."
 	fetchingResultsMsg = "Fetching results..."
+	waitingMsg         = "Waiting for query executions..."
 	cancelingMsg       = "Canceling..."
 
-	replPrompt      = "athenai> "
-	historyFileName = "history"
+	replPrompt             = "athenai> "
+	replContinuationPrompt = "     -> "
+	historyFileName        = "history"
+
+	// entryTimeLayout is a layout of submission times in entries, which is the one of time.Time.String().
+	entryTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+	// entryTailFields is the number of fields following a query in an entry.
+	entryTailFields = 4
 
 	maxResults = 50
 
@@ -66,6 +76,8 @@ func (sw *safeWriter) Write(p []byte) (int, error) {
 // readlineCloser is an interface to read every line in REPL and then close it.
 type readlineCloser interface {
 	Readline() (string, error)
+	SetPrompt(prompt string)
+	SaveHistory(content string) error
 	Close() error
 }
 
@@ -89,7 +101,18 @@ type Athenai struct {
 	cfg    *Config
 
 	refreshInterval time.Duration
-	waitInterval    time.Duration
+	termWidth       func() int // Returns the width of the terminal for auto format
+	backoff         exec.Backoff
+	retryFailed     exec.RetryFailedPolicy
+	noFooter        bool
+	catalog         *catalogCache
+	states          []string        // States of query executions to list
+	criteria        filter.Criteria // Criteria to select query executions without user interaction
+	maxScan         int64
+	vars            params.Vars
+	s3              exec.ObjectGetter
+	exporter        *export.Exporter
+	session         sessionCost
 
 	mu       sync.RWMutex
 	signalCh chan os.Signal
@@ -102,13 +125,14 @@ func New(client athenaiface.AthenaAPI, cfg *Config, out io.Writer) *Athenai {
 		stdin:           os.Stdin,
 		stdout:          out,
 		stderr:          &safeWriter{w: os.Stderr},
-		p:               print.New(out, cfg.Format),
 		cfg:             cfg,
 		client:          client,
 		refreshInterval: refreshInterval,
-		waitInterval:    exec.DefaultWaitInterval,
+		termWidth:       terminalWidth,
+		backoff:         cfg.Backoff(),
 		signalCh:        make(chan os.Signal, 1),
 	}
+	a.p = a.newPrinter()
 	return a
 }
 
@@ -120,14 +144,100 @@ func (a *Athenai) WithStderr(stderr io.Writer) *Athenai {
 	return a
 }
 
-// WithWaitInterval sets wait interval to a.
+// WithWaitInterval makes a poll query executions at the constant interval.
 func (a *Athenai) WithWaitInterval(interval time.Duration) *Athenai {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	a.waitInterval = interval
+	a.backoff = exec.ConstantBackoff(interval)
+	return a
+}
+
+// WithMaxScan makes a stop each query execution once it has scanned more than maxScan bytes.
+// Zero means no limit.
+func (a *Athenai) WithMaxScan(maxScan int64) *Athenai {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.maxScan = maxScan
+	return a
+}
+
+// WithVars sets variables to bind to placeholders in queries to a.
+func (a *Athenai) WithVars(vars params.Vars) *Athenai {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.vars = vars
+	return a
+}
+
+// WithS3 sets a client to fetch query results from Amazon S3 to a.
+// How results are fetched is decided by `Fetch` setting.
+func (a *Athenai) WithS3(client exec.ObjectGetter) *Athenai {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.s3 = client
+	return a
+}
+
+// WithExporter makes a export the rows of each result to a file with e instead of printing them.
+func (a *Athenai) WithExporter(e *export.Exporter) *Athenai {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.exporter = e
+	return a
+}
+
+// WithStates makes a list query executions in the given states to be selected.
+// By default only SUCCEEDED ones are listed.
+func (a *Athenai) WithStates(states []string) *Athenai {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.states = states
+	return a
+}
+
+// WithCriteria makes a select query executions matching c without user interaction.
+func (a *Athenai) WithCriteria(c filter.Criteria) *Athenai {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.f = filter.NewSelector(c, parseEntry)
+	a.criteria = c
+	return a
+}
+
+// WithRetryFailed sets a policy to resubmit failed query executions to a.
+func (a *Athenai) WithRetryFailed(p exec.RetryFailedPolicy) *Athenai {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.retryFailed = p
 	return a
 }
 
+// newPrinter creates a new Printer based on the current settings.
+func (a *Athenai) newPrinter() print.Printer {
+	var opts []print.Option
+	if a.noFooter {
+		opts = append(opts, print.WithoutFooter())
+	}
+	if a.cfg.PricePerTB > 0 {
+		opts = append(opts, print.WithPrice(a.cfg.PricePerTB))
+	}
+	// Results saved to a file are not limited by the width of the terminal
+	if a.cfg.Output == "" {
+		opts = append(opts, print.WithWidth(a.termWidth))
+	}
+	return print.New(a.stdout, a.cfg.Format, opts...)
+}
+
+// terminalWidth returns the width of the terminal connected to stdout, or 0 if stdout is not
+// a terminal.
+func terminalWidth() int {
+	width, _, err := readline.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
 func (a *Athenai) print(x ...interface{}) {
 	fmt.Fprint(a.stdout, x...)
 }
@@ -146,21 +256,39 @@ func (a *Athenai) showProgressMsg(ctx context.Context, msg string) {
 	s.Stop()
 }
 
-// runSingleQuery runs a single query. `query` must be a single SQL statement.
-func (a *Athenai) runSingleQuery(ctx context.Context, query string, ch chan *Either) {
-	// Run a query, and send results or an error
-	log.Printf("Start running %q\n", query)
-	q := exec.NewQuery(a.client, a.cfg.QueryConfig(), query).WithWaitInterval(a.waitInterval)
+// runSingleQuery runs a single SQL statement and returns its results or an error.
+func (a *Athenai) runSingleQuery(ctx context.Context, stmt *splitter.Statement) *Either {
+	log.Printf("Start running %q at %s\n", stmt.Text, stmt.Pos)
+	q := exec.NewQuery(a.client, a.cfg.QueryConfig(), stmt.Text).WithBackoff(a.backoff).WithTimeout(a.cfg.Timeout).
+		WithRetryFailed(a.retryFailed).WithMaxScan(a.maxScan).WithS3(a.s3, a.cfg.Fetch).WithStreaming()
 	r, err := q.Run(ctx)
+	if berr, ok := errors.Cause(err).(*exec.BudgetExceededError); ok {
+		// Data scanned before stopping the query execution is charged as well
+		a.session.add(berr.Scanned, a.cfg.PricePerTB)
+	}
 	if err != nil {
-		ch <- &Either{Right: err}
-	} else {
-		ch <- &Either{Left: r}
+		if stmt.Pos.Source != "" {
+			// Point at the statement in the file
+			err = errors.Wrapf(err, "statement at %s", stmt.Pos)
+		}
+		return &Either{Right: err}
 	}
+	if stats := r.Info().Statistics; stats != nil {
+		a.session.add(aws.Int64Value(stats.DataScannedInBytes), a.cfg.PricePerTB)
+	}
+	return &Either{Left: r}
 }
 
-func (a *Athenai) printResultOrErr(et *Either) {
-	a.print("\n")
+// printResultOrErr prints a result or an error. If header is not empty, it is printed before
+// the result in text formats. If an exporter is set, the result is exported as the one of
+// the statement at index (starting from 1) instead, and a summary of it is printed.
+func (a *Athenai) printResultOrErr(et *Either, index int, header string) {
+	if print.IsText(a.cfg.Format) {
+		a.print("\n")
+		if header != "" {
+			a.println(header)
+		}
+	}
 
 	if err := et.Right; err != nil {
 		cause := errors.Cause(err)
@@ -173,16 +301,171 @@ func (a *Athenai) printResultOrErr(et *Either) {
 		return
 	}
 
+	if a.exporter != nil {
+		a.exportResult(et.Left.(*exec.Result), index)
+		return
+	}
+
 	r := et.Left.(print.Result)
 	a.p.Print(r)
+
+	// Rows of the results are streamed while being printed
+	if er, ok := r.(*exec.Result); ok {
+		err := er.Err()
+		if _, canceled := errors.Cause(err).(*exec.CanceledError); canceled {
+			log.Println(err) // Just log the error
+		} else if err != nil {
+			a.printErr(err, "failed to read results")
+		}
+		er.Close()
+	}
+}
+
+// exportResult exports r to a file and prints a summary of it in text formats.
+func (a *Athenai) exportResult(r *exec.Result, index int) {
+	defer r.Close()
+	entry, err := a.exporter.Export(index, r)
+	if err != nil {
+		if _, canceled := errors.Cause(err).(*exec.CanceledError); canceled {
+			log.Println(err) // Just log the error
+			return
+		}
+		a.printErr(err, "failed to export results")
+		return
+	}
+	if print.IsText(a.cfg.Format) {
+		a.println(entry)
+	}
+}
+
+// reserveIndices reserves indices of n statements to export their results with, and returns
+// the offset to add to their indices (starting from 1).
+func (a *Athenai) reserveIndices(n int) int {
+	if a.exporter == nil {
+		return 0
+	}
+	return a.exporter.Reserve(n)
+}
+
+// writeManifest writes the manifest of the exported files, if any.
+func (a *Athenai) writeManifest() {
+	if a.exporter == nil {
+		return
+	}
+	if err := a.exporter.WriteManifest(); err != nil {
+		a.printErr(err, "failed to write manifest of exported files")
+	}
+}
+
+// indexedResult is a result of the statement at index in a script.
+type indexedResult struct {
+	index int
+	*Either
+}
+
+// runStmts runs stmts concurrently up to the concurrency limit, and sends their results to
+// resultCh as each of them completes.
+//
+// Each statement is started once all the statements it depends on (see deps) have completed.
+// If any of them which it requires has not succeeded, the statement is skipped. Statements
+// without dependencies are started in order, while the others wait for their dependencies in
+// their own goroutines so that they never hold up independent statements after them.
+// Statements not started yet when ctx is canceled are reported as canceled.
+func (a *Athenai) runStmts(ctx context.Context, stmts []*splitter.Statement, deps [][]dependency, resultCh chan<- *indexedResult, wg *sync.WaitGroup) {
+	concurrency := a.cfg.Concurrent
+	if concurrency == 0 {
+		concurrency = defaultConcurrentcy
+	}
+	// Limit the number of concurrent query executions
+	sema := make(chan struct{}, concurrency)
+
+	l := len(stmts)
+	doneChs := make([]chan struct{}, l)
+	for i := range doneChs {
+		doneChs[i] = make(chan struct{})
+	}
+	// succeeded[i] must be written before doneChs[i] is closed
+	succeeded := make([]bool, l)
+
+	skip := func(i int, err error) {
+		resultCh <- &indexedResult{index: i, Either: &Either{Right: err}}
+		close(doneChs[i])
+		wg.Done()
+	}
+
+	// waitDeps waits for the statements which the statement i depends on, and returns true if
+	// all of them which it requires have succeeded. Otherwise it skips the statement and returns
+	// false.
+	waitDeps := func(i int, stmt *splitter.Statement) bool {
+		for _, d := range deps[i] {
+			select {
+			case <-doneChs[d.index]:
+			case <-ctx.Done():
+				log.Printf("Skipping statement %d since canceled: %q\n", i+1, stmt.Text)
+				skip(i, &exec.CanceledError{Query: stmt.Text})
+				return false
+			}
+			if d.required && !succeeded[d.index] {
+				log.Printf("Skipping statement %d since statement %d has not succeeded\n", i+1, d.index+1)
+				skip(i, errors.Errorf("statement %d has been skipped since statement %d it depends on has not succeeded", i+1, d.index+1))
+				return false
+			}
+		}
+		return true
+	}
+
+	// start runs the statement i once a slot is available
+	start := func(i int, stmt *splitter.Statement) {
+		select {
+		case sema <- struct{}{}:
+		case <-ctx.Done():
+			log.Printf("Skipping statement %d since canceled: %q\n", i+1, stmt.Text)
+			skip(i, &exec.CanceledError{Query: stmt.Text})
+			return
+		}
+
+		go func() {
+			defer func() {
+				<-sema
+				close(doneChs[i])
+				wg.Done()
+			}()
+			et := a.runSingleQuery(ctx, stmt)
+			succeeded[i] = et.Right == nil
+			resultCh <- &indexedResult{index: i, Either: et}
+		}()
+	}
+
+	for i, stmt := range stmts {
+		if len(deps[i]) == 0 {
+			start(i, stmt)
+			continue
+		}
+		go func(i int, stmt *splitter.Statement) {
+			if waitDeps(i, stmt) {
+				start(i, stmt)
+			}
+		}(i, stmt) // Capture i and stmt locally in order to use them in goroutines
+	}
 }
 
 // RunQuery runs the given queries.
 // It splits each statement by semicolons and run them concurrently.
 // It skips empty statements.
-func (a *Athenai) RunQuery(queries ...string) {
+//
+// DDL statements act as barriers: they wait for all the statements before them, and the
+// statements after them wait for them. Dependencies can also be annotated in comments
+// preceding statements with `-- @barrier`, `-- @name <name>` and `-- @depends [<name or index>...]`.
+//
+// By default the results are printed in the order of the statements. If Config.Stream is true,
+// each result is printed as soon as its query execution completes.
+//
+// Errors of query executions are printed, and only an error which prevents the statements from
+// being run, such as undefined variables, is returned.
+func (a *Athenai) RunQuery(queries ...string) error {
 	// Trap SIGINT signal
 	signal.Notify(a.signalCh, os.Interrupt)
+	defer signal.Stop(a.signalCh)
 	// Context to propagate cancellation initiated by user
 	userCancelCtx, userCancelFunc := context.WithCancel(context.Background())
 	// Context to notify cancellation process is complete
@@ -192,7 +475,8 @@ func (a *Athenai) RunQuery(queries ...string) {
 		cancelingFunc()
 	}()
 
-	canceledCh := make(chan struct{})
+	// Buffered since the results may be being printed when canceled
+	canceledCh := make(chan struct{}, 1)
 
 	// Watcher goroutine to cancel query executions
 	go func() {
@@ -210,63 +494,81 @@ func (a *Athenai) RunQuery(queries ...string) {
 	}()
 
 	// Split SQL statements
-	stmts := a.splitStmts(queries)
+	stmts, err := a.splitStmts(queries)
+	if err != nil {
+		return errors.Wrap(err, "failed to bind variables")
+	}
 	l := len(stmts)
-	log.Printf("%d SQL statements to execute: %#v\n", l, stmts)
+	log.Printf("%d SQL statements to execute: %q\n", l, stmts)
 	if l == 0 {
 		a.println(noStmtFound)
-		return
+		return nil
 	}
 
-	// Print progress messages
+	deps, err := dependencies(stmts)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve dependencies between statements")
+	}
+	// List the exported files even if canceled
+	defer a.writeManifest()
+	offset := a.reserveIndices(l)
+
+	// Print progress messages. Results are still streamed after all executions have been
+	// completed, so they are stopped separately from userCancelCtx
+	progressCtx, stopProgress := context.WithCancel(userCancelCtx)
+	defer stopProgress()
 	if !a.cfg.Silent {
-		go a.showProgressMsg(userCancelCtx, runningQueryMsg)
+		go a.showProgressMsg(progressCtx, runningQueryMsg)
 	}
 
 	// Run each statement concurrently
-	chs := make([]chan *Either, l)
+	resultCh := make(chan *indexedResult, l)
 	var wg sync.WaitGroup
 	wg.Add(l)
-	concurrency := a.cfg.Concurrent
-	if concurrency == 0 {
-		concurrency = defaultConcurrentcy
-	}
-	// Limit the number of concurrent query executions
-	sema := make(chan struct{}, concurrency)
-
-	for i, stmt := range stmts {
-		sema <- struct{}{}
-		ch := make(chan *Either, 1)
-		chs[i] = ch
-		go func(query string) {
-			defer func() {
-				<-sema
-				wg.Done()
-			}()
-			a.runSingleQuery(userCancelCtx, query, ch)
-		}(stmt) // Capture stmt locally in order to use it in goroutines
-	}
+	go a.runStmts(userCancelCtx, stmts, deps, resultCh, &wg)
 
 	go func() {
 		wg.Wait()
-		userCancelFunc() // All executions have been completed; Stop showing the progress messages
-		signal.Stop(a.signalCh)
+		stopProgress() // All executions have been completed; Stop showing the progress messages
 	}()
 
-	for _, ch := range chs {
+	pending := make(map[int]*Either, l)
+	next := 0
+	for n := 0; n < l; n++ {
+		var r *indexedResult
 		select {
 		case <-canceledCh: // Stop showing results if canceled
+			// Wait for the running query executions to be stopped
+			for ; n < l; n++ {
+				<-resultCh
+			}
 			a.printE("\n")
-			return
-		default:
-			a.printResultOrErr(<-ch)
+			return nil
+		case r = <-resultCh:
+		}
+
+		if a.cfg.Stream {
+			log.Printf("Statement %d/%d has completed\n", r.index+1, l)
+			a.printResultOrErr(r.Either, offset+r.index+1, fmt.Sprintf("[%d/%d]", r.index+1, l))
+			continue
+		}
+
+		// Print results in the order of the statements
+		pending[r.index] = r.Either
+		for ; pending[next] != nil; next++ {
+			a.printResultOrErr(pending[next], offset+next+1, "")
+			delete(pending, next)
 		}
 	}
 
 	log.Println("All query executions have been completed")
+	if a.rl == nil && l > 1 {
+		a.printSessionCost()
+	}
 	if a.cfg.Output != "" {
 		a.printE("\n")
 	}
+	return nil
 }
 
 func (a *Athenai) setupREPL() error {
@@ -284,13 +586,33 @@ func (a *Athenai) setupREPL() error {
 		return errors.Wrap(err, "error ensuring the default directory exists")
 	}
 
+	// Load the catalog cache for completion, which is refreshed in background if expired
+	cache := newCatalogCache(filepath.Join(dir, catalogFileName(a.cfg.Region)), a.cfg.CatalogTTL)
+	if err := cache.load(); err != nil {
+		log.Println("Catalog cache is not available:", err)
+	}
+	a.mu.Lock()
+	a.catalog = cache
+	a.mu.Unlock()
+	a.refreshCatalogInBackground()
+
 	historyFile := filepath.Join(dir, historyFileName)
 	rl, err := readline.NewEx(&readline.Config{
 		Prompt:            replPrompt,
 		HistoryFile:       historyFile,
 		HistorySearchFold: true,
-		Stdin:             a.stdin,
-		Stdout:            a.stdout,
+		// Save a whole statement rather than each line of it
+		DisableAutoSaveHistory: true,
+		AutoComplete: &completer{
+			cache: cache,
+			database: func() string {
+				a.mu.RLock()
+				defer a.mu.RUnlock()
+				return a.cfg.Database
+			},
+		},
+		Stdin:  a.stdin,
+		Stdout: a.stdout,
 	})
 	if err != nil {
 		return err
@@ -305,19 +627,33 @@ func (a *Athenai) setupREPL() error {
 }
 
 // RunREPL runs REPL mode (interactive mode).
+// Lines are buffered until a statement is terminated by a semicolon, and then the buffered
+// statement is run.
 func (a *Athenai) RunREPL() error {
 	if err := a.setupREPL(); err != nil {
 		return errors.Wrap(err, "failed to setup REPL")
 	}
 	defer a.rl.Close()
+	defer a.printSessionCost()
+
+	var lines []string // Lines of the statement being typed
+	reset := func() {
+		lines = nil
+		a.rl.SetPrompt(replPrompt)
+	}
 
 	for {
 		// Read a line from stdin
-		query, err := a.rl.Readline()
+		line, err := a.rl.Readline()
 		if err != nil {
 			switch err {
 			case readline.ErrInterrupt:
-				if query == "" {
+				if len(lines) > 0 {
+					log.Println("Ctrl-C is pressed while typing a statement, clearing the buffer")
+					reset()
+					continue
+				}
+				if line == "" {
 					log.Println("Ctrl-C is pressed on empty line, exitting REPL")
 					return nil
 				}
@@ -332,14 +668,43 @@ func (a *Athenai) RunREPL() error {
 			}
 		}
 
-		// Ignore empty input
-		if query == "" {
+		// Ignore empty input before a statement
+		if len(lines) == 0 && strings.TrimSpace(line) == "" {
 			continue
 		}
 
+		// Run a meta-command given at the beginning of a statement immediately
+		if len(lines) == 0 && isMetaCommand(line) {
+			if err := a.rl.SaveHistory(strings.TrimSpace(line)); err != nil {
+				log.Println("Error saving history:", err)
+			}
+			if err := a.runMetaCommand(line); err != nil {
+				if err == errQuit {
+					log.Println("Quit meta-command is given, exitting REPL")
+					return nil
+				}
+				a.printErr(err, "meta-command failed")
+			}
+			continue
+		}
+
+		lines = append(lines, line)
+		query := strings.Join(lines, "\n")
+		if !splitter.Terminated(query) {
+			a.rl.SetPrompt(replContinuationPrompt)
+			continue
+		}
+		reset()
+
+		if err := a.rl.SaveHistory(splitter.OneLine(query)); err != nil {
+			log.Println("Error saving history:", err)
+		}
+
 		// Run the query
 		log.Printf("Given input: %q\n", query)
-		a.RunQuery(query)
+		if err := a.RunQuery(query); err != nil {
+			fmt.Fprintf(a.stderr, "Error: %s\n", err)
+		}
 	}
 }
 
@@ -409,19 +774,96 @@ Loop:
 	}
 
 	log.Println("Sorting query executions by SubmissionDateTime in descending order")
-	sort.Slice(qxs, func(i, j int) bool {
-		// Sort by SubmissionDateTime in descending order
-		return qxs[i].Status.SubmissionDateTime.After(*qxs[j].Status.SubmissionDateTime)
-	})
+	sortQueryExecutions(qxs)
 	return qxs, nil
 }
 
+// scanQueryExecutions fetches query executions page by page from the newest, and calls fn with
+// each page of them sorted by submission date in the descending order. It stops fetching once
+// fn returns false or all the query executions have been fetched.
+func (a *Athenai) scanQueryExecutions(ctx context.Context, fn func(qxs []*athena.QueryExecution) bool) error {
+	var batchErr error
+	pageNum := 0
+	callback := func(page *athena.ListQueryExecutionsOutput, lastPage bool) bool {
+		pageNum++
+		log.Printf("Scanning page %d of query executions\n", pageNum)
+		qxs, err := a.batchGetQueryExecutions(ctx, aws.StringValueSlice(page.QueryExecutionIds))
+		if err != nil {
+			batchErr = err
+			return false
+		}
+		sortQueryExecutions(qxs)
+		return fn(qxs)
+	}
+
+	if err := a.client.ListQueryExecutionsPagesWithContext(ctx, &athena.ListQueryExecutionsInput{}, callback); err != nil {
+		return errors.Wrap(err, "ListQueryExecutions API error")
+	}
+	return batchErr
+}
+
+// findQueryExecutions fetches query executions which can match c, sorted by submission date in
+// the descending order. The query executions of the IDs of c are fetched directly. Otherwise,
+// it pages through query executions until it has found the configured count (or c.Latest if
+// less) of ones matching c or has passed c.Since, so that old matching ones are found as well.
+func (a *Athenai) findQueryExecutions(ctx context.Context, c filter.Criteria) ([]*athena.QueryExecution, error) {
+	if len(c.IDs) > 0 {
+		log.Printf("Fetching %d query executions by ID\n", len(c.IDs))
+		qxs, err := a.batchGetQueryExecutions(ctx, c.IDs)
+		if err != nil {
+			return nil, err
+		}
+		sortQueryExecutions(qxs)
+		return qxs, nil
+	}
+
+	limit := int(a.cfg.Count)
+	if c.Latest > 0 && (limit == 0 || c.Latest < limit) {
+		limit = c.Latest
+	}
+	log.Printf("Finding up to %d query executions matching the criteria\n", limit)
+
+	var found []*athena.QueryExecution
+	err := a.scanQueryExecutions(ctx, func(qxs []*athena.QueryExecution) bool {
+		for _, qx := range qxs {
+			if a.listable(aws.StringValue(qx.Status.State)) && c.Matches(newEntry(qx)) {
+				found = append(found, qx)
+			}
+		}
+		if limit > 0 && len(found) >= limit {
+			return false
+		}
+		return !passedSince(qxs, c.Since)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("%d query executions matching the criteria have been found\n", len(found))
+	return found, nil
+}
+
+// passedSince returns true if qxs sorted by submission date in the descending order include
+// the one submitted before since, which means query executions on the later pages are all
+// older than since. It always returns false if since is the zero time.
+func passedSince(qxs []*athena.QueryExecution, since time.Time) bool {
+	if since.IsZero() || len(qxs) == 0 {
+		return false
+	}
+	return aws.TimeValue(qxs[len(qxs)-1].Status.SubmissionDateTime).Before(since)
+}
+
+// sortQueryExecutions sorts qxs by submission date in the descending order.
+func sortQueryExecutions(qxs []*athena.QueryExecution) {
+	sort.SliceStable(qxs, func(i, j int) bool {
+		return aws.TimeValue(qxs[i].Status.SubmissionDateTime).After(aws.TimeValue(qxs[j].Status.SubmissionDateTime))
+	})
+}
+
 func (a *Athenai) filterQueryExecutions(qxs []*athena.QueryExecution) ([]*athena.QueryExecution, error) {
 	entryMap := make(map[string]*athena.QueryExecution, len(qxs))
 	entries := make([]string, 0, len(qxs))
 	for _, qx := range qxs {
-		if aws.StringValue(qx.Status.State) != athena.QueryExecutionStateSucceeded {
-			// Skip if not succeeded
+		if !a.listable(aws.StringValue(qx.Status.State)) {
 			log.Printf("Eliminating QueryExecutionId %s because of %s state\n",
 				aws.StringValue(qx.QueryExecutionId),
 				aws.StringValue(qx.Status.State),
@@ -433,41 +875,74 @@ func (a *Athenai) filterQueryExecutions(qxs []*athena.QueryExecution) ([]*athena
 		entries = append(entries, entry)
 	}
 
-	// Reduce entries
+	// Reduce entries unless they have been already limited by the criteria
 	c := int(a.cfg.Count)
 	l := len(entries)
-	if c == 0 || c > l {
+	if c == 0 || c > l || !a.criteria.IsZero() {
 		c = l
 	}
 	log.Printf("Reducing the number of entries from %d to %d\n", l, c)
 	entries = entries[:c]
 
-	history := strings.Join(entries, "\n")
-	a.f.SetInput(history)
-
-	if err := a.f.Run(context.Background()); err != nil {
+	// Preview the original query, which is flattened into a line in the entry
+	selected, err := a.pick(context.Background(), entries, func(item string) string {
+		return aws.StringValue(entryMap[item].Query)
+	})
+	if err != nil {
 		return nil, errors.Wrap(err, "error filtering query executions")
 	}
 
-	l = a.f.Len()
-	log.Printf("Selected %d query execution entries\n", l)
-	selectedQxs := make([]*athena.QueryExecution, 0, l)
-	a.f.Each(func(item string) bool {
+	log.Printf("Selected %d query execution entries\n", len(selected))
+	selectedQxs := make([]*athena.QueryExecution, 0, len(selected))
+	for _, item := range selected {
 		if entry, ok := entryMap[item]; ok {
 			selectedQxs = append(selectedQxs, entry)
 		}
+	}
+	return selectedQxs, nil
+}
+
+// ensureFilter sets a new Filter with the configured finder to a unless a already has one.
+func (a *Athenai) ensureFilter() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f != nil {
+		return nil
+	}
+
+	log.Println("Filter not set in Athenai. Creating and setting a new Filter")
+	f, err := filter.NewFinder(a.cfg.Finder)
+	if err != nil {
+		return errors.Wrap(err, "error creating filter")
+	}
+	a.f = f
+	return nil
+}
+
+// pick lets the user select entries with the filter of a, and returns the selected ones.
+// If the filter can show previews, preview returns the text to preview for an entry.
+func (a *Athenai) pick(ctx context.Context, entries []string, preview func(entry string) string) ([]string, error) {
+	a.f.SetInput(strings.Join(entries, "\n"))
+	if p, ok := a.f.(filter.Previewer); ok {
+		p.SetPreview(preview)
+	}
+
+	if err := a.f.Run(ctx); err != nil {
+		return nil, err
+	}
+
+	selected := make([]string, 0, a.f.Len())
+	a.f.Each(func(item string) bool {
+		selected = append(selected, item)
 		return true
 	})
-	return selectedQxs, nil
+	return selected, nil
 }
 
 func (a *Athenai) selectQueryExecutions(ctx context.Context) ([]*athena.QueryExecution, error) {
-	a.mu.Lock()
-	if a.f == nil {
-		log.Println("Filter not set in Athenai. Creating and setting a new Filter")
-		a.f = filter.New()
+	if err := a.ensureFilter(); err != nil {
+		return nil, err
 	}
-	a.mu.Unlock()
 
 	loadingCtx, cancel := context.WithCancel(ctx)
 	defer cancel() // Ensure to cancel
@@ -477,7 +952,13 @@ func (a *Athenai) selectQueryExecutions(ctx context.Context) ([]*athena.QueryExe
 		go a.showProgressMsg(loadingCtx, loadingHistoryMsg)
 	}
 
-	qxs, err := a.fetchQueryExecutions(loadingCtx)
+	var qxs []*athena.QueryExecution
+	var err error
+	if a.criteria.IsZero() {
+		qxs, err = a.fetchQueryExecutions(loadingCtx)
+	} else {
+		qxs, err = a.findQueryExecutions(loadingCtx, a.criteria)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "error fetching query executions")
 	}
@@ -490,13 +971,65 @@ func (a *Athenai) selectQueryExecutions(ctx context.Context) ([]*athena.QueryExe
 		return nil, errors.Wrap(err, "error selecting query executions")
 	}
 
+	// Selecting nothing without user interaction is an error, which scripts should notice
+	if !a.criteria.IsZero() && len(selectedQxs) == 0 {
+		return nil, errors.New("no query executions match the given conditions")
+	}
 	return selectedQxs, nil
 }
 
+// listable returns true if query executions in state are listed to be selected, otherwise false.
+func (a *Athenai) listable(state string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if len(a.states) == 0 {
+		return state == athena.QueryExecutionStateSucceeded
+	}
+	for _, s := range a.states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
 // fetchQueryResults fetches query results of qx and send them to ch.
-func (a *Athenai) fetchQueryResults(ctx context.Context, qx *athena.QueryExecution, ch chan *Either) {
-	log.Printf("Start fetching query results of QueryExecutionId %s\n", aws.StringValue(qx.QueryExecutionId))
-	q := exec.NewQueryFromQx(a.client, a.cfg.QueryConfig(), qx).WithWaitInterval(a.waitInterval)
+// If qx is queued or running, it waits for qx to complete first with wait, which is either
+// (*exec.Query).Attach or (*exec.Query).Wait. If qx has failed or been cancelled, it sends qx
+// without results so that its status is printed.
+func (a *Athenai) fetchQueryResults(ctx context.Context, qx *athena.QueryExecution, ch chan *Either, wait func(*exec.Query, context.Context) error) {
+	id := aws.StringValue(qx.QueryExecutionId)
+	q := exec.NewQueryFromQx(a.client, a.cfg.QueryConfig(), qx).WithBackoff(a.backoff).WithS3(a.s3, a.cfg.Fetch).
+		WithStreaming()
+
+	switch aws.StringValue(qx.Status.State) {
+	case athena.QueryExecutionStateQueued, athena.QueryExecutionStateRunning:
+		log.Printf("Attaching to QueryExecutionId %s and waiting for it to complete\n", id)
+		err := wait(q, ctx)
+		switch errors.Cause(err).(type) {
+		case nil:
+		case *exec.FailedError:
+			ch <- &Either{Left: q.Result}
+			return
+		case *exec.CanceledError:
+			if ctx.Err() == nil { // Cancelled by someone else
+				ch <- &Either{Left: q.Result}
+				return
+			}
+			ch <- &Either{Right: err}
+			return
+		default:
+			ch <- &Either{Right: err}
+			return
+		}
+	case athena.QueryExecutionStateFailed, athena.QueryExecutionStateCancelled:
+		log.Printf("QueryExecutionId %s has no results because of %s state\n", id, aws.StringValue(qx.Status.State))
+		ch <- &Either{Left: q.Result}
+		return
+	}
+
+	log.Printf("Start fetching query results of QueryExecutionId %s\n", id)
 	if err := q.GetResults(ctx); err != nil {
 		ch <- &Either{Right: err}
 	} else {
@@ -505,12 +1038,15 @@ func (a *Athenai) fetchQueryResults(ctx context.Context, qx *athena.QueryExecuti
 }
 
 // ShowResults shows results of completed query executions.
-func (a *Athenai) ShowResults() {
+// It returns an error if query executions have failed to be selected.
+func (a *Athenai) ShowResults() error {
 	// Trap SIGINT signal
 	signal.Notify(a.signalCh, os.Interrupt)
+	defer signal.Stop(a.signalCh)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	canceledCh := make(chan struct{})
+	// Buffered since the results may be being printed when canceled
+	canceledCh := make(chan struct{}, 1)
 
 	// Watch user-initiated cancellation
 	go func() {
@@ -526,16 +1062,19 @@ func (a *Athenai) ShowResults() {
 	qxs, err := a.selectQueryExecutions(ctx)
 	if err != nil {
 		a.printE("\n")
-		if !strings.Contains(err.Error(), "canceled") { // Ignore user-canceled error
-			a.printErr(err, "error selecting query executions")
+		if strings.Contains(err.Error(), "canceled") { // Ignore user-canceled error
+			return nil
 		}
-		return
+		return err
 	}
 
-	// Print messages while fetching query results
+	// Print messages while fetching query results. They are stopped separately from ctx, which
+	// keeps streaming the results while they are printed
+	progressCtx, stopProgress := context.WithCancel(ctx)
+	defer stopProgress()
 	if !a.cfg.Silent {
 		a.printE("\n")
-		go a.showProgressMsg(ctx, fetchingResultsMsg)
+		go a.showProgressMsg(progressCtx, fetchingResultsMsg)
 	}
 
 	// Get each query result concurrently
@@ -547,28 +1086,28 @@ func (a *Athenai) ShowResults() {
 		ch := make(chan *Either, 1)
 		chs[i] = ch
 		go func(qx *athena.QueryExecution) {
-			a.fetchQueryResults(ctx, qx, ch)
+			a.fetchQueryResults(ctx, qx, ch, (*exec.Query).Attach)
 			wg.Done()
 		}(qx) // Capture locally in order to use it in goroutines
 	}
 
 	go func() {
 		wg.Wait()
-		cancel() // All results have been fetched; Stop showing the progress messages
-		signal.Stop(a.signalCh)
+		stopProgress() // All results have been fetched; Stop showing the progress messages
 	}()
 
-	for _, ch := range chs {
+	for i, ch := range chs {
 		select {
 		case <-canceledCh: // Stop showing results if canceled
 			a.printE("\n")
-			return
+			return nil
 		default:
-			a.printResultOrErr(<-ch)
+			a.printResultOrErr(<-ch, i+1, "")
 		}
 	}
 
 	log.Println("Fetched all query results")
+	return nil
 }
 
 func (a *Athenai) printErr(err error, message string) {
@@ -597,18 +1136,26 @@ func readFile(arg string) (string, error) {
 }
 
 // splitStmts splits SQL statements contained in args by semicolons and flattens them.
-// It drops empty statements.
+// It drops empty statements. Semicolons in string literals, quoted identifiers and comments
+// do not separate statements.
 //
 // If an argument has `file://` prefix, splitStmts reads the file content
 // and splits each statement as well.
-// If it encounters errors while reading files, it just prints the errors on stderr and ignores them.
-func (a *Athenai) splitStmts(args []string) []string {
-	stmts := make([]string, 0, len(args))
+// If it encounters errors while reading files or splitting statements, it just prints the errors
+// on stderr and ignores them.
+//
+// Placeholders in the statements are replaced with the variables set with WithVars. Unlike the
+// errors above, it returns an error if any of the placeholders refer to undefined variables,
+// so that no statements are run with placeholders left.
+func (a *Athenai) splitStmts(args []string) ([]*splitter.Statement, error) {
+	stmts := make([]*splitter.Statement, 0, len(args))
 
 	for _, arg := range args {
 		arg := arg // Capture locally
+		source := ""
 		if strings.HasPrefix(arg, filePrefix) {
 			log.Printf("%q prefix found in %q, reading its contents from file\n", filePrefix, arg)
+			source = strings.TrimPrefix(arg, filePrefix)
 			var err error
 			arg, err = readFile(arg)
 			if err != nil {
@@ -617,16 +1164,56 @@ func (a *Athenai) splitStmts(args []string) []string {
 			}
 		}
 
-		splitted := strings.Split(arg, ";")
-		for _, s := range splitted {
-			stmt := strings.TrimSpace(s)
-			if stmt != "" {
-				stmts = append(stmts, stmt)
+		splitted, err := splitter.Split(arg)
+		if err != nil {
+			if serr, ok := err.(*splitter.SyntaxError); ok {
+				serr.Pos.Source = source
 			}
+			a.printErr(err, "failed to split SQL statements")
+			continue
+		}
+		for _, stmt := range splitted {
+			stmt.Pos.Source = source
+			stmts = append(stmts, stmt)
 		}
 	}
 
-	return stmts
+	var undefined []string
+	for _, stmt := range stmts {
+		text, err := splitter.Expand(stmt.Text, a.vars.Lookup)
+		if uerr, ok := err.(*splitter.UndefinedError); ok {
+			log.Printf("Undefined variables %q in statement at %s\n", uerr.Names, stmt.Pos)
+			undefined = appendNew(undefined, uerr.Names...)
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to replace placeholders in statement at %s", stmt.Pos)
+		}
+		stmt.Text = text
+	}
+	if len(undefined) > 0 {
+		return nil, errors.Errorf("undefined variables: %s. Specify them with --var or --vars-file flag",
+			strings.Join(undefined, ", "))
+	}
+
+	return stmts, nil
+}
+
+// appendNew appends the elements of elems not contained in s to s.
+func appendNew(s []string, elems ...string) []string {
+	for _, e := range elems {
+		found := false
+		for _, x := range s {
+			if x == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s = append(s, e)
+		}
+	}
+	return s
 }
 
 func ensureDefaultDir() (string, error) {
@@ -649,16 +1236,60 @@ func generateEntry(qx *athena.QueryExecution) string {
 		query = strings.Join(strings.Split(query, "\n"), " ")
 	}
 
-	entry := fmt.Sprintf("%s\t%s\t%s\t%.2f seconds\t%s",
+	entry := fmt.Sprintf("%s\t%s\t%s\t%.2f seconds\t%s\t%s",
 		qx.Status.SubmissionDateTime,
 		query,
 		aws.StringValue(qx.Status.State),
 		float64(aws.Int64Value(qx.Statistics.EngineExecutionTimeInMillis))/1000,
 		print.FormatBytes(aws.Int64Value(qx.Statistics.DataScannedInBytes)),
+		aws.StringValue(qx.QueryExecutionId),
 	)
 	return entry
 }
 
+// newEntry returns an Entry of qx to be matched against criteria, which is the same as the one
+// parsed from the entry generated by generateEntry.
+func newEntry(qx *athena.QueryExecution) *filter.Entry {
+	return &filter.Entry{
+		ID:             aws.StringValue(qx.QueryExecutionId),
+		Query:          strings.Replace(aws.StringValue(qx.Query), "\n", " ", -1),
+		State:          aws.StringValue(qx.Status.State),
+		SubmissionTime: aws.TimeValue(qx.Status.SubmissionDateTime),
+	}
+}
+
+// parseEntry parses an entry generated by generateEntry.
+func parseEntry(entry string) (*filter.Entry, error) {
+	// Split the submission time from the head and the other fields from the tail,
+	// since the query itself can contain tabs
+	head := strings.SplitN(entry, "\t", 2)
+	if len(head) != 2 {
+		return nil, errors.Errorf("invalid entry %q", entry)
+	}
+	fields := strings.Split(head[1], "\t")
+	l := len(fields)
+	if l < entryTailFields+1 {
+		return nil, errors.Errorf("invalid entry %q", entry)
+	}
+
+	// Drop the monotonic clock reading, e.g. " m=+0.000000001", if any
+	ts := head[0]
+	if i := strings.Index(ts, " m="); i >= 0 {
+		ts = ts[:i]
+	}
+	t, err := time.Parse(entryTimeLayout, ts)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid submission time in entry")
+	}
+
+	return &filter.Entry{
+		ID:             fields[l-1],
+		Query:          strings.Join(fields[:l-entryTailFields], "\t"),
+		State:          fields[l-entryTailFields],
+		SubmissionTime: t,
+	}, nil
+}
+
 func calcMaxPages(c int) float64 {
 	if c == 0 {
 		// No page limit if zero is given
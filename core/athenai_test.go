@@ -2,19 +2,28 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/athena"
 	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/exec"
+	"github.com/skatsuta/athenai/export"
+	"github.com/skatsuta/athenai/filter"
 	"github.com/skatsuta/athenai/internal/bytes"
 	"github.com/skatsuta/athenai/internal/stub"
 	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/skatsuta/athenai/params"
 	"github.com/skatsuta/readline"
 	"github.com/stretchr/testify/assert"
 )
@@ -42,13 +51,270 @@ func TestSplitStmts(t *testing.T) {
 			2,
 		},
 		{[]string{"", ";", "SELECT; SHOW; ", "; DESCRIBE"}, 3},
+		{[]string{"SELECT * FROM logs WHERE msg LIKE '%;%'; -- comment; \n SHOW TABLES"}, 2},
+		{[]string{"SELECT 'unterminated; SHOW TABLES", "SHOW DATABASES"}, 1},
 	}
 
 	for _, tt := range tests {
-		got := new(Athenai).splitStmts(tt.queries)
+		a := &Athenai{stderr: ioutil.Discard}
+		got, err := a.splitStmts(tt.queries)
 
-		assert.Len(t, got, tt.wantLen, "Query: %q")
+		assert.NoError(t, err)
+		assert.Len(t, got, tt.wantLen, "Query: %q", tt.queries)
+	}
+}
+
+func TestSplitStmtsVars(t *testing.T) {
+	vars := make(params.Vars)
+	for _, spec := range []string{"table:ident=elb_logs", "start:date=2017-07-01", "status=it's ok"} {
+		assert.NoError(t, vars.Set(spec))
+	}
+
+	tests := []struct {
+		queries []string
+		want    []string
+		wantErr string
+	}{
+		{
+			queries: []string{"SELECT * FROM ${table} WHERE day >= :start AND status = :status; SELECT ':start' -- :start"},
+			want: []string{
+				"SELECT * FROM elb_logs WHERE day >= DATE '2017-07-01' AND status = 'it''s ok'",
+				"SELECT ':start' -- :start",
+			},
+		},
+		{
+			queries: []string{"SELECT :start", "SELECT :end FROM ${tbl}; SELECT :end"},
+			wantErr: "undefined variables: end, tbl",
+		},
+	}
+
+	for _, tt := range tests {
+		a := &Athenai{stderr: ioutil.Discard, vars: vars}
+		got, err := a.splitStmts(tt.queries)
+
+		if tt.wantErr != "" {
+			if assert.Error(t, err, "Query: %q", tt.queries) {
+				assert.Contains(t, err.Error(), tt.wantErr, "Query: %q", tt.queries)
+			}
+			continue
+		}
+		texts := make([]string, len(got))
+		for i, stmt := range got {
+			texts[i] = stmt.Text
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, texts, "Query: %q", tt.queries)
+	}
+}
+
+func TestRunQueryFetchS3(t *testing.T) {
+	srv := stub.NewS3Server()
+	defer srv.Close()
+	location := "s3://bucket/TestRunQueryFetchS3.csv"
+	srv.PutObject(location, "\"name\"\n\"from s3\"\n")
+
+	query := "SELECT name FROM users"
+	client := stub.NewClient(&stub.Result{
+		ID:             "TestRunQueryFetchS3",
+		Query:          query,
+		OutputLocation: location,
+		ResultSet: athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{},
+			Rows:              testhelper.CreateRows([][]string{{"name"}, {"from api"}}),
+		},
+	})
+	sess := session.Must(session.NewSession(aws.NewConfig().WithRegion("us-east-1").
+		WithCredentials(credentials.NewStaticCredentials("AKID", "SECRET", ""))))
+	s3Client, err := exec.NewS3Client(sess, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		fetch string
+		want  string
+	}{
+		{fetch: exec.FetchAuto, want: "from s3"},
+		{fetch: exec.FetchAPI, want: "from api"},
+	} {
+		var out bytes.Buffer
+		a := New(client, &Config{Silent: true, Fetch: tt.fetch}, &out).WithWaitInterval(testWaitInterval).WithS3(s3Client)
+		a.RunQuery(query)
+
+		assert.Contains(t, out.String(), tt.want, "Fetch: %s", tt.fetch)
+	}
+}
+
+func TestRunQueryStreamError(t *testing.T) {
+	srv := stub.NewS3Server()
+	defer srv.Close()
+	// The CSV file is broken after two pages of 1000 rows including the header have been streamed
+	location := "s3://bucket/TestRunQueryStreamError.csv"
+	content := "\"n\"\n" + strings.Repeat("\"1\"\n", 1999) + "\"unterminated"
+	srv.PutObject(location, content)
+
+	query := "SELECT n FROM large"
+	client := stub.NewClient(&stub.Result{
+		ID:             "TestRunQueryStreamError",
+		Query:          query,
+		OutputLocation: location,
+		ResultSet:      athena.ResultSet{ResultSetMetadata: &athena.ResultSetMetadata{}},
+	})
+	sess := session.Must(session.NewSession(aws.NewConfig().WithRegion("us-east-1").
+		WithCredentials(credentials.NewStaticCredentials("AKID", "SECRET", ""))))
+	s3Client, err := exec.NewS3Client(sess, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	a := New(client, &Config{Silent: true, Format: "csv", Fetch: exec.FetchS3}, &out).WithStderr(&errOut).
+		WithWaitInterval(testWaitInterval).WithS3(s3Client)
+	a.RunQuery(query)
+
+	assert.Equal(t, 1999, strings.Count(out.String(), "1\n"), "Rows read before the failure should be printed")
+	assert.Contains(t, errOut.String(), "failed to read results")
+	assert.Contains(t, errOut.String(), "unterminated quoted field")
+}
+
+func TestRunQueryExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "athenai-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	columnInfo := []*athena.ColumnInfo{
+		{Name: aws.String("id"), Type: aws.String("integer")},
+		{Name: aws.String("name"), Type: aws.String("varchar")},
+	}
+	client := stub.NewClient(
+		&stub.Result{
+			ID:    "TestRunQueryExport1",
+			Query: "SELECT * FROM users",
+			ResultSet: athena.ResultSet{
+				ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: columnInfo},
+				Rows:              testhelper.CreateRows([][]string{{"id", "name"}, {"1", "alice"}, {"2", "bob"}}),
+			},
+		},
+		&stub.Result{
+			ID:         "TestRunQueryExport2",
+			Query:      "SELECT * FROM broken",
+			FinalState: stub.Failed,
+			Reason:     "SYNTAX_ERROR",
+		},
+	)
+
+	e, err := export.New(dir, export.FormatJSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	a := New(client, &Config{Silent: true, Format: "table"}, &out).WithStderr(&errOut).
+		WithWaitInterval(testWaitInterval).WithExporter(e)
+	a.RunQuery("SELECT * FROM users; SELECT * FROM broken")
+
+	path := filepath.Join(dir, "1-TestRunQueryExport1.jsonl")
+	assert.Contains(t, out.String(), "Exported 2 rows to "+path)
+	assert.NotContains(t, out.String(), "alice", "Rows should not be printed")
+	assert.Contains(t, errOut.String(), "SYNTAX_ERROR")
+
+	got, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"name":"alice"}`+"\n"+`{"id":2,"name":"bob"}`+"\n", string(got))
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, export.ManifestFileName))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var m struct{ Results []*export.Entry }
+	assert.NoError(t, json.Unmarshal(b, &m))
+	if assert.Len(t, m.Results, 1) {
+		assert.Equal(t, 1, m.Results[0].Index)
+		assert.Equal(t, "SELECT * FROM users", m.Results[0].Query)
+		assert.Equal(t, "TestRunQueryExport1", m.Results[0].QueryExecutionID)
+		assert.Equal(t, int64(2), m.Results[0].RowCount)
+		assert.Equal(t, path, m.Results[0].Path)
+	}
+
+	// Indices keep increasing in the next run, e.g. in interactive mode
+	out.Reset()
+	a.RunQuery("SELECT * FROM users")
+
+	path3 := filepath.Join(dir, "3-TestRunQueryExport1.jsonl")
+	assert.Contains(t, out.String(), "Exported 2 rows to "+path3)
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, export.ManifestFileName))
+	if !assert.NoError(t, err) {
+		return
+	}
+	m.Results = nil
+	assert.NoError(t, json.Unmarshal(b, &m))
+	if assert.Len(t, m.Results, 2) {
+		assert.Equal(t, 1, m.Results[0].Index)
+		assert.Equal(t, path, m.Results[0].Path)
+		assert.Equal(t, 3, m.Results[1].Index)
+		assert.Equal(t, path3, m.Results[1].Path)
+	}
+}
+
+func TestRunQueryAutoFormat(t *testing.T) {
+	query := "SELECT * FROM wide"
+	client := stub.NewClient(&stub.Result{
+		ID:    "TestRunQueryAutoFormat",
+		Query: query,
+		ResultSet: athena.ResultSet{
+			Rows: testhelper.CreateRows([][]string{{"a_long_column_name", "another_long_column_name"}, {"1", "2"}}),
+		},
+	})
+
+	tests := []struct {
+		width    int
+		output   string
+		vertical bool
+	}{
+		{width: 200, vertical: false},
+		{width: 20, vertical: true},
+		{width: 0, vertical: false},                     // Not a terminal
+		{width: 20, output: "out.txt", vertical: false}, // Saved to a file
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		a := New(client, &Config{Silent: true, Format: "auto", Output: tt.output}, &out).WithWaitInterval(testWaitInterval)
+		width := tt.width
+		a.termWidth = func() int { return width }
+		a.p = a.newPrinter()
+		a.RunQuery(query)
+
+		assert.Equal(t, tt.vertical, strings.Contains(out.String(), "-[ RECORD 1 ]"), "Width: %d, Output: %q", tt.width, tt.output)
+		assert.Contains(t, out.String(), "another_long_column_name")
+	}
+}
+
+func TestRunQueryVars(t *testing.T) {
+	query := "SELECT * FROM elb_logs WHERE day = DATE '2017-07-01'"
+	client := stub.NewClient(&stub.Result{ID: "TestRunQueryVars", Query: query})
+	vars := params.Vars{}
+	assert.NoError(t, vars.Set("day:date=2017-07-01"))
+
+	var out, errOut bytes.Buffer
+	a := New(client, &Config{Silent: true}, &out).WithStderr(&errOut).WithWaitInterval(testWaitInterval).WithVars(vars)
+	err := a.RunQuery("SELECT * FROM elb_logs WHERE day = :day")
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), query)
+	assert.Empty(t, errOut.String())
+
+	// Nothing is run if any variables are undefined
+	out.Reset()
+	errOut.Reset()
+	err = a.RunQuery("SELECT * FROM elb_logs WHERE day = :day; SELECT :undefined")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to bind variables: undefined variables: undefined")
 	}
+	assert.Empty(t, out.String())
 }
 
 func TestShowProgressMsg(t *testing.T) {
@@ -60,7 +326,7 @@ func TestShowProgressMsg(t *testing.T) {
 		stderr:          &out,
 		cfg:             &Config{},
 		refreshInterval: 5 * time.Millisecond,
-		waitInterval:    testWaitInterval,
+		backoff:         exec.ConstantBackoff(testWaitInterval),
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
 	defer cancel()
@@ -407,6 +673,7 @@ func TestSetupREPL(t *testing.T) {
 func TestRunREPL(t *testing.T) {
 	tests := []struct {
 		input    string
+		query    string
 		id       string
 		execTime int64
 		scanned  int64
@@ -424,7 +691,8 @@ func TestRunREPL(t *testing.T) {
 			want:  noStmtFound,
 		},
 		{
-			input:    "SHOW DATABASES\n",
+			input:    "SHOW DATABASES;\n",
+			query:    "SHOW DATABASES",
 			id:       "TestRunREPL_ShowDBs",
 			execTime: 12345,
 			scanned:  56789,
@@ -437,21 +705,41 @@ func TestRunREPL(t *testing.T) {
 			},
 			want: showDatabasesOutput,
 		},
+		{
+			input:    "SHOW\n\nDATABASES\n;\n",
+			query:    "SHOW\n\nDATABASES",
+			id:       "TestRunREPL_MultiLine",
+			execTime: 12345,
+			scanned:  56789,
+			rs: athena.ResultSet{
+				Rows: testhelper.CreateRows([][]string{
+					{"cloudfront_logs"},
+				}),
+			},
+			want: "Query: SHOW\n\nDATABASES;\n+-----------------+\n| cloudfront_logs |\n",
+		},
+		{
+			input: "SHOW DATABASES\n",
+			query: "SHOW DATABASES",
+			id:    "TestRunREPL_NotTerminated",
+			want:  "",
+		},
 	}
 
 	for _, tt := range tests {
 		in := strings.NewReader(tt.input)
 		var out bytes.Buffer
 		rl, err := readline.NewEx(&readline.Config{
-			Stdin:               in,
-			Stdout:              &out,
-			ForceUseInteractive: true,
+			Stdin:                  in,
+			Stdout:                 &out,
+			ForceUseInteractive:    true,
+			DisableAutoSaveHistory: true,
 		})
 		assert.NoError(t, err)
 
 		client := stub.NewClient(&stub.Result{
 			ID:           tt.id,
-			Query:        strings.TrimSpace(tt.input),
+			Query:        tt.query,
 			ExecTime:     tt.execTime,
 			ScannedBytes: tt.scanned,
 			ResultSet:    tt.rs,
@@ -468,9 +756,11 @@ func TestRunREPL(t *testing.T) {
 }
 
 type stubReadline struct {
-	query string
-	err   error
-	cnt   int
+	query   string
+	err     error
+	cnt     int
+	prompt  string
+	history []string
 }
 
 func (r *stubReadline) Readline() (string, error) {
@@ -481,10 +771,76 @@ func (r *stubReadline) Readline() (string, error) {
 	return r.query, r.err
 }
 
+func (r *stubReadline) SetPrompt(prompt string) {
+	r.prompt = prompt
+}
+
+func (r *stubReadline) SaveHistory(content string) error {
+	r.history = append(r.history, content)
+	return nil
+}
+
 func (r *stubReadline) Close() error {
 	return nil
 }
 
+// linesReadline is a readlineCloser which returns lines in order. An empty line with
+// readline.ErrInterrupt simulates Ctrl-C.
+type linesReadline struct {
+	stubReadline
+	lines   []string
+	errs    []error
+	prompts []string
+}
+
+func (r *linesReadline) Readline() (string, error) {
+	r.prompts = append(r.prompts, r.prompt)
+	if r.cnt >= len(r.lines) {
+		return "", io.EOF
+	}
+	i := r.cnt
+	r.cnt++
+	var err error
+	if i < len(r.errs) {
+		err = r.errs[i]
+	}
+	return r.lines[i], err
+}
+
+func TestRunREPLMultiLine(t *testing.T) {
+	rl := &linesReadline{
+		stubReadline: stubReadline{prompt: replPrompt},
+		lines: []string{
+			"SELECT *",
+			"",
+			"FROM t -- ;",
+			"WHERE s = ';'",
+			"; -- done",
+			"SELECT 'canceled",
+			"",
+			"SELECT 2;",
+		},
+		errs: []error{nil, nil, nil, nil, nil, nil, readline.ErrInterrupt, nil},
+	}
+	client := stub.NewClient(
+		&stub.Result{ID: "TestRunREPLMultiLine_1", Query: "SELECT *\n\nFROM t -- ;\nWHERE s = ';'"},
+		&stub.Result{ID: "TestRunREPLMultiLine_2", Query: "SELECT 2"},
+	)
+	var out bytes.Buffer
+	a := New(client, &Config{}, &out).WithStderr(ioutil.Discard).WithWaitInterval(testWaitInterval)
+	a.rl = rl
+	err := a.RunREPL()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		replPrompt, replContinuationPrompt, replContinuationPrompt, replContinuationPrompt,
+		replContinuationPrompt, replPrompt, replContinuationPrompt, replPrompt, replPrompt,
+	}, rl.prompts)
+	assert.Equal(t, []string{"SELECT * FROM t /* ; */ WHERE s = ';' ; /* done */", "SELECT 2;"}, rl.history)
+	assert.NotContains(t, out.String(), "canceled")
+	assert.Equal(t, 2, strings.Count(out.String(), "Run time:"))
+}
+
 func TestRunREPLError(t *testing.T) {
 	tests := []struct {
 		rl   readlineCloser
@@ -620,6 +976,217 @@ func TestSelectQueryExecutions(t *testing.T) {
 	}
 }
 
+type previewFilter struct {
+	*stubFilter
+	preview func(item string) string
+}
+
+func (f *previewFilter) SetPreview(fn func(item string) string) {
+	f.preview = fn
+}
+
+func TestFilterQueryExecutionsPreview(t *testing.T) {
+	query := "SELECT *\nFROM elb_logs\nLIMIT 1"
+	client := stub.NewClient(&stub.Result{
+		ID:         "TestFilterQueryExecutionsPreview",
+		Query:      query,
+		SubmitTime: time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC),
+	})
+	a := New(client, &Config{Silent: true}, &bytes.Buffer{}).WithWaitInterval(testWaitInterval)
+	f := &previewFilter{stubFilter: newStubFilter(0)}
+	a.f = f
+
+	qxs, err := a.selectQueryExecutions(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, qxs, 1)
+	if assert.NotNil(t, f.preview) {
+		assert.Equal(t, query, f.preview(f.lines[0]))
+	}
+}
+
+func TestSelectQueryExecutionsFinderNotFound(t *testing.T) {
+	a := New(stub.NewClient(), &Config{Finder: "athenai-no-such-finder"}, &bytes.Buffer{})
+	_, err := a.selectQueryExecutions(context.Background())
+	assert.Error(t, err)
+}
+
+func TestParseEntry(t *testing.T) {
+	dt := time.Date(2017, 7, 1, 9, 30, 0, 0, time.FixedZone("JST", 9*60*60))
+	qx := &athena.QueryExecution{
+		QueryExecutionId: aws.String("TestParseEntry"),
+		Query:            aws.String("SELECT '\t'\nFROM table"),
+		Status: &athena.QueryExecutionStatus{
+			SubmissionDateTime: &dt,
+			State:              aws.String(athena.QueryExecutionStateSucceeded),
+		},
+		Statistics: testhelper.CreateStats(1000, 2000),
+	}
+
+	got, err := parseEntry(generateEntry(qx))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "TestParseEntry", got.ID)
+		assert.Equal(t, "SELECT '\t' FROM table", got.Query)
+		assert.Equal(t, athena.QueryExecutionStateSucceeded, got.State)
+		assert.True(t, dt.Equal(got.SubmissionTime), "Got: %s", got.SubmissionTime)
+	}
+
+	for _, entry := range []string{"", "2017-07-01 00:00:00 +0000 UTC\tSHOW TABLES", "yesterday\tSHOW TABLES\tSUCCEEDED\t1.00 seconds\t2.00 KB\tid"} {
+		_, err := parseEntry(entry)
+		assert.Error(t, err, "Entry: %q", entry)
+	}
+}
+
+func TestShowResultsWithCriteria(t *testing.T) {
+	results := []*stub.Result{
+		{
+			ID:         "TestShowResultsWithCriteria_ShowTables",
+			Query:      "SHOW TABLES",
+			SubmitTime: time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC),
+			ResultSet:  athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"elb_logs"}})},
+		},
+		{
+			ID:         "TestShowResultsWithCriteria_ShowDatabases",
+			Query:      "SHOW DATABASES",
+			SubmitTime: time.Date(2017, 7, 1, 1, 0, 0, 0, time.UTC),
+			ResultSet:  athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"sampledb"}})},
+		},
+	}
+
+	tests := []struct {
+		criteria filter.Criteria
+		count    uint
+		want     string
+		notWant  string
+		wantErr  string
+	}{
+		{
+			criteria: filter.Criteria{IDs: []string{"TestShowResultsWithCriteria_ShowTables"}},
+			want:     "elb_logs",
+			notWant:  "sampledb",
+		},
+		{
+			// Older than the latest count of query executions
+			criteria: filter.Criteria{IDs: []string{"TestShowResultsWithCriteria_ShowTables"}},
+			count:    1,
+			want:     "elb_logs",
+			notWant:  "sampledb",
+		},
+		{
+			criteria: filter.Criteria{Match: regexp.MustCompile("TABLES")},
+			count:    1,
+			want:     "elb_logs",
+			notWant:  "sampledb",
+		},
+		{
+			criteria: filter.Criteria{Latest: 1},
+			want:     "sampledb",
+			notWant:  "elb_logs",
+		},
+		{
+			criteria: filter.Criteria{Since: time.Date(2017, 7, 1, 0, 30, 0, 0, time.UTC)},
+			count:    1,
+			want:     "sampledb",
+			notWant:  "elb_logs",
+		},
+		{
+			criteria: filter.Criteria{Since: time.Date(2017, 7, 1, 2, 0, 0, 0, time.UTC)},
+			notWant:  "Query:",
+			wantErr:  "no query executions match",
+		},
+		{
+			criteria: filter.Criteria{IDs: []string{"TestShowResultsWithCriteria_Missing"}},
+			notWant:  "Query:",
+			wantErr:  "TestShowResultsWithCriteria_Missing",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		client := stub.NewClient(results...)
+		client.PageSize = 1
+		a := New(client, &Config{Count: tt.count, Silent: true}, &out).
+			WithWaitInterval(testWaitInterval).
+			WithCriteria(tt.criteria)
+		err := a.ShowResults()
+		got := out.String()
+
+		if tt.wantErr == "" {
+			assert.NoError(t, err, "Criteria: %#v", tt.criteria)
+		} else if assert.Error(t, err, "Criteria: %#v", tt.criteria) {
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+		assert.Contains(t, got, tt.want, "Criteria: %#v", tt.criteria)
+		assert.NotContains(t, got, tt.notWant, "Criteria: %#v", tt.criteria)
+	}
+}
+
+func TestShowResultsStates(t *testing.T) {
+	results := []*stub.Result{
+		{
+			ID:         "TestShowResultsStates_Succeeded",
+			Query:      "SHOW DATABASES",
+			SubmitTime: time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC),
+			ResultSet:  athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"sampledb"}})},
+		},
+		{
+			ID:         "TestShowResultsStates_Failed",
+			Query:      "SELECT * FROM missing",
+			FinalState: stub.Failed,
+			Reason:     "Table sampledb.missing does not exist",
+			SubmitTime: time.Date(2017, 7, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:         "TestShowResultsStates_Running",
+			Query:      "SHOW TABLES",
+			Running:    true,
+			SubmitTime: time.Date(2017, 7, 1, 2, 0, 0, 0, time.UTC),
+			ResultSet:  athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"elb_logs"}})},
+		},
+	}
+
+	tests := []struct {
+		states   []string
+		wants    []string
+		notWants []string
+	}{
+		{
+			states:   nil,
+			wants:    []string{"sampledb"},
+			notWants: []string{"missing", "elb_logs"},
+		},
+		{
+			states:   []string{athena.QueryExecutionStateFailed},
+			wants:    []string{"Query: SELECT * FROM missing;", "State: FAILED", "Reason: Table sampledb.missing does not exist", "Submitted: 2017-07-01 01:00:00 +0000 UTC"},
+			notWants: []string{"SHOW DATABASES", "elb_logs"},
+		},
+		{
+			states:   []string{athena.QueryExecutionStateQueued, athena.QueryExecutionStateRunning},
+			wants:    []string{"elb_logs"},
+			notWants: []string{"sampledb", "missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		client := stub.NewClient(results...)
+		a := New(client, &Config{Silent: true}, &out).
+			WithWaitInterval(testWaitInterval).
+			WithStates(tt.states).
+			WithCriteria(filter.Criteria{Latest: 3})
+		a.ShowResults()
+		got := out.String()
+
+		for _, want := range tt.wants {
+			assert.Contains(t, got, want, "States: %q", tt.states)
+		}
+		for _, notWant := range tt.notWants {
+			assert.NotContains(t, got, notWant, "States: %q", tt.states)
+		}
+	}
+}
+
 func TestShowResults(t *testing.T) {
 	tests := []struct {
 		results  []*stub.Result
@@ -797,10 +1364,11 @@ func TestShowResultsError(t *testing.T) {
 		f := newStubFilter()
 		f.errMsg = tt.errMsg
 		a.f = f
-		a.ShowResults()
-		got := out.String()
+		err := a.ShowResults()
 
-		assert.Contains(t, got, tt.want, "Results: %#v", tt.results)
+		if assert.Error(t, err, "Results: %#v", tt.results) {
+			assert.Contains(t, err.Error(), tt.want, "Results: %#v", tt.results)
+		}
 	}
 }
 
@@ -857,3 +1425,183 @@ LIMIT 10`,
 		assert.Contains(t, got, tt.want, "Query: %q", tt.query)
 	}
 }
+
+func TestRunQueryStream(t *testing.T) {
+	query := "SELECT date, time, bytes FROM cloudfront_logs LIMIT 3; SHOW TABLES;"
+	results := []*stub.Result{
+		{
+			ID:           "TestRunQueryStream_Select",
+			Query:        "SELECT date, time, bytes FROM cloudfront_logs LIMIT 3",
+			ExecTime:     5555,
+			ScannedBytes: 6666,
+			RunningTime:  100 * time.Millisecond, // Slower than the second one
+			ResultSet: athena.ResultSet{
+				ResultSetMetadata: &athena.ResultSetMetadata{},
+				Rows: testhelper.CreateRows([][]string{
+					{"date", "time", "bytes"},
+					{"2014-07-05", "15:00:00", "4260"},
+					{"2014-07-05", "15:00:00", "10"},
+					{"2014-07-05", "15:00:00", "4252"},
+				}),
+			},
+		},
+		{
+			ID:           "TestRunQueryStream_ShowTables",
+			Query:        "SHOW TABLES",
+			ExecTime:     1111,
+			ScannedBytes: 2222,
+			ResultSet: athena.ResultSet{
+				ResultSetMetadata: &athena.ResultSetMetadata{},
+				Rows: testhelper.CreateRows([][]string{
+					{"cloudfront_logs"},
+					{"elb_logs"},
+					{"flights_parquet"},
+				}),
+			},
+		},
+	}
+
+	tests := []struct {
+		stream bool
+		wants  []string // In the order of appearance
+	}{
+		{
+			stream: false,
+			wants:  []string{selectOutput, showTablesOutput},
+		},
+		{
+			stream: true,
+			wants:  []string{"[2/2]" + showTablesOutput, "[1/2]" + selectOutput},
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		client := stub.NewClient(results...)
+		cfg := &Config{Database: "sampledb", Silent: true, Stream: tt.stream}
+		a := New(client, cfg, &out).WithWaitInterval(testWaitInterval)
+		a.RunQuery(query)
+		got := out.String()
+
+		idx := -1
+		for _, want := range tt.wants {
+			i := strings.Index(got, want)
+			assert.True(t, i > idx, "Stream: %t, Want: %q, Got: %q", tt.stream, want, got)
+			idx = i
+		}
+	}
+}
+
+func TestRunQueryIndependentAfterDependent(t *testing.T) {
+	query := `SELECT * FROM slow_table;
+-- @depends 1
+SELECT * FROM dependent_table;
+SELECT * FROM independent_table;`
+	results := []*stub.Result{
+		{
+			ID:          "TestRunQueryIndependentAfterDependent_Slow",
+			Query:       "SELECT * FROM slow_table",
+			RunningTime: 200 * time.Millisecond,
+		},
+		{
+			ID:    "TestRunQueryIndependentAfterDependent_Dependent",
+			Query: "SELECT * FROM dependent_table",
+		},
+		{
+			ID:    "TestRunQueryIndependentAfterDependent_Independent",
+			Query: "SELECT * FROM independent_table",
+		},
+	}
+	// The independent statement should not wait for the slow one
+	wants := []string{
+		"[3/3]\nQuery: SELECT * FROM independent_table;",
+		"[1/3]\nQuery: SELECT * FROM slow_table;",
+		"[2/3]\nQuery: SELECT * FROM dependent_table;",
+	}
+
+	var out bytes.Buffer
+	client := stub.NewClient(results...)
+	a := New(client, &Config{Database: "sampledb", Silent: true, Stream: true}, &out).
+		WithStderr(&out).
+		WithWaitInterval(testWaitInterval)
+	a.RunQuery(query)
+	got := out.String()
+
+	idx := -1
+	for _, want := range wants {
+		i := strings.Index(got, want)
+		assert.True(t, i > idx, "Want: %q, Got: %q", want, got)
+		idx = i
+	}
+}
+
+func TestRunQueryBarrier(t *testing.T) {
+	// Barriers only order statements, so a failed statement does not stop the ones after it
+	query := "SELECT * FROM broken_table; CREATE TABLE err_table (id int); SELECT * FROM err_table;"
+	results := []*stub.Result{
+		{
+			ID:         "TestRunQueryBarrier_Broken",
+			Query:      "SELECT * FROM broken_table",
+			FinalState: stub.Failed,
+			Reason:     "TABLE_NOT_FOUND",
+		},
+		{
+			ID:    "TestRunQueryBarrier_CreateTable",
+			Query: "CREATE TABLE err_table (id int)", // Always fails in the stub
+		},
+		{
+			ID:    "TestRunQueryBarrier_Select",
+			Query: "SELECT * FROM err_table",
+		},
+	}
+	wants := []string{
+		"TABLE_NOT_FOUND",
+		"is not an allowed statement",
+		"Query: SELECT * FROM err_table;",
+	}
+
+	var out bytes.Buffer
+	client := stub.NewClient(results...)
+	a := New(client, &Config{Database: "sampledb", Silent: true}, &out).
+		WithStderr(&out).
+		WithWaitInterval(testWaitInterval)
+	a.RunQuery(query)
+	got := out.String()
+
+	for _, want := range wants {
+		assert.Contains(t, got, want, "Query: %q", query)
+	}
+	assert.NotContains(t, got, "has been skipped", "Query: %q", query)
+}
+
+func TestRunQueryDependsFailed(t *testing.T) {
+	// Statements annotated with @depends are skipped if the statements they depend on have failed
+	query := "CREATE TABLE err_table (id int);\n-- @depends\nSHOW TABLES;"
+	results := []*stub.Result{
+		{
+			ID:    "TestRunQueryDependsFailed_CreateTable",
+			Query: "CREATE TABLE err_table (id int)", // Always fails in the stub
+		},
+		{
+			ID:    "TestRunQueryDependsFailed_ShowTables",
+			Query: "SHOW TABLES",
+		},
+	}
+	wants := []string{
+		"is not an allowed statement",
+		"statement 2 has been skipped since statement 1 it depends on has not succeeded",
+	}
+
+	var out bytes.Buffer
+	client := stub.NewClient(results...)
+	a := New(client, &Config{Database: "sampledb", Silent: true}, &out).
+		WithStderr(&out).
+		WithWaitInterval(testWaitInterval)
+	a.RunQuery(query)
+	got := out.String()
+
+	for _, want := range wants {
+		assert.Contains(t, got, want, "Query: %q", query)
+	}
+	assert.NotContains(t, got, "Query: SHOW TABLES", "Query: %q", query)
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/pkg/errors"
+)
+
+// maxQueryLen is the maximum length of queries shown in a list of query executions to stop.
+const maxQueryLen = 60
+
+// stoppable returns true if qx is queued or running, otherwise false.
+func stoppable(qx *athena.QueryExecution) bool {
+	state := aws.StringValue(qx.Status.State)
+	return state == athena.QueryExecutionStateQueued || state == athena.QueryExecutionStateRunning
+}
+
+// batchGetQueryExecutions gets the query executions whose IDs are ids in the order of ids.
+// It returns an error if any of them is not found.
+func (a *Athenai) batchGetQueryExecutions(ctx context.Context, ids []string) ([]*athena.QueryExecution, error) {
+	qxs := make([]*athena.QueryExecution, 0, len(ids))
+	for start := 0; start < len(ids); start += maxResults {
+		end := start + maxResults
+		if end > len(ids) {
+			end = len(ids)
+		}
+		out, err := a.client.BatchGetQueryExecutionWithContext(ctx, &athena.BatchGetQueryExecutionInput{
+			QueryExecutionIds: aws.StringSlice(ids[start:end]),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "BatchGetQueryExecution API error")
+		}
+		if len(out.UnprocessedQueryExecutionIds) > 0 {
+			u := out.UnprocessedQueryExecutionIds[0]
+			return nil, errors.Errorf("failed to get QueryExecutionId %s: %s: %s", aws.StringValue(u.QueryExecutionId),
+				aws.StringValue(u.ErrorCode), aws.StringValue(u.ErrorMessage))
+		}
+		qxs = append(qxs, out.QueryExecutions...)
+	}
+	return qxs, nil
+}
+
+// findQueryExecutionsToStop returns the query executions to stop. If ids is not empty, they are
+// the ones whose IDs are ids, otherwise the queued or running ones among the latest query
+// executions whose queries match match (nil matches all of them).
+func (a *Athenai) findQueryExecutionsToStop(ctx context.Context, ids []string, match *regexp.Regexp) ([]*athena.QueryExecution, error) {
+	if len(ids) > 0 {
+		found, err := a.batchGetQueryExecutions(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		qxs := make([]*athena.QueryExecution, 0, len(found))
+		for _, qx := range found {
+			if !stoppable(qx) {
+				id, state := aws.StringValue(qx.QueryExecutionId), aws.StringValue(qx.Status.State)
+				log.Printf("Skipping QueryExecutionId %s because of %s state\n", id, state)
+				a.printE(fmt.Sprintf("Skipping %s since it is already %s\n", id, state))
+				continue
+			}
+			qxs = append(qxs, qx)
+		}
+		return qxs, nil
+	}
+
+	all, err := a.fetchQueryExecutions(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching query executions")
+	}
+	var qxs []*athena.QueryExecution
+	for _, qx := range all {
+		if stoppable(qx) && (match == nil || match.MatchString(aws.StringValue(qx.Query))) {
+			qxs = append(qxs, qx)
+		}
+	}
+	return qxs, nil
+}
+
+// confirm asks the user msg and returns true if the answer is yes, otherwise false.
+func (a *Athenai) confirm(msg string) bool {
+	a.printE(msg + " [y/N]: ")
+	answer, err := bufio.NewReader(a.stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		a.printE("\n")
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// summarizeQuery returns the first line of query shortened to maxQueryLen.
+func summarizeQuery(query string) string {
+	q := strings.TrimSpace(query)
+	multiline := false
+	if i := strings.IndexByte(q, '\n'); i >= 0 {
+		q, multiline = strings.TrimSpace(q[:i]), true
+	}
+	if r := []rune(q); len(r) > maxQueryLen {
+		q, multiline = string(r[:maxQueryLen]), true
+	}
+	if multiline {
+		q += "..."
+	}
+	return q
+}
+
+// CancelQueryExecutions stops query executions with StopQueryExecution API. If ids is not empty,
+// it stops the ones whose IDs are ids, otherwise all the queued or running ones among the latest
+// query executions whose queries match match (nil matches all of them).
+// Unless yes is true, it asks for confirmation before stopping them.
+func (a *Athenai) CancelQueryExecutions(ids []string, match *regexp.Regexp, yes bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if !a.cfg.Silent && len(ids) == 0 {
+		go a.showProgressMsg(ctx, loadingHistoryMsg)
+	}
+	qxs, err := a.findQueryExecutionsToStop(ctx, ids, match)
+	cancel() // Stop printing loading messages
+	if !a.cfg.Silent && len(ids) == 0 {
+		a.printE("\n")
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(qxs) == 0 {
+		a.println("No running query executions to stop")
+		return nil
+	}
+
+	for _, qx := range qxs {
+		a.println(fmt.Sprintf("%s  %-9s  %s", aws.StringValue(qx.QueryExecutionId),
+			aws.StringValue(qx.Status.State), summarizeQuery(aws.StringValue(qx.Query))))
+	}
+	if !yes && !a.confirm(fmt.Sprintf("Stop %d query executions?", len(qxs))) {
+		a.println("Aborted")
+		return nil
+	}
+
+	failed := 0
+	for _, qx := range qxs {
+		id := aws.StringValue(qx.QueryExecutionId)
+		log.Printf("Stopping QueryExecutionId %s\n", id)
+		if _, err := a.client.StopQueryExecution(&athena.StopQueryExecutionInput{QueryExecutionId: &id}); err != nil {
+			a.printErr(err, "failed to stop "+id)
+			failed++
+			continue
+		}
+		a.println("Stopped", id)
+	}
+
+	if failed > 0 {
+		return errors.Errorf("failed to stop %d of %d query executions", failed, len(qxs))
+	}
+	return nil
+}
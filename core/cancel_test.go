@@ -0,0 +1,130 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skatsuta/athenai/internal/bytes"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCancelTestResults() []*stub.Result {
+	return []*stub.Result{
+		{
+			ID:         "TestCancel_Succeeded",
+			Query:      "SHOW DATABASES",
+			SubmitTime: time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:          "TestCancel_RunningCloudFront",
+			Query:       "SELECT * FROM cloudfront_logs",
+			Running:     true,
+			RunningTime: time.Minute,
+			SubmitTime:  time.Date(2017, 7, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:          "TestCancel_RunningELB",
+			Query:       "SELECT * FROM elb_logs",
+			Running:     true,
+			RunningTime: time.Minute,
+			SubmitTime:  time.Date(2017, 7, 1, 2, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func TestCancelQueryExecutions(t *testing.T) {
+	tests := []struct {
+		ids         []string
+		match       *regexp.Regexp
+		yes         bool
+		stdin       string
+		wantStopped []string
+		want        string
+	}{
+		{
+			match:       regexp.MustCompile("cloudfront"),
+			yes:         true,
+			wantStopped: []string{"TestCancel_RunningCloudFront"},
+			want:        "Stopped TestCancel_RunningCloudFront",
+		},
+		{
+			stdin:       "y\n",
+			wantStopped: []string{"TestCancel_RunningCloudFront", "TestCancel_RunningELB"},
+			want:        "Stopped TestCancel_RunningELB",
+		},
+		{
+			stdin: "n\n",
+			want:  "Aborted",
+		},
+		{
+			stdin: "",
+			want:  "Aborted",
+		},
+		{
+			match: regexp.MustCompile("no_such_table"),
+			want:  "No running query executions to stop",
+		},
+		{
+			ids:         []string{"TestCancel_Succeeded", "TestCancel_RunningELB"},
+			yes:         true,
+			wantStopped: []string{"TestCancel_RunningELB"},
+			want:        "Stopped TestCancel_RunningELB",
+		},
+	}
+
+	for _, tt := range tests {
+		results := newCancelTestResults()
+		var out, errOut bytes.Buffer
+		a := New(stub.NewClient(results...), &Config{Silent: true}, &out).WithStderr(&errOut)
+		a.stdin = strings.NewReader(tt.stdin)
+		err := a.CancelQueryExecutions(tt.ids, tt.match, tt.yes)
+
+		assert.NoError(t, err, "IDs: %q, Match: %v", tt.ids, tt.match)
+		assert.Contains(t, out.String(), tt.want, "IDs: %q, Match: %v", tt.ids, tt.match)
+		var stopped []string
+		for _, r := range results {
+			if r.FinalState == stub.Cancelled {
+				stopped = append(stopped, r.ID)
+			}
+		}
+		assert.Equal(t, tt.wantStopped, stopped, "IDs: %q, Match: %v", tt.ids, tt.match)
+	}
+}
+
+func TestCancelQueryExecutionsSkipped(t *testing.T) {
+	var out, errOut bytes.Buffer
+	a := New(stub.NewClient(newCancelTestResults()...), &Config{Silent: true}, &out).WithStderr(&errOut)
+	err := a.CancelQueryExecutions([]string{"TestCancel_Succeeded"}, nil, true)
+
+	assert.NoError(t, err)
+	assert.Contains(t, errOut.String(), "Skipping TestCancel_Succeeded since it is already SUCCEEDED")
+	assert.Contains(t, out.String(), "No running query executions to stop")
+}
+
+func TestCancelQueryExecutionsError(t *testing.T) {
+	var out bytes.Buffer
+	a := New(stub.NewClient(newCancelTestResults()...), &Config{Silent: true}, &out).WithStderr(&out)
+	err := a.CancelQueryExecutions([]string{"TestCancel_Unknown"}, nil, true)
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "QueryExecution TestCancel_Unknown was not found")
+	}
+}
+
+func TestSummarizeQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "SELECT 1", want: "SELECT 1"},
+		{query: "  SELECT *\n  FROM cloudfront_logs", want: "SELECT *..."},
+		{query: strings.Repeat("x", 70), want: strings.Repeat("x", 60) + "..."},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, summarizeQuery(tt.query), "Query: %q", tt.query)
+	}
+}
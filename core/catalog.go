@@ -0,0 +1,287 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/exec"
+)
+
+const (
+	// defaultCatalogTTL is a default duration for which the catalog cache is fresh.
+	defaultCatalogTTL = 24 * time.Hour
+
+	refreshingCatalogMsg = "Refreshing catalog..."
+)
+
+// catalogFileName returns a name of the catalog cache file for region.
+func catalogFileName(region string) string {
+	if region == "" {
+		return "catalog.json"
+	}
+	return fmt.Sprintf("catalog.%s.json", region)
+}
+
+// catalog represents names of databases, tables and columns in Athena.
+type catalog struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Databases maps database names to their tables, which map table names to their column names.
+	// Column names are fetched only for tables in the database in use, so they may be nil.
+	Databases map[string]map[string][]string `json:"databases"`
+}
+
+// databases returns the sorted names of databases in cat.
+func (cat *catalog) databases() []string {
+	if cat == nil {
+		return nil
+	}
+	names := make([]string, 0, len(cat.Databases))
+	for db := range cat.Databases {
+		names = append(names, db)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tables returns the sorted names of tables in database db.
+func (cat *catalog) tables(db string) []string {
+	if cat == nil {
+		return nil
+	}
+	tables := cat.Databases[db]
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// columns returns the names of columns in table of database db.
+func (cat *catalog) columns(db, table string) []string {
+	if cat == nil {
+		return nil
+	}
+	return cat.Databases[db][table]
+}
+
+// catalogCache is a cache of catalog persisted to a file.
+// It is goroutine-safe, and reading the cache never waits for refreshing it.
+type catalogCache struct {
+	mu         sync.RWMutex
+	path       string
+	ttl        time.Duration
+	cat        *catalog
+	refreshing int32 // 1 while refreshing, accessed atomically
+}
+
+// newCatalogCache creates a new catalogCache saved to path.
+func newCatalogCache(path string, ttl time.Duration) *catalogCache {
+	if ttl <= 0 {
+		ttl = defaultCatalogTTL
+	}
+	return &catalogCache{path: path, ttl: ttl}
+}
+
+// get returns the cached catalog, which must not be modified. It returns nil if not cached yet.
+func (c *catalogCache) get() *catalog {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cat
+}
+
+// set replaces the cached catalog with cat.
+func (c *catalogCache) set(cat *catalog) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cat = cat
+}
+
+// expired returns true if the catalog has not been cached or the cache is older than TTL.
+func (c *catalogCache) expired() bool {
+	cat := c.get()
+	return cat == nil || time.Since(cat.UpdatedAt) > c.ttl
+}
+
+// load loads the catalog from the cache file.
+func (c *catalogCache) load() error {
+	b, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read catalog cache file")
+	}
+
+	cat := &catalog{}
+	if err := json.Unmarshal(b, cat); err != nil {
+		return errors.Wrap(err, "failed to decode catalog cache file")
+	}
+	c.set(cat)
+	log.Printf("Loaded catalog cache updated at %s from %s\n", cat.UpdatedAt, c.path)
+	return nil
+}
+
+// save saves the cached catalog to the cache file.
+func (c *catalogCache) save() error {
+	b, err := json.Marshal(c.get())
+	if err != nil {
+		return errors.Wrap(err, "failed to encode catalog")
+	}
+
+	// Write to a temporary file and then rename it not to leave a broken cache file
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return errors.Wrap(err, "failed to write catalog cache file")
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return errors.Wrap(err, "failed to replace catalog cache file")
+	}
+	log.Println("Saved catalog cache to", c.path)
+	return nil
+}
+
+// refresh fetches the catalog with fetch and then caches and saves it.
+// If another refresh is in progress, it returns immediately without doing anything.
+func (c *catalogCache) refresh(fetch func() (*catalog, error)) (*catalog, error) {
+	if !atomic.CompareAndSwapInt32(&c.refreshing, 0, 1) {
+		return nil, errors.New("catalog is being refreshed already")
+	}
+	defer atomic.StoreInt32(&c.refreshing, 0)
+
+	cat, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.set(cat)
+	return cat, c.save()
+}
+
+// refreshCatalog refreshes the catalog cache of a.
+func (a *Athenai) refreshCatalog(ctx context.Context) (*catalog, error) {
+	return a.catalog.refresh(func() (*catalog, error) {
+		return a.fetchCatalog(ctx)
+	})
+}
+
+// refreshCatalogInBackground refreshes the catalog cache of a in a new goroutine if it has been
+// expired. Errors are just logged.
+func (a *Athenai) refreshCatalogInBackground() {
+	if !a.catalog.expired() {
+		return
+	}
+
+	go func() {
+		log.Println("Refreshing expired catalog cache in background")
+		if _, err := a.refreshCatalog(context.Background()); err != nil {
+			log.Println("Error refreshing catalog cache in background:", err)
+		}
+	}()
+}
+
+// fetchCatalog fetches names of all the databases and tables, and names of columns in the tables
+// of the database in use, by running SHOW DATABASES, SHOW TABLES and DESCRIBE statements.
+func (a *Athenai) fetchCatalog(ctx context.Context) (*catalog, error) {
+	// Take a snapshot of the config since the database in use can be changed in REPL meanwhile
+	a.mu.RLock()
+	qcfg := a.cfg.QueryConfig()
+	concurrent := a.cfg.Concurrent
+	a.mu.RUnlock()
+	current := qcfg.Database
+
+	dbs, err := a.queryNames(ctx, qcfg, "SHOW DATABASES")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch databases")
+	}
+
+	if concurrent == 0 {
+		concurrent = defaultConcurrentcy
+	}
+
+	cat := &catalog{
+		UpdatedAt: time.Now(),
+		Databases: make(map[string]map[string][]string, len(dbs)),
+	}
+	var mu sync.Mutex // Guards cat
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrent)
+
+	// run runs fn concurrently, limiting the number of concurrent query executions
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			fn()
+		}()
+	}
+
+	for _, db := range dbs {
+		cat.Databases[db] = make(map[string][]string)
+		db := db
+		run(func() {
+			tables, err := a.queryNames(ctx, qcfg, fmt.Sprintf("SHOW TABLES IN `%s`", db))
+			if err != nil {
+				log.Printf("Skipping tables in database %s: %s\n", db, err)
+				return
+			}
+			mu.Lock()
+			for _, table := range tables {
+				cat.Databases[db][table] = nil
+			}
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	for _, table := range cat.tables(current) {
+		table := table
+		run(func() {
+			cols, err := a.queryNames(ctx, qcfg, fmt.Sprintf("DESCRIBE `%s`.`%s`", current, table))
+			if err != nil {
+				log.Printf("Skipping columns in table %s.%s: %s\n", current, table, err)
+				return
+			}
+			mu.Lock()
+			cat.Databases[current][table] = cols
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	return cat, nil
+}
+
+// queryNames runs query with cfg and returns the names in the first column of its results.
+// Results of DESCRIBE statements, which contain comment lines starting with `#`, blank lines
+// and tab-separated types, are also supported.
+func (a *Athenai) queryNames(ctx context.Context, cfg *exec.QueryConfig, query string) ([]string, error) {
+	q := exec.NewQuery(a.client, cfg, query).WithBackoff(a.backoff)
+	r, err := q.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, row := range r.Rows() {
+		if len(row) == 0 {
+			continue
+		}
+		name := strings.TrimSpace(strings.SplitN(row[0], "\t", 2)[0])
+		if name == "" || strings.HasPrefix(name, "#") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/bytes"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogFileName(t *testing.T) {
+	assert.Equal(t, "catalog.us-east-1.json", catalogFileName("us-east-1"))
+	assert.Equal(t, "catalog.json", catalogFileName(""))
+}
+
+func TestFetchCatalog(t *testing.T) {
+	client := stub.NewClient(
+		&stub.Result{
+			ID:        "TestFetchCatalog_ShowDatabases",
+			Query:     "SHOW DATABASES",
+			ResultSet: athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"sampledb"}, {"testdb"}, {"brokendb"}})},
+		},
+		&stub.Result{
+			ID:        "TestFetchCatalog_ShowTablesInSampledb",
+			Query:     "SHOW TABLES IN `sampledb`",
+			ResultSet: athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"elb_logs"}, {"flights"}})},
+		},
+		&stub.Result{
+			ID:        "TestFetchCatalog_ShowTablesInTestdb",
+			Query:     "SHOW TABLES IN `testdb`",
+			ResultSet: athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"users"}})},
+		},
+		&stub.Result{
+			ID:    "TestFetchCatalog_DescribeElbLogs",
+			Query: "DESCRIBE `sampledb`.`elb_logs`",
+			ResultSet: athena.ResultSet{Rows: testhelper.CreateRows([][]string{
+				{"request_timestamp   \tstring              \t                    "},
+				{"elb_name            \tstring              \t                    "},
+				{"                    "},
+				{"# Partition Information"},
+				{"# col_name            \tdata_type           \tcomment             "},
+				{"request_timestamp   \tstring              \t                    "},
+			})},
+		},
+		&stub.Result{
+			ID:        "TestFetchCatalog_DescribeFlights",
+			Query:     "DESCRIBE `sampledb`.`flights`",
+			ResultSet: athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"year\tint\t"}, {"origin\tstring\t"}})},
+		},
+	)
+	cfg := &Config{Database: "sampledb", Location: "s3://bucket/"}
+	a := New(client, cfg, &bytes.Buffer{}).WithWaitInterval(testWaitInterval)
+
+	cat, err := a.fetchCatalog(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"brokendb", "sampledb", "testdb"}, cat.databases())
+	assert.Equal(t, []string{"elb_logs", "flights"}, cat.tables("sampledb"))
+	assert.Equal(t, []string{"users"}, cat.tables("testdb"))
+	assert.Empty(t, cat.tables("brokendb"))
+	assert.Equal(t, []string{"request_timestamp", "elb_name"}, cat.columns("sampledb", "elb_logs"))
+	assert.Equal(t, []string{"year", "origin"}, cat.columns("sampledb", "flights"))
+	assert.Nil(t, cat.columns("testdb", "users"))
+}
+
+func TestFetchCatalogWhileUsingDatabase(t *testing.T) {
+	client := stub.NewClient(
+		&stub.Result{
+			ID:        "TestFetchCatalogWhileUsingDatabase_ShowDatabases",
+			Query:     "SHOW DATABASES",
+			ResultSet: athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"sampledb"}})},
+		},
+		&stub.Result{ID: "TestFetchCatalogWhileUsingDatabase_ShowTables", Query: "SHOW TABLES IN `sampledb`"},
+	)
+	cfg := &Config{Database: "sampledb", Location: "s3://bucket/"}
+	a := New(client, cfg, &bytes.Buffer{}).WithWaitInterval(testWaitInterval)
+
+	// The database in use is changed by the user while the catalog is being fetched
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, db := range []string{"testdb", "sampledb", "testdb"} {
+			assert.NoError(t, metaUse(a, []string{db}))
+		}
+	}()
+	_, err := a.fetchCatalog(context.Background())
+	<-done
+
+	assert.NoError(t, err)
+}
+
+func TestCatalogCacheSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "athenai-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, catalogFileName("us-east-1"))
+
+	c := newCatalogCache(path, time.Hour)
+	assert.True(t, c.expired())
+	assert.Error(t, c.load())
+
+	cat := &catalog{
+		UpdatedAt: time.Now().Truncate(time.Second),
+		Databases: map[string]map[string][]string{"sampledb": {"elb_logs": {"elb_name"}}},
+	}
+	got, err := c.refresh(func() (*catalog, error) { return cat, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, cat, got)
+	assert.False(t, c.expired())
+
+	loaded := newCatalogCache(path, time.Hour)
+	if assert.NoError(t, loaded.load()) {
+		assert.True(t, cat.UpdatedAt.Equal(loaded.get().UpdatedAt))
+		assert.Equal(t, cat.Databases, loaded.get().Databases)
+	}
+
+	stale := newCatalogCache(path, time.Nanosecond)
+	assert.NoError(t, stale.load())
+	assert.True(t, stale.expired())
+}
+
+func TestCatalogCacheRefreshInProgress(t *testing.T) {
+	c := newCatalogCache(filepath.Join(os.TempDir(), "unused.json"), 0)
+	assert.Equal(t, defaultCatalogTTL, c.ttl)
+
+	atomic.StoreInt32(&c.refreshing, 1)
+	_, err := c.refresh(func() (*catalog, error) {
+		t.Fatal("fetch should not be called while refreshing")
+		return nil, nil
+	})
+	assert.Error(t, err)
+}
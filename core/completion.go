@@ -0,0 +1,131 @@
+package core
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sqlKeywords are SQL keywords to be completed in REPL.
+var sqlKeywords = []string{
+	"ALL", "ALTER", "AND", "ANY", "ARRAY", "AS", "ASC", "BETWEEN", "BY", "CASE", "CAST", "COLUMNS",
+	"CREATE", "CROSS", "CUBE", "DATABASE", "DATABASES", "DESC", "DESCRIBE", "DISTINCT", "DROP",
+	"ELSE", "END", "EXCEPT", "EXISTS", "EXPLAIN", "EXTERNAL", "FALSE", "FIRST", "FROM", "FULL",
+	"GROUP", "GROUPING", "HAVING", "IF", "IN", "INNER", "INSERT", "INTERSECT", "INTERVAL", "INTO",
+	"IS", "JOIN", "LAST", "LEFT", "LIKE", "LIMIT", "LOCATION", "MAP", "MSCK", "NOT", "NULL",
+	"NULLS", "ON", "OR", "ORDER", "OUTER", "OVER", "PARTITION", "PARTITIONED", "PARTITIONS",
+	"REPAIR", "RIGHT", "ROLLUP", "ROW", "ROWS", "SCHEMA", "SCHEMAS", "SELECT", "SET", "SHOW",
+	"TABLE", "TABLES", "TBLPROPERTIES", "THEN", "TRUE", "UNION", "UNNEST", "USING", "VALUES",
+	"VIEW", "VIEWS", "WHEN", "WHERE", "WITH",
+}
+
+// sqlFunctions are Athena (Presto) functions to be completed in REPL.
+var sqlFunctions = []string{
+	"abs", "approx_distinct", "approx_percentile", "array_agg", "array_join", "avg",
+	"cardinality", "ceil", "coalesce", "concat", "contains", "count", "current_date",
+	"current_timestamp", "date_add", "date_diff", "date_format", "date_parse", "date_trunc",
+	"day", "element_at", "floor", "format_datetime", "from_iso8601_timestamp",
+	"from_unixtime", "hour", "if", "json_extract", "json_extract_scalar", "json_parse",
+	"length", "lower", "max", "max_by", "min", "min_by", "minute", "month", "now",
+	"nullif", "regexp_extract", "regexp_like", "regexp_replace", "replace", "round",
+	"row_number", "split", "split_part", "strpos", "substr", "sum", "to_unixtime", "trim",
+	"try", "try_cast", "upper", "url_extract_host", "url_extract_parameter",
+	"url_extract_path", "year",
+}
+
+// completer completes SQL keywords, functions and names of databases, tables and columns in
+// REPL. It implements readline.AutoCompleter.
+// It only reads the catalog cache so as not to block while the user types.
+type completer struct {
+	cache    *catalogCache
+	database func() string // Returns the name of the database in use
+}
+
+// isNameRune returns true if r can be a part of a (qualified) name, otherwise false.
+func isNameRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Do returns the candidates to complete the word before pos in line, and the length of the word.
+func (c *completer) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && isNameRune(line[start-1]) {
+		start--
+	}
+	word := string(line[start:pos])
+	if word == "" {
+		return nil, 0
+	}
+
+	cat := c.cache.get()
+	current := c.database()
+
+	var candidates []string
+	prefix := word
+	if i := strings.LastIndex(word, "."); i >= 0 {
+		// Qualified name: `db.table`, `table.column` or `db.table.column`
+		qualifier, name := word[:i], word[i+1:]
+		prefix = name
+		parts := strings.Split(qualifier, ".")
+		switch len(parts) {
+		case 1:
+			if tables := cat.tables(parts[0]); len(tables) > 0 {
+				candidates = tables
+			} else {
+				candidates = cat.columns(current, parts[0])
+			}
+		case 2:
+			candidates = cat.columns(parts[0], parts[1])
+		}
+	} else {
+		candidates = append(candidates, matchCase(sqlKeywords, word)...)
+		candidates = append(candidates, sqlFunctions...)
+		candidates = append(candidates, cat.databases()...)
+		tables := cat.tables(current)
+		candidates = append(candidates, tables...)
+		for _, table := range tables {
+			candidates = append(candidates, cat.columns(current, table)...)
+		}
+	}
+
+	return suffixes(candidates, prefix), len([]rune(prefix))
+}
+
+// matchCase converts keywords into lower case if word is in lower case.
+func matchCase(keywords []string, word string) []string {
+	if strings.ToLower(word) != word {
+		return keywords
+	}
+	lower := make([]string, len(keywords))
+	for i, kwd := range keywords {
+		lower[i] = strings.ToLower(kwd)
+	}
+	return lower
+}
+
+// suffixes returns the sorted unique remainders of candidates which start with prefix
+// case-insensitively. The remainders are cut from the candidates by the number of characters of
+// prefix, since the case of a character can change its length in bytes.
+func suffixes(candidates []string, prefix string) [][]rune {
+	l := utf8.RuneCountInString(prefix)
+	seen := make(map[string]bool)
+	var matched []string
+	for _, cand := range candidates {
+		if seen[cand] {
+			continue
+		}
+		if rs := []rune(cand); len(rs) < l || !strings.EqualFold(string(rs[:l]), prefix) {
+			continue
+		}
+		seen[cand] = true
+		matched = append(matched, cand)
+	}
+	sort.Strings(matched)
+
+	sufs := make([][]rune, len(matched))
+	for i, m := range matched {
+		sufs[i] = []rune(m)[l:]
+	}
+	return sufs
+}
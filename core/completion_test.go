@@ -0,0 +1,84 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleterDo(t *testing.T) {
+	cache := newCatalogCache("", 0)
+	cache.set(&catalog{
+		Databases: map[string]map[string][]string{
+			"sampledb": {
+				"elb_logs": {"elb_name", "request_ip"},
+				"flights":  {"origin", "elapsed"},
+			},
+			"testdb": {"users": nil},
+		},
+	})
+	c := &completer{cache: cache, database: func() string { return "sampledb" }}
+
+	tests := []struct {
+		line       string
+		want       []string
+		wantLength int
+	}{
+		{line: "SELECT * FROM t WHE", want: []string{"N", "RE"}, wantLength: 3},
+		{line: "select * from t whe", want: []string{"n", "re"}, wantLength: 3},
+		{line: "SELECT * FR", want: []string{"OM", "om_iso8601_timestamp", "om_unixtime"}, wantLength: 2},
+		{line: "SELECT approx_d", want: []string{"istinct"}, wantLength: 8},
+		{line: "SELECT el", want: []string{"apsed", "b_logs", "b_name", "ement_at", "se"}, wantLength: 2},
+		{line: "SELECT * FROM te", want: []string{"stdb"}, wantLength: 2},
+		{line: "SELECT * FROM testdb.", want: []string{"users"}, wantLength: 0},
+		{line: "SELECT * FROM sampledb.F", want: []string{"lights"}, wantLength: 1},
+		{line: "SELECT flights.or", want: []string{"igin"}, wantLength: 2},
+		{line: "SELECT sampledb.elb_logs.", want: []string{"elb_name", "request_ip"}, wantLength: 0},
+		{line: "SELECT unknown.", want: []string{}, wantLength: 0},
+		{line: "SELECT ", want: nil, wantLength: 0},
+	}
+
+	for _, tt := range tests {
+		got, length := c.Do([]rune(tt.line), len([]rune(tt.line)))
+		var gotStrs []string
+		if got != nil {
+			gotStrs = make([]string, len(got))
+			for i, s := range got {
+				gotStrs[i] = string(s)
+			}
+		}
+		assert.Equal(t, tt.want, gotStrs, "Line: %q", tt.line)
+		assert.Equal(t, tt.wantLength, length, "Line: %q", tt.line)
+	}
+}
+
+func TestCompleterDoWithoutCatalog(t *testing.T) {
+	c := &completer{cache: newCatalogCache("", 0), database: func() string { return "" }}
+	got, length := c.Do([]rune("SEL"), 3)
+	assert.Equal(t, [][]rune{[]rune("ECT")}, got)
+	assert.Equal(t, 3, length)
+}
+
+func TestSuffixes(t *testing.T) {
+	tests := []struct {
+		candidates []string
+		prefix     string
+		want       []string
+	}{
+		{candidates: []string{"elb_logs", "ELEMENT_AT", "flights", "elb_logs"}, prefix: "El", want: []string{"EMENT_AT", "b_logs"}},
+		// The lowercase of Ⱥ is longer than it in bytes, and that of K (Kelvin sign) is shorter
+		{candidates: []string{"Ⱥpple", "ⱥvocado"}, prefix: "ⱥ", want: []string{"pple", "vocado"}},
+		{candidates: []string{"Ⱥpple", "ⱥvocado"}, prefix: "Ⱥ", want: []string{"pple", "vocado"}},
+		{candidates: []string{"Kelvin", "kilo"}, prefix: "K", want: []string{"elvin", "ilo"}},
+		{candidates: []string{"é"}, prefix: "éa", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		got := suffixes(tt.candidates, tt.prefix)
+		gotStrs := make([]string, len(got))
+		for i, s := range got {
+			gotStrs[i] = string(s)
+		}
+		assert.Equal(t, tt.want, gotStrs, "Candidates: %q, Prefix: %q", tt.candidates, tt.prefix)
+	}
+}
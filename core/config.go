@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/skatsuta/athenai/exec"
+	"github.com/skatsuta/athenai/export"
+	"github.com/skatsuta/athenai/params"
+	"github.com/skatsuta/athenai/print"
 	"gopkg.in/ini.v1"
 )
 
@@ -31,6 +35,37 @@ type Config struct {
 	Format     string `ini:"format"`
 	Count      uint   `ini:"count"`
 	Concurrent uint   `ini:"concurrent"`
+	Stream     bool   `ini:"stream"`
+	Finder     string `ini:"finder"`
+
+	Timeout         time.Duration `ini:"timeout"`
+	PollInterval    time.Duration `ini:"poll_interval"`
+	PollMaxInterval time.Duration `ini:"poll_max_interval"`
+	PollMultiplier  float64       `ini:"poll_multiplier"`
+	PollJitter      float64       `ini:"poll_jitter"`
+
+	RetryMaxAttempts int           `ini:"retry_max_attempts"`
+	RetryInterval    time.Duration `ini:"retry_interval"`
+	RetryMaxInterval time.Duration `ini:"retry_max_interval"`
+	RetryCodes       []string      `ini:"retry_codes"`
+
+	RetryFailed         int      `ini:"retry_failed"`
+	RetryFailedPatterns []string `ini:"retry_failed_patterns"`
+
+	CatalogTTL time.Duration `ini:"catalog_ttl"`
+
+	PricePerTB float64 `ini:"price_per_tb"`
+	MaxScan    string  `ini:"max_scan"`
+
+	Fetch      string `ini:"fetch"`
+	S3Endpoint string `ini:"s3_endpoint"`
+
+	Vars     []string `ini:"-"`
+	VarsFile string   `ini:"vars_file"`
+
+	Export       string `ini:"export"`
+	ExportFile   string `ini:"export_file"`
+	ExportFormat string `ini:"export_format"`
 
 	iniCfg *ini.File `ini:"-"`
 }
@@ -45,6 +80,91 @@ func (c *Config) QueryConfig() *exec.QueryConfig {
 	}
 }
 
+// Backoff creates an exec.Backoff to poll query executions based on c.
+// If the poll interval is not specified, exec.DefaultBackoff is returned.
+func (c *Config) Backoff() exec.Backoff {
+	if c.PollInterval <= 0 {
+		return exec.DefaultBackoff
+	}
+	return exec.Backoff{
+		Initial:    c.PollInterval,
+		Multiplier: c.PollMultiplier,
+		Max:        c.PollMaxInterval,
+		Jitter:     c.PollJitter,
+	}
+}
+
+// MaxScanBytes returns the maximum bytes for each query execution to scan, which is parsed from
+// a human readable size such as "10GB". Zero means no limit.
+func (c *Config) MaxScanBytes() (int64, error) {
+	if c.MaxScan == "" {
+		return 0, nil
+	}
+	return print.ParseBytes(c.MaxScan)
+}
+
+// Variables returns the variables to bind to placeholders in queries. They are loaded from
+// the vars file first, and then the ones given in `name[:type]=value` format override them.
+func (c *Config) Variables() (params.Vars, error) {
+	vs := make(params.Vars)
+	if c.VarsFile != "" {
+		loaded, err := params.LoadFile(c.VarsFile)
+		if err != nil {
+			return nil, err
+		}
+		vs.Merge(loaded)
+	}
+	for _, spec := range c.Vars {
+		if err := vs.Set(spec); err != nil {
+			return nil, err
+		}
+	}
+	return vs, nil
+}
+
+// Exporter creates an export.Exporter to export results to files based on c.
+// It returns nil if neither the export directory nor the pattern of file paths is specified.
+func (c *Config) Exporter() (*export.Exporter, error) {
+	switch {
+	case c.Export != "" && c.ExportFile != "":
+		return nil, errors.New("export and export_file settings cannot be specified at the same time")
+	case c.Export != "":
+		return export.New(c.Export, c.ExportFormat)
+	case c.ExportFile != "":
+		return export.NewWithPattern(c.ExportFile, c.ExportFormat)
+	}
+	return nil, nil
+}
+
+// RetryPolicy creates an exec.RetryPolicy to retry API calls based on c.
+// Unspecified settings are taken from exec.DefaultRetryPolicy.
+func (c *Config) RetryPolicy() exec.RetryPolicy {
+	p := exec.DefaultRetryPolicy
+	if c.RetryMaxAttempts > 0 {
+		p.MaxAttempts = c.RetryMaxAttempts
+	}
+	if c.RetryInterval > 0 {
+		p.Backoff.Initial = c.RetryInterval
+	}
+	if c.RetryMaxInterval > 0 {
+		p.Backoff.Max = c.RetryMaxInterval
+	}
+	if len(c.RetryCodes) > 0 {
+		p.Codes = c.RetryCodes
+	}
+	return p
+}
+
+// RetryFailedPolicy creates an exec.RetryFailedPolicy to resubmit failed query executions based
+// on c. If no patterns are specified, exec.DefaultRetryFailedPatterns are used.
+func (c *Config) RetryFailedPolicy() (exec.RetryFailedPolicy, error) {
+	patterns := c.RetryFailedPatterns
+	if len(patterns) == 0 {
+		patterns = exec.DefaultRetryFailedPatterns
+	}
+	return exec.NewRetryFailedPolicy(c.RetryFailed, patterns)
+}
+
 // SectionError represents an error about section in config file.
 type SectionError struct {
 	Path    string
@@ -1,10 +1,13 @@
 package core
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/exec"
 	"github.com/skatsuta/athenai/internal/testhelper"
 	"github.com/stretchr/testify/assert"
 )
@@ -57,3 +60,159 @@ func TestLoadConfigFileError(t *testing.T) {
 	assert.Equal(t, section, e.Section)
 	assert.Contains(t, e.Cause.Error(), "does not exist")
 }
+
+func TestConfigBackoff(t *testing.T) {
+	tests := []struct {
+		cfg  *Config
+		want exec.Backoff
+	}{
+		{
+			cfg:  &Config{},
+			want: exec.DefaultBackoff,
+		},
+		{
+			cfg: &Config{
+				PollInterval:    500 * time.Millisecond,
+				PollMaxInterval: 5 * time.Second,
+				PollMultiplier:  2,
+				PollJitter:      0.2,
+			},
+			want: exec.Backoff{
+				Initial:    500 * time.Millisecond,
+				Multiplier: 2,
+				Max:        5 * time.Second,
+				Jitter:     0.2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.cfg.Backoff(), "Config: %#v", tt.cfg)
+	}
+}
+
+func TestConfigRetryPolicy(t *testing.T) {
+	custom := exec.DefaultRetryPolicy
+	custom.MaxAttempts = 3
+	custom.Backoff.Initial = time.Second
+	custom.Backoff.Max = 30 * time.Second
+	custom.Codes = []string{"ThrottlingException"}
+
+	tests := []struct {
+		cfg  *Config
+		want exec.RetryPolicy
+	}{
+		{
+			cfg:  &Config{},
+			want: exec.DefaultRetryPolicy,
+		},
+		{
+			cfg: &Config{
+				RetryMaxAttempts: 3,
+				RetryInterval:    time.Second,
+				RetryMaxInterval: 30 * time.Second,
+				RetryCodes:       []string{"ThrottlingException"},
+			},
+			want: custom,
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.cfg.RetryPolicy(), "Config: %#v", tt.cfg)
+	}
+}
+
+func TestConfigRetryFailedPolicy(t *testing.T) {
+	p, err := (&Config{RetryFailed: 2}).RetryFailedPolicy()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, p.MaxRetries)
+	assert.Len(t, p.Patterns, len(exec.DefaultRetryFailedPatterns))
+
+	p, err = (&Config{RetryFailed: 1, RetryFailedPatterns: []string{"HIVE_CURSOR_ERROR"}}).RetryFailedPolicy()
+	assert.NoError(t, err)
+	assert.True(t, p.Retryable(&exec.FailedError{Reason: "hive_cursor_error: unexpected end of input stream"}))
+
+	_, err = (&Config{RetryFailedPatterns: []string{"("}}).RetryFailedPolicy()
+	assert.Error(t, err)
+}
+
+func TestConfigMaxScanBytes(t *testing.T) {
+	tests := []struct {
+		maxScan string
+		want    int64
+		wantErr bool
+	}{
+		{maxScan: "", want: 0},
+		{maxScan: "10GB", want: 10 * 1000 * 1000 * 1000},
+		{maxScan: "1TiB", want: 1 << 40},
+		{maxScan: "ten gigabytes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := (&Config{MaxScan: tt.maxScan}).MaxScanBytes()
+		if tt.wantErr {
+			assert.Error(t, err, "MaxScan: %q", tt.maxScan)
+			continue
+		}
+		assert.NoError(t, err, "MaxScan: %q", tt.maxScan)
+		assert.Equal(t, tt.want, got, "MaxScan: %q", tt.maxScan)
+	}
+}
+
+func TestConfigVariables(t *testing.T) {
+	f, err := ioutil.TempFile("", "athenai-vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("table: !ident elb_logs\nday: !date 2017-07-01\n")
+	f.Close()
+
+	vs, err := (&Config{VarsFile: f.Name(), Vars: []string{"day:date=2017-08-01", "limit:number=10"}}).Variables()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"day", "limit", "table"}, vs.Names())
+	got, _ := vs.Lookup("day")
+	assert.Equal(t, "DATE '2017-08-01'", got, "--var should override the vars file")
+
+	_, err = (&Config{Vars: []string{"day:date=yesterday"}}).Variables()
+	assert.Error(t, err)
+
+	_, err = (&Config{VarsFile: "/path/to/nonexistent.yaml"}).Variables()
+	assert.Error(t, err)
+}
+
+func TestConfigExporter(t *testing.T) {
+	tests := []struct {
+		cfg          Config
+		wantNil      bool
+		wantFormat   string
+		wantManifest string
+		wantErr      string
+	}{
+		{cfg: Config{}, wantNil: true},
+		{cfg: Config{Export: "out"}, wantFormat: "csv.gz", wantManifest: "out/manifest.json"},
+		{cfg: Config{Export: "out", ExportFormat: "parquet"}, wantFormat: "parquet", wantManifest: "out/manifest.json"},
+		{cfg: Config{ExportFile: "out/q{index}.jsonl"}, wantFormat: "jsonl", wantManifest: "out/manifest.json"},
+		{cfg: Config{Export: "out", ExportFile: "out/q{index}.jsonl"}, wantErr: "cannot be specified at the same time"},
+		{cfg: Config{Export: "out", ExportFormat: "xml"}, wantErr: `invalid export format "xml"`},
+		{cfg: Config{ExportFile: "out/result.jsonl"}, wantErr: "must contain {index} or {id}"},
+	}
+
+	for _, tt := range tests {
+		e, err := tt.cfg.Exporter()
+		if tt.wantErr != "" {
+			if assert.Error(t, err, "Config: %#v", tt.cfg) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+			continue
+		}
+		assert.NoError(t, err, "Config: %#v", tt.cfg)
+		if tt.wantNil {
+			assert.Nil(t, e)
+			continue
+		}
+		assert.Equal(t, tt.wantFormat, e.Format())
+		assert.Equal(t, tt.wantManifest, e.ManifestPath())
+	}
+}
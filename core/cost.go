@@ -0,0 +1,46 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/skatsuta/athenai/print"
+)
+
+// sessionCost accumulates data scanned by query executions in a session and their estimated costs.
+// It is goroutine-safe.
+type sessionCost struct {
+	mu      sync.Mutex
+	queries int
+	scanned int64
+	cost    float64
+}
+
+// add adds a query execution which has scanned scannedBytes at pricePerTB.
+func (s *sessionCost) add(scannedBytes int64, pricePerTB float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	s.scanned += scannedBytes
+	s.cost += print.Cost(scannedBytes, pricePerTB)
+}
+
+// String returns a summary line of the session.
+func (s *sessionCost) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("Session total: %d queries | Data scanned: %s | Cost: %s",
+		s.queries, print.FormatBytes(s.scanned), print.FormatCost(s.cost))
+}
+
+// printSessionCost prints the summary of the session if costs are estimated in a text format and
+// some queries have been run.
+func (a *Athenai) printSessionCost() {
+	a.session.mu.Lock()
+	queries := a.session.queries
+	a.session.mu.Unlock()
+	if queries == 0 || a.cfg.PricePerTB <= 0 || !print.IsText(a.cfg.Format) {
+		return
+	}
+	a.println("\n" + a.session.String())
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/skatsuta/athenai/internal/bytes"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/skatsuta/athenai/print"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionCost(t *testing.T) {
+	var s sessionCost
+	s.add(1<<30, 5)
+	s.add(0, 5)
+	s.add(1<<20, 5)
+
+	assert.Equal(t, 3, s.queries)
+	assert.Equal(t, int64(1<<30+1<<20), s.scanned)
+	assert.Equal(t, "Session total: 3 queries | Data scanned: 1.07 GB | Cost: $0.0049", s.String())
+}
+
+func TestPrintSessionCost(t *testing.T) {
+	tests := []struct {
+		cfg     *Config
+		queries int
+		want    string
+	}{
+		{cfg: &Config{Format: print.FormatTable, PricePerTB: 5}, queries: 1, want: "Session total: 1 queries"},
+		{cfg: &Config{Format: print.FormatTable, PricePerTB: 5}, queries: 0, want: ""},
+		{cfg: &Config{Format: print.FormatTable}, queries: 1, want: ""},
+		{cfg: &Config{Format: print.FormatJSON, PricePerTB: 5}, queries: 1, want: ""},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		a := New(stub.NewClient(), tt.cfg, &out)
+		for i := 0; i < tt.queries; i++ {
+			a.session.add(1<<30, tt.cfg.PricePerTB)
+		}
+		a.printSessionCost()
+
+		if tt.want == "" {
+			assert.Empty(t, out.String(), "Config: %#v", tt.cfg)
+		} else {
+			assert.Contains(t, out.String(), tt.want, "Config: %#v", tt.cfg)
+		}
+	}
+}
+
+func TestRunQueryCost(t *testing.T) {
+	query := "SELECT * FROM cost_table1; SELECT * FROM cost_table2"
+	client := stub.NewClient(
+		&stub.Result{ID: "TestRunQueryCost1", Query: "SELECT * FROM cost_table1", ScannedBytes: 1 << 40},
+		&stub.Result{ID: "TestRunQueryCost2", Query: "SELECT * FROM cost_table2", ScannedBytes: 1 << 30},
+	)
+	var out bytes.Buffer
+	a := New(client, &Config{Silent: true, PricePerTB: 5}, &out).WithWaitInterval(testWaitInterval)
+	a.RunQuery(query)
+	got := out.String()
+
+	assert.Contains(t, got, "Cost: $5.00")
+	assert.Contains(t, got, "Cost: $0.0049")
+	assert.Contains(t, got, "Session total: 2 queries | Data scanned: 1.10 TB | Cost: $5.00")
+}
+
+func TestRunQueryBudgetExceeded(t *testing.T) {
+	id := "TestRunQueryBudgetExceeded"
+	query := "SELECT * FROM budget_table"
+	client := stub.NewClient(&stub.Result{ID: id, Query: query, ScannedBytes: 2 << 30, RunningTime: time.Minute})
+	var out, errOut bytes.Buffer
+	a := New(client, &Config{Silent: true, PricePerTB: 5}, &out).WithStderr(&errOut).
+		WithWaitInterval(testWaitInterval).WithMaxScan(1 << 30)
+	a.RunQuery(query)
+
+	assert.Contains(t, errOut.String(), "exceeding the budget")
+	assert.Equal(t, 1, a.session.queries)
+	assert.Equal(t, int64(2<<30), a.session.scanned)
+}
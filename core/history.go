@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/print"
+)
+
+// ShowHistory prints the latest query executions submitted at or after since, which are in
+// the states set by WithStates. If since is the zero time, it is ignored.
+func (a *Athenai) ShowHistory(since time.Time) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if !a.cfg.Silent {
+		go a.showProgressMsg(ctx, loadingHistoryMsg)
+	}
+
+	qxs, err := a.fetchHistory(ctx, since)
+	cancel() // Stop printing loading messages
+	if err != nil {
+		return errors.Wrap(err, "error fetching query executions")
+	}
+	if !a.cfg.Silent {
+		a.printE("\n")
+	}
+
+	log.Printf("Printing %d query executions in history\n", len(qxs))
+	return print.PrintHistory(a.stdout, a.cfg.Format, qxs)
+}
+
+// fetchHistory fetches up to the configured count of the latest query executions which are in
+// the listable states and submitted at or after since. It keeps paging through query executions
+// until enough of them have been found or since has been passed, so that the history is never
+// truncated by filtering.
+func (a *Athenai) fetchHistory(ctx context.Context, since time.Time) ([]*athena.QueryExecution, error) {
+	c := int(a.cfg.Count)
+	var qxs []*athena.QueryExecution
+	err := a.scanQueryExecutions(ctx, func(page []*athena.QueryExecution) bool {
+		qxs = append(qxs, a.filterHistory(page, since)...)
+		if c > 0 && len(qxs) >= c {
+			return false
+		}
+		return !passedSince(page, since)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortQueryExecutions(qxs)
+	if c > 0 && c < len(qxs) {
+		qxs = qxs[:c]
+	}
+	return qxs, nil
+}
+
+// filterHistory returns query executions in qxs which are in the listable states and submitted
+// at or after since.
+func (a *Athenai) filterHistory(qxs []*athena.QueryExecution, since time.Time) []*athena.QueryExecution {
+	filtered := make([]*athena.QueryExecution, 0, len(qxs))
+	for _, qx := range qxs {
+		if !a.listable(aws.StringValue(qx.Status.State)) {
+			continue
+		}
+		if !since.IsZero() && aws.TimeValue(qx.Status.SubmissionDateTime).Before(since) {
+			continue
+		}
+		filtered = append(filtered, qx)
+	}
+	return filtered
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/bytes"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/skatsuta/athenai/print"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShowHistory(t *testing.T) {
+	results := []*stub.Result{
+		{
+			ID:         "TestShowHistory_Old",
+			Query:      "SHOW TABLES",
+			SubmitTime: time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:         "TestShowHistory_Failed",
+			Query:      "SELECT * FROM missing",
+			FinalState: stub.Failed,
+			SubmitTime: time.Date(2017, 7, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:         "TestShowHistory_New",
+			Query:      "SHOW DATABASES",
+			SubmitTime: time.Date(2017, 7, 1, 2, 0, 0, 0, time.UTC),
+		},
+	}
+	all, _ := ParseStates([]string{"all"})
+
+	tests := []struct {
+		count  uint
+		since  time.Time
+		states []string
+		want   string
+	}{
+		{
+			states: all,
+			want:   "TestShowHistory_New\nTestShowHistory_Failed\nTestShowHistory_Old\n",
+		},
+		{
+			count:  2,
+			states: all,
+			want:   "TestShowHistory_New\nTestShowHistory_Failed\n",
+		},
+		{
+			since:  time.Date(2017, 7, 1, 0, 30, 0, 0, time.UTC),
+			states: all,
+			want:   "TestShowHistory_New\nTestShowHistory_Failed\n",
+		},
+		{
+			states: []string{athena.QueryExecutionStateSucceeded},
+			want:   "TestShowHistory_New\nTestShowHistory_Old\n",
+		},
+		{
+			// Older than the latest count of query executions
+			count:  1,
+			states: []string{athena.QueryExecutionStateFailed},
+			want:   "TestShowHistory_Failed\n",
+		},
+		{
+			count:  1,
+			since:  time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC),
+			states: []string{athena.QueryExecutionStateSucceeded, athena.QueryExecutionStateFailed},
+			want:   "TestShowHistory_New\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		cfg := &Config{Count: tt.count, Format: print.FormatCSV, Silent: true}
+		client := stub.NewClient(results...)
+		client.PageSize = 1
+		a := New(client, cfg, &out).WithStates(tt.states)
+		err := a.ShowHistory(tt.since)
+
+		assert.NoError(t, err)
+		var ids string
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		for _, line := range lines[1:] { // Skip the header
+			ids += strings.SplitN(line, ",", 2)[0] + "\n"
+		}
+		assert.Equal(t, tt.want, ids, "Count: %d, Since: %s, States: %q", tt.count, tt.since, tt.states)
+	}
+}
+
+// batchCountingClient counts BatchGetQueryExecution API calls, each of which gets a page of
+// query executions.
+type batchCountingClient struct {
+	*stub.Client
+	batches int
+}
+
+func (c *batchCountingClient) BatchGetQueryExecutionWithContext(ctx aws.Context, input *athena.BatchGetQueryExecutionInput, opts ...request.Option) (*athena.BatchGetQueryExecutionOutput, error) {
+	c.batches++
+	return c.Client.BatchGetQueryExecutionWithContext(ctx, input, opts...)
+}
+
+func TestShowHistoryStopsPaging(t *testing.T) {
+	var results []*stub.Result
+	for i := 0; i < 5; i++ {
+		results = append(results, &stub.Result{
+			ID:         fmt.Sprintf("TestShowHistoryStopsPaging_%d", i),
+			Query:      "SHOW TABLES",
+			SubmitTime: time.Date(2017, 7, 1, i, 0, 0, 0, time.UTC),
+		})
+	}
+	all, _ := ParseStates([]string{"all"})
+
+	tests := []struct {
+		count       uint
+		since       time.Time
+		wantLines   int
+		wantBatches int
+	}{
+		{count: 0, since: time.Date(2017, 7, 1, 2, 30, 0, 0, time.UTC), wantLines: 2, wantBatches: 3},
+		{count: 2, wantLines: 2, wantBatches: 2},
+		{count: 0, wantLines: 5, wantBatches: 5},
+	}
+
+	for _, tt := range tests {
+		client := &batchCountingClient{Client: stub.NewClient(results...)}
+		client.PageSize = 1
+		var out bytes.Buffer
+		cfg := &Config{Count: tt.count, Format: print.FormatCSV, Silent: true}
+		err := New(client, cfg, &out).WithStates(all).ShowHistory(tt.since)
+
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, lines, tt.wantLines+1, "Count: %d, Since: %s", tt.count, tt.since) // Including the header
+		assert.Equal(t, tt.wantBatches, client.batches, "Count: %d, Since: %s", tt.count, tt.since)
+	}
+}
@@ -0,0 +1,262 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/print"
+)
+
+// metaCommandPrefix is a prefix of meta-commands in REPL.
+const metaCommandPrefix = `\`
+
+// errQuit is returned by a meta-command to exit REPL.
+var errQuit = errors.New("quit REPL")
+
+// metaCommand represents a meta-command in REPL, which changes the state of the session.
+type metaCommand struct {
+	names []string // The first one is the primary name and the others are aliases
+	args  string   // Usage of arguments
+	help  string
+	run   func(a *Athenai, args []string) error
+}
+
+// metaCommands are all the available meta-commands. It is initialized in init() since \? refers to it.
+var metaCommands []*metaCommand
+
+func init() {
+	metaCommands = []*metaCommand{
+		{names: []string{"use"}, args: "<database>", help: "Use the database for subsequent queries", run: metaUse},
+		{names: []string{"format"}, args: "<" + strings.Join(print.Formats, "|") + ">", help: "Change the output format", run: metaFormat},
+		{names: []string{"x"}, args: "[on|off|auto]", help: "Toggle expanded display, which prints each row as a vertical record", run: metaExpanded},
+		{names: []string{"c", "concurrent"}, args: "<n>", help: "Change the maximum number of concurrent query executions", run: metaConcurrent},
+		{names: []string{"timing"}, args: "[on|off]", help: "Toggle footers showing run time and data scanned", run: metaTiming},
+		{names: []string{"set"}, help: "List the current settings", run: metaSet},
+		{names: []string{"refresh"}, help: "Rebuild the catalog cache for completion", run: metaRefresh},
+		{names: []string{"?", "help"}, help: "Show this help", run: metaHelp},
+		{names: []string{"q", "quit"}, help: "Exit REPL", run: metaQuit},
+	}
+}
+
+// isMetaCommand returns true if line is a meta-command, otherwise false.
+func isMetaCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), metaCommandPrefix)
+}
+
+// lookupMetaCommand returns the meta-command named name, or nil if it is not found.
+func lookupMetaCommand(name string) *metaCommand {
+	for _, cmd := range metaCommands {
+		for _, n := range cmd.names {
+			if n == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// runMetaCommand runs a meta-command given as a line. It returns errQuit if REPL should exit.
+func (a *Athenai) runMetaCommand(line string) error {
+	fields := strings.Fields(strings.TrimSpace(line))
+	name := strings.TrimPrefix(fields[0], metaCommandPrefix)
+	log.Printf("Running meta-command %q with args %q\n", name, fields[1:])
+
+	cmd := lookupMetaCommand(name)
+	if cmd == nil {
+		return errors.Errorf(`unknown meta-command %s%s. Type \? for help`, metaCommandPrefix, name)
+	}
+	return cmd.run(a, fields[1:])
+}
+
+func (cmd *metaCommand) usage() string {
+	u := metaCommandPrefix + cmd.names[0]
+	if cmd.args != "" {
+		u += " " + cmd.args
+	}
+	return u
+}
+
+// usageError returns an error showing the usage of the meta-command named name.
+func usageError(name string) error {
+	return errors.Errorf("usage: %s", lookupMetaCommand(name).usage())
+}
+
+func metaUse(a *Athenai, args []string) error {
+	if len(args) != 1 {
+		return usageError("use")
+	}
+
+	a.mu.Lock()
+	a.cfg.Database = args[0]
+	a.mu.Unlock()
+
+	a.println("Using database", args[0])
+	return nil
+}
+
+func metaFormat(a *Athenai, args []string) error {
+	if len(args) != 1 {
+		return usageError("format")
+	}
+	if !print.IsValid(args[0]) {
+		return errors.Errorf("invalid format %q. Valid values: %s", args[0], strings.Join(print.Formats, ", "))
+	}
+
+	a.mu.Lock()
+	a.cfg.Format = args[0]
+	a.p = a.newPrinter()
+	a.mu.Unlock()
+
+	a.println("Output format is", args[0])
+	return nil
+}
+
+// metaExpanded switches between vertical format (on), table format (off) and auto format, which
+// prints vertical records only for results wider than the terminal. Without args, it toggles
+// vertical format.
+func metaExpanded(a *Athenai, args []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var format string
+	switch {
+	case len(args) == 0 && a.cfg.Format == print.FormatVertical:
+		format = print.FormatTable
+	case len(args) == 0:
+		format = print.FormatVertical
+	case len(args) == 1 && args[0] == "on":
+		format = print.FormatVertical
+	case len(args) == 1 && args[0] == "off":
+		format = print.FormatTable
+	case len(args) == 1 && args[0] == "auto":
+		format = print.FormatAuto
+	default:
+		return usageError("x")
+	}
+	a.cfg.Format = format
+	a.p = a.newPrinter()
+
+	a.println("Expanded display is", expandedState(format))
+	return nil
+}
+
+// expandedState returns the state of expanded display in format.
+func expandedState(format string) string {
+	switch format {
+	case print.FormatVertical:
+		return "on"
+	case print.FormatAuto:
+		return "auto"
+	}
+	return "off"
+}
+
+func metaConcurrent(a *Athenai, args []string) error {
+	if len(args) != 1 {
+		return usageError("c")
+	}
+	n, err := strconv.ParseUint(args[0], 10, 0)
+	if err != nil || n == 0 {
+		return errors.Errorf("invalid number of concurrent query executions %q. It must be a positive integer", args[0])
+	}
+
+	a.mu.Lock()
+	a.cfg.Concurrent = uint(n)
+	a.mu.Unlock()
+
+	a.println("Maximum number of concurrent query executions is", n)
+	return nil
+}
+
+func metaTiming(a *Athenai, args []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case len(args) == 0:
+		a.noFooter = !a.noFooter
+	case len(args) == 1 && args[0] == "on":
+		a.noFooter = false
+	case len(args) == 1 && args[0] == "off":
+		a.noFooter = true
+	default:
+		return usageError("timing")
+	}
+	a.p = a.newPrinter()
+
+	a.println("Timing is", onOff(!a.noFooter))
+	return nil
+}
+
+func metaSet(a *Athenai, args []string) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	concurrent := a.cfg.Concurrent
+	if concurrent == 0 {
+		concurrent = defaultConcurrentcy
+	}
+	settings := [][2]string{
+		{"database", a.cfg.Database},
+		{"location", a.cfg.Location},
+		{"format", a.cfg.Format},
+		{"concurrent", fmt.Sprint(concurrent)},
+		{"timing", onOff(!a.noFooter)},
+	}
+	for _, s := range settings {
+		a.println(fmt.Sprintf("%-10s = %s", s[0], s[1]))
+	}
+	return nil
+}
+
+func metaRefresh(a *Athenai, args []string) error {
+	a.mu.RLock()
+	cache := a.catalog
+	a.mu.RUnlock()
+	if cache == nil {
+		return errors.New("catalog cache is not available")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.showProgressMsg(ctx, refreshingCatalogMsg)
+
+	cat, err := a.refreshCatalog(ctx)
+	cancel()
+	if err != nil {
+		return errors.Wrap(err, "failed to refresh catalog")
+	}
+
+	tables, columns := 0, 0
+	for _, db := range cat.databases() {
+		for _, table := range cat.tables(db) {
+			tables++
+			columns += len(cat.columns(db, table))
+		}
+	}
+	a.println(fmt.Sprintf("Catalog has been refreshed: %d databases, %d tables and %d columns",
+		len(cat.Databases), tables, columns))
+	return nil
+}
+
+func metaHelp(a *Athenai, args []string) error {
+	for _, cmd := range metaCommands {
+		a.println(fmt.Sprintf("%-28s %s", cmd.usage(), cmd.help))
+	}
+	return nil
+}
+
+func metaQuit(a *Athenai, args []string) error {
+	return errQuit
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
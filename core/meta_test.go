@@ -0,0 +1,152 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/bytes"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/skatsuta/athenai/print"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsMetaCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{line: `\q`, want: true},
+		{line: `  \use sampledb`, want: true},
+		{line: `SELECT '\q'`, want: false},
+		{line: ``, want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isMetaCommand(tt.line), "Line: %q", tt.line)
+	}
+}
+
+func TestRunMetaCommand(t *testing.T) {
+	tests := []struct {
+		line    string
+		check   func(a *Athenai) bool
+		want    string
+		wantErr string
+	}{
+		{
+			line:  `\use testdb`,
+			check: func(a *Athenai) bool { return a.cfg.Database == "testdb" },
+			want:  "Using database testdb",
+		},
+		{
+			line:  `\format csv`,
+			check: func(a *Athenai) bool { return a.cfg.Format == print.FormatCSV },
+			want:  "Output format is csv",
+		},
+		{
+			line:  `\c 2`,
+			check: func(a *Athenai) bool { return a.cfg.Concurrent == 2 },
+			want:  "Maximum number of concurrent query executions is 2",
+		},
+		{
+			line:  `\concurrent 3`,
+			check: func(a *Athenai) bool { return a.cfg.Concurrent == 3 },
+			want:  "Maximum number of concurrent query executions is 3",
+		},
+		{
+			line:  `\timing`,
+			check: func(a *Athenai) bool { return a.noFooter },
+			want:  "Timing is off",
+		},
+		{
+			line:  `\timing on`,
+			check: func(a *Athenai) bool { return !a.noFooter },
+			want:  "Timing is on",
+		},
+		{
+			line:  `\x`,
+			check: func(a *Athenai) bool { return a.cfg.Format == print.FormatVertical },
+			want:  "Expanded display is on",
+		},
+		{
+			line:  `\x auto`,
+			check: func(a *Athenai) bool { return a.cfg.Format == print.FormatAuto },
+			want:  "Expanded display is auto",
+		},
+		{
+			line:  `\x off`,
+			check: func(a *Athenai) bool { return a.cfg.Format == print.FormatTable },
+			want:  "Expanded display is off",
+		},
+		{
+			line: `\set`,
+			want: "database   = sampledb\nlocation   = s3://bucket/\nformat     = table\nconcurrent = 5\ntiming     = on\n",
+		},
+		{
+			line: `\?`,
+			want: `\use <database>`,
+		},
+		{line: `\use`, wantErr: `usage: \use <database>`},
+		{line: `\format xml`, wantErr: `invalid format "xml"`},
+		{line: `\c 0`, wantErr: "must be a positive integer"},
+		{line: `\timing maybe`, wantErr: `usage: \timing [on|off]`},
+		{line: `\x always`, wantErr: `usage: \x [on|off|auto]`},
+		{line: `\foo`, wantErr: `unknown meta-command \foo`},
+		{line: `\refresh`, wantErr: "catalog cache is not available"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		cfg := &Config{Database: "sampledb", Location: "s3://bucket/", Format: print.FormatTable}
+		a := New(stub.NewClient(), cfg, &out)
+		err := a.runMetaCommand(tt.line)
+
+		if tt.wantErr != "" {
+			if assert.Error(t, err, "Line: %q", tt.line) {
+				assert.Contains(t, err.Error(), tt.wantErr, "Line: %q", tt.line)
+			}
+			continue
+		}
+		assert.NoError(t, err, "Line: %q", tt.line)
+		assert.Contains(t, out.String(), tt.want, "Line: %q", tt.line)
+		if tt.check != nil {
+			assert.True(t, tt.check(a), "Line: %q", tt.line)
+		}
+	}
+}
+
+func TestRunREPLMetaCommands(t *testing.T) {
+	rl := &linesReadline{
+		stubReadline: stubReadline{prompt: replPrompt},
+		lines: []string{
+			`\use testdb`,
+			`\timing off`,
+			`\x`,
+			`\bogus`,
+			"SHOW TABLES;",
+			`\q`,
+			"SHOW DATABASES;",
+		},
+	}
+	client := stub.NewClient(
+		&stub.Result{
+			ID:        "TestRunREPLMetaCommands_ShowTables",
+			Query:     "SHOW TABLES",
+			ResultSet: athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"testtable"}})},
+		},
+	)
+	var out, errOut bytes.Buffer
+	a := New(client, &Config{}, &out).WithStderr(&errOut).WithWaitInterval(testWaitInterval)
+	a.rl = rl
+	err := a.RunREPL()
+	got := out.String()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "testdb", a.cfg.Database)
+	assert.Contains(t, got, "testtable")
+	assert.NotContains(t, got, "Run time:")
+	assert.Contains(t, errOut.String(), `unknown meta-command \bogus`)
+	assert.Contains(t, got, "-[ RECORD 1 ]")
+	assert.Equal(t, []string{`\use testdb`, `\timing off`, `\x`, `\bogus`, "SHOW TABLES;", `\q`}, rl.history)
+}
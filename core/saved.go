@@ -0,0 +1,263 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/print"
+)
+
+const loadingSavedQueriesMsg = "Loading saved queries..."
+
+// fetchSavedQueries fetches all the saved (named) queries and returns them sorted by name.
+func (a *Athenai) fetchSavedQueries(ctx context.Context) ([]*athena.NamedQuery, error) {
+	var ids []*string
+	callback := func(page *athena.ListNamedQueriesOutput, lastPage bool) bool {
+		ids = append(ids, page.NamedQueryIds...)
+		return true
+	}
+	if err := a.client.ListNamedQueriesPagesWithContext(ctx, &athena.ListNamedQueriesInput{}, callback); err != nil {
+		return nil, errors.Wrap(err, "ListNamedQueries API error")
+	}
+	log.Printf("%d saved queries are found\n", len(ids))
+
+	nqs := make([]*athena.NamedQuery, 0, len(ids))
+	for start := 0; start < len(ids); start += maxResults {
+		end := start + maxResults
+		if end > len(ids) {
+			end = len(ids)
+		}
+		out, err := a.client.BatchGetNamedQueryWithContext(ctx, &athena.BatchGetNamedQueryInput{
+			NamedQueryIds: ids[start:end],
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "BatchGetNamedQuery API error")
+		}
+		for _, u := range out.UnprocessedNamedQueryIds {
+			// Saved queries may have been deleted after being listed
+			log.Printf("Skipping NamedQueryId %s: %s: %s\n", aws.StringValue(u.NamedQueryId),
+				aws.StringValue(u.ErrorCode), aws.StringValue(u.ErrorMessage))
+		}
+		nqs = append(nqs, out.NamedQueries...)
+	}
+
+	sort.SliceStable(nqs, func(i, j int) bool {
+		return aws.StringValue(nqs[i].Name) < aws.StringValue(nqs[j].Name)
+	})
+	return nqs, nil
+}
+
+// loadSavedQueries fetches all the saved queries while showing a progress message.
+func (a *Athenai) loadSavedQueries() ([]*athena.NamedQuery, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if !a.cfg.Silent {
+		go a.showProgressMsg(ctx, loadingSavedQueriesMsg)
+	}
+	nqs, err := a.fetchSavedQueries(ctx)
+	cancel() // Stop printing loading messages
+	if !a.cfg.Silent {
+		a.printE("\n")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching saved queries")
+	}
+	return nqs, nil
+}
+
+// findSavedQuery returns the saved query in nqs whose name or ID is nameOrID.
+// It returns an error if it is not found or the name is ambiguous.
+func findSavedQuery(nqs []*athena.NamedQuery, nameOrID string) (*athena.NamedQuery, error) {
+	var found []*athena.NamedQuery
+	for _, nq := range nqs {
+		if aws.StringValue(nq.NamedQueryId) == nameOrID {
+			return nq, nil
+		}
+		if aws.StringValue(nq.Name) == nameOrID {
+			found = append(found, nq)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, errors.Errorf("saved query %q is not found", nameOrID)
+	case 1:
+		return found[0], nil
+	}
+	ids := make([]string, len(found))
+	for i, nq := range found {
+		ids[i] = aws.StringValue(nq.NamedQueryId)
+	}
+	return nil, errors.Errorf("%d saved queries are named %q. Specify one of their IDs instead: %s",
+		len(found), nameOrID, strings.Join(ids, ", "))
+}
+
+// ListSavedQueries prints all the saved queries.
+func (a *Athenai) ListSavedQueries() error {
+	nqs, err := a.loadSavedQueries()
+	if err != nil {
+		return err
+	}
+	return print.PrintSavedQueries(a.stdout, a.cfg.Format, nqs)
+}
+
+// GetSavedQuery prints the saved query whose name or ID is nameOrID.
+func (a *Athenai) GetSavedQuery(nameOrID string) error {
+	nqs, err := a.loadSavedQueries()
+	if err != nil {
+		return err
+	}
+	nq, err := findSavedQuery(nqs, nameOrID)
+	if err != nil {
+		return err
+	}
+	return print.PrintSavedQuery(a.stdout, a.cfg.Format, nq)
+}
+
+// CreateSavedQuery saves query as a new saved query named name, which is run on database.
+// It returns an error if a saved query with the same name already exists.
+func (a *Athenai) CreateSavedQuery(name, database, description, query string) error {
+	nqs, err := a.loadSavedQueries()
+	if err != nil {
+		return err
+	}
+	for _, nq := range nqs {
+		if aws.StringValue(nq.Name) == name {
+			return errors.Errorf("saved query %q already exists with ID %s", name, aws.StringValue(nq.NamedQueryId))
+		}
+	}
+
+	input := &athena.CreateNamedQueryInput{
+		Name:        aws.String(name),
+		Database:    aws.String(database),
+		QueryString: aws.String(strings.TrimSpace(query)),
+	}
+	if description != "" {
+		input.Description = aws.String(description)
+	}
+	out, err := a.client.CreateNamedQueryWithContext(context.Background(), input)
+	if err != nil {
+		return errors.Wrap(err, "CreateNamedQuery API error")
+	}
+
+	a.println(fmt.Sprintf("Saved query %q has been created with ID %s", name, aws.StringValue(out.NamedQueryId)))
+	return nil
+}
+
+// DeleteSavedQuery deletes the saved query whose name or ID is nameOrID.
+// Unless yes is true, it asks for confirmation before deleting it.
+func (a *Athenai) DeleteSavedQuery(nameOrID string, yes bool) error {
+	nqs, err := a.loadSavedQueries()
+	if err != nil {
+		return err
+	}
+	nq, err := findSavedQuery(nqs, nameOrID)
+	if err != nil {
+		return err
+	}
+
+	name, id := aws.StringValue(nq.Name), aws.StringValue(nq.NamedQueryId)
+	if !yes && !a.confirm(fmt.Sprintf("Delete saved query %q (%s)?", name, id)) {
+		a.println("Aborted")
+		return nil
+	}
+
+	if _, err := a.client.DeleteNamedQueryWithContext(context.Background(), &athena.DeleteNamedQueryInput{NamedQueryId: &id}); err != nil {
+		return errors.Wrap(err, "DeleteNamedQuery API error")
+	}
+	a.println(fmt.Sprintf("Saved query %q has been deleted", name))
+	return nil
+}
+
+// generateSavedQueryEntry generates an entry of nq to be selected in the filter.
+func generateSavedQueryEntry(nq *athena.NamedQuery) string {
+	query := strings.Join(strings.Fields(aws.StringValue(nq.QueryString)), " ")
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s",
+		aws.StringValue(nq.Name),
+		aws.StringValue(nq.Database),
+		aws.StringValue(nq.Description),
+		query,
+		aws.StringValue(nq.NamedQueryId),
+	)
+}
+
+// selectSavedQueries lets the user select saved queries among nqs in the same way as
+// the query executions in ShowResults.
+func (a *Athenai) selectSavedQueries(nqs []*athena.NamedQuery) ([]*athena.NamedQuery, error) {
+	if err := a.ensureFilter(); err != nil {
+		return nil, err
+	}
+
+	entryMap := make(map[string]*athena.NamedQuery, len(nqs))
+	entries := make([]string, len(nqs))
+	for i, nq := range nqs {
+		entry := generateSavedQueryEntry(nq)
+		entryMap[entry] = nq
+		entries[i] = entry
+	}
+
+	selected, err := a.pick(context.Background(), entries, func(item string) string {
+		return aws.StringValue(entryMap[item].QueryString)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error selecting saved queries")
+	}
+
+	log.Printf("Selected %d saved queries\n", len(selected))
+	selectedNqs := make([]*athena.NamedQuery, 0, len(selected))
+	for _, item := range selected {
+		if nq, ok := entryMap[item]; ok {
+			selectedNqs = append(selectedNqs, nq)
+		}
+	}
+	return selectedNqs, nil
+}
+
+// RunSavedQuery runs the saved query whose name or ID is nameOrID on its database in the same way
+// as RunQuery. If nameOrID is empty, the user selects saved queries to run interactively, and
+// they are run one after another.
+func (a *Athenai) RunSavedQuery(nameOrID string) error {
+	nqs, err := a.loadSavedQueries()
+	if err != nil {
+		return err
+	}
+
+	var targets []*athena.NamedQuery
+	if nameOrID != "" {
+		nq, err := findSavedQuery(nqs, nameOrID)
+		if err != nil {
+			return err
+		}
+		targets = []*athena.NamedQuery{nq}
+	} else {
+		if len(nqs) == 0 {
+			a.println("No saved queries found")
+			return nil
+		}
+		targets, err = a.selectSavedQueries(nqs)
+		if err != nil {
+			if strings.Contains(err.Error(), "canceled") { // Ignore user-canceled error
+				return nil
+			}
+			return err
+		}
+	}
+
+	for _, nq := range targets {
+		log.Printf("Running saved query %q on database %s\n", aws.StringValue(nq.Name), aws.StringValue(nq.Database))
+		a.mu.Lock()
+		a.cfg.Database = aws.StringValue(nq.Database)
+		a.mu.Unlock()
+		if err := a.RunQuery(aws.StringValue(nq.QueryString)); err != nil {
+			return errors.Wrapf(err, "failed to run saved query %q", aws.StringValue(nq.Name))
+		}
+	}
+	return nil
+}
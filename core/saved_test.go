@@ -0,0 +1,209 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/bytes"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/skatsuta/athenai/print"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSavedQueries() []*athena.NamedQuery {
+	return []*athena.NamedQuery{
+		{
+			NamedQueryId: aws.String("nq-daily"),
+			Name:         aws.String("daily_requests"),
+			Database:     aws.String("sampledb"),
+			Description:  aws.String("Requests per day"),
+			QueryString:  aws.String("SELECT date, count(*)\nFROM cloudfront_logs\nGROUP BY date"),
+		},
+		{
+			NamedQueryId: aws.String("nq-dbs"),
+			Name:         aws.String("databases"),
+			Database:     aws.String("default"),
+			QueryString:  aws.String("SHOW DATABASES"),
+		},
+		{
+			NamedQueryId: aws.String("nq-dup1"),
+			Name:         aws.String("duplicated"),
+			Database:     aws.String("default"),
+			QueryString:  aws.String("SHOW TABLES"),
+		},
+		{
+			NamedQueryId: aws.String("nq-dup2"),
+			Name:         aws.String("duplicated"),
+			Database:     aws.String("default"),
+			QueryString:  aws.String("SHOW TABLES"),
+		},
+	}
+}
+
+func TestFetchSavedQueries(t *testing.T) {
+	client := stub.NewClient().WithNamedQueries(newTestSavedQueries()...)
+	a := New(client, &Config{Silent: true}, &bytes.Buffer{})
+
+	nqs, err := a.fetchSavedQueries(context.Background())
+
+	assert.NoError(t, err)
+	names := make([]string, len(nqs))
+	for i, nq := range nqs {
+		names[i] = aws.StringValue(nq.Name)
+	}
+	assert.Equal(t, []string{"daily_requests", "databases", "duplicated", "duplicated"}, names)
+}
+
+func TestFindSavedQuery(t *testing.T) {
+	nqs := newTestSavedQueries()
+	tests := []struct {
+		nameOrID string
+		wantID   string
+		wantErr  string
+	}{
+		{nameOrID: "databases", wantID: "nq-dbs"},
+		{nameOrID: "nq-daily", wantID: "nq-daily"},
+		{nameOrID: "nq-dup2", wantID: "nq-dup2"},
+		{nameOrID: "duplicated", wantErr: "2 saved queries are named \"duplicated\". Specify one of their IDs instead: nq-dup1, nq-dup2"},
+		{nameOrID: "missing", wantErr: "saved query \"missing\" is not found"},
+	}
+
+	for _, tt := range tests {
+		nq, err := findSavedQuery(nqs, tt.nameOrID)
+		if tt.wantErr != "" {
+			if assert.Error(t, err, "NameOrID: %s", tt.nameOrID) {
+				assert.Equal(t, tt.wantErr, err.Error())
+			}
+			continue
+		}
+		assert.NoError(t, err, "NameOrID: %s", tt.nameOrID)
+		assert.Equal(t, tt.wantID, aws.StringValue(nq.NamedQueryId), "NameOrID: %s", tt.nameOrID)
+	}
+}
+
+func TestListSavedQueries(t *testing.T) {
+	client := stub.NewClient().WithNamedQueries(newTestSavedQueries()...)
+	var out bytes.Buffer
+	a := New(client, &Config{Silent: true, Format: print.FormatTable}, &out)
+
+	err := a.ListSavedQueries()
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "| daily_requests | sampledb | Requests per day | nq-daily     |")
+}
+
+func TestGetSavedQuery(t *testing.T) {
+	client := stub.NewClient().WithNamedQueries(newTestSavedQueries()...)
+	var out bytes.Buffer
+	a := New(client, &Config{Silent: true, Format: print.FormatTable}, &out)
+
+	err := a.GetSavedQuery("daily_requests")
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "-- Database: sampledb\n")
+	assert.Contains(t, out.String(), "GROUP BY date\n")
+
+	err = a.GetSavedQuery("duplicated")
+	assert.Error(t, err)
+}
+
+func TestCreateSavedQuery(t *testing.T) {
+	client := stub.NewClient().WithNamedQueries(newTestSavedQueries()...)
+	var out bytes.Buffer
+	a := New(client, &Config{Silent: true}, &out)
+
+	err := a.CreateSavedQuery("tables", "sampledb", "", "  SHOW TABLES\n")
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `Saved query "tables" has been created with ID`)
+	nqs, err := a.fetchSavedQueries(context.Background())
+	assert.NoError(t, err)
+	nq, err := findSavedQuery(nqs, "tables")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SHOW TABLES", aws.StringValue(nq.QueryString))
+		assert.Equal(t, "sampledb", aws.StringValue(nq.Database))
+		assert.Nil(t, nq.Description)
+	}
+
+	err = a.CreateSavedQuery("databases", "sampledb", "", "SHOW DATABASES")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `saved query "databases" already exists with ID nq-dbs`)
+	}
+}
+
+func TestDeleteSavedQuery(t *testing.T) {
+	tests := []struct {
+		nameOrID    string
+		yes         bool
+		stdin       string
+		want        string
+		wantDeleted bool
+	}{
+		{nameOrID: "databases", yes: true, want: `Saved query "databases" has been deleted`, wantDeleted: true},
+		{nameOrID: "nq-dbs", stdin: "yes\n", want: `Saved query "databases" has been deleted`, wantDeleted: true},
+		{nameOrID: "databases", stdin: "\n", want: "Aborted"},
+	}
+
+	for _, tt := range tests {
+		client := stub.NewClient().WithNamedQueries(newTestSavedQueries()...)
+		var out bytes.Buffer
+		a := New(client, &Config{Silent: true}, &out).WithStderr(&bytes.Buffer{})
+		a.stdin = strings.NewReader(tt.stdin)
+
+		err := a.DeleteSavedQuery(tt.nameOrID, tt.yes)
+
+		assert.NoError(t, err, "NameOrID: %s", tt.nameOrID)
+		assert.Contains(t, out.String(), tt.want, "NameOrID: %s", tt.nameOrID)
+		nqs, _ := a.fetchSavedQueries(context.Background())
+		_, err = findSavedQuery(nqs, "nq-dbs")
+		assert.Equal(t, tt.wantDeleted, err != nil, "NameOrID: %s", tt.nameOrID)
+	}
+}
+
+func TestRunSavedQuery(t *testing.T) {
+	results := []*stub.Result{
+		{
+			ID:        "TestRunSavedQuery_Databases",
+			Query:     "SHOW DATABASES",
+			ResultSet: athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"sampledb"}})},
+		},
+		{
+			ID:        "TestRunSavedQuery_Tables",
+			Query:     "SHOW TABLES",
+			ResultSet: athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"elb_logs"}})},
+		},
+	}
+	tests := []struct {
+		nameOrID string
+		idxs     []int
+		errMsg   string
+		want     string
+		wantDB   string
+	}{
+		{nameOrID: "databases", want: "sampledb", wantDB: "default"},
+		{idxs: []int{3}, want: "elb_logs", wantDB: "default"},
+		{errMsg: "filtering canceled", want: "", wantDB: "sampledb"},
+	}
+
+	for _, tt := range tests {
+		client := stub.NewClient(results...).WithNamedQueries(newTestSavedQueries()...)
+		var out bytes.Buffer
+		a := New(client, &Config{Silent: true, Database: "sampledb"}, &out).WithWaitInterval(testWaitInterval)
+		f := &previewFilter{stubFilter: newStubFilter(tt.idxs...)}
+		f.errMsg = tt.errMsg
+		a.f = f
+
+		err := a.RunSavedQuery(tt.nameOrID)
+
+		assert.NoError(t, err, "NameOrID: %s", tt.nameOrID)
+		assert.Contains(t, out.String(), tt.want, "NameOrID: %s", tt.nameOrID)
+		assert.Equal(t, tt.wantDB, a.cfg.Database, "NameOrID: %s", tt.nameOrID)
+		if len(tt.idxs) > 0 && assert.NotNil(t, f.preview) {
+			assert.Equal(t, "SELECT date, count(*)\nFROM cloudfront_logs\nGROUP BY date", f.preview(f.lines[0]))
+		}
+	}
+}
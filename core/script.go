@@ -0,0 +1,174 @@
+package core
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/splitter"
+)
+
+// Annotations in comments preceding a statement, e.g. `-- @depends load_users`.
+const (
+	// annotationBarrier makes the statement a barrier.
+	annotationBarrier = "@barrier"
+	// annotationName names the statement so that it can be referred to by @depends.
+	annotationName = "@name"
+	// annotationDepends makes the statement wait for the given statements, which are specified by
+	// names or 1-based indices. Without any arguments, it waits for the previous statement.
+	annotationDepends = "@depends"
+)
+
+// barrierKeywords are leading keywords of statements which act as barriers by default.
+var barrierKeywords = []string{"CREATE", "DROP", "ALTER", "MSCK", "INSERT INTO"}
+
+// annotations represents annotations of a statement.
+type annotations struct {
+	barrier bool
+	name    string
+	depends []string
+	hasDeps bool // true if @depends is given even without arguments
+}
+
+// parseAnnotations parses annotations in the leading comments of stmt.
+func parseAnnotations(stmt *splitter.Statement) (*annotations, error) {
+	ann := &annotations{}
+	for _, c := range stmt.Comments {
+		if strings.HasPrefix(c, "--") {
+			c = strings.TrimPrefix(c, "--")
+		} else {
+			c = strings.TrimSuffix(strings.TrimPrefix(c, "/*"), "*/")
+		}
+
+		for _, line := range strings.Split(c, "\n") {
+			fields := strings.Fields(strings.Replace(line, ",", " ", -1))
+			if len(fields) == 0 {
+				continue
+			}
+
+			switch fields[0] {
+			case annotationBarrier:
+				ann.barrier = true
+			case annotationName:
+				if len(fields) != 2 {
+					return nil, errors.Errorf("%s: %s requires exactly one name", stmt.Pos, annotationName)
+				}
+				ann.name = fields[1]
+			case annotationDepends:
+				ann.hasDeps = true
+				ann.depends = append(ann.depends, fields[1:]...)
+			}
+		}
+	}
+	return ann, nil
+}
+
+// isBarrierStmt returns true if stmt starts with one of barrierKeywords, otherwise false.
+func isBarrierStmt(stmt *splitter.Statement) bool {
+	words := strings.Fields(strings.ToUpper(stmt.Text))
+	if len(words) == 0 {
+		return false
+	}
+	head := words[0]
+	if len(words) > 1 {
+		head += " " + words[1]
+	}
+
+	for _, kwd := range barrierKeywords {
+		if head == kwd || strings.HasPrefix(head, kwd+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// dependency is a statement on which another statement depends.
+type dependency struct {
+	index    int  // Index of the statement
+	required bool // Whether the dependent statement is skipped unless the statement has succeeded
+}
+
+// dependencies returns the statements on which each statement depends.
+//
+// A barrier statement, which is a DDL statement or one annotated with @barrier, waits for all
+// the statements before it, and all the statements after it wait for the barrier. They only wait
+// for completion, so they are run even if the statements they wait for have failed.
+// A statement annotated with @depends waits for the given statements in addition, and requires
+// them to succeed.
+func dependencies(stmts []*splitter.Statement) ([][]dependency, error) {
+	deps := make([][]dependency, len(stmts))
+	names := make(map[string]int, len(stmts))
+	lastBarrier := -1
+
+	for i, stmt := range stmts {
+		ann, err := parseAnnotations(stmt)
+		if err != nil {
+			return nil, err
+		}
+
+		seen := make(map[int]int) // map[index of dependency]index in deps[i]
+		add := func(j int, required bool) {
+			if k, ok := seen[j]; ok {
+				deps[i][k].required = deps[i][k].required || required
+				return
+			}
+			seen[j] = len(deps[i])
+			deps[i] = append(deps[i], dependency{index: j, required: required})
+		}
+
+		if ann.barrier || isBarrierStmt(stmt) {
+			// Wait for all the statements since the last barrier, which itself has waited for
+			// all the statements before it
+			start := lastBarrier
+			if start < 0 {
+				start = 0
+			}
+			for j := start; j < i; j++ {
+				add(j, false)
+			}
+			lastBarrier = i
+		} else if lastBarrier >= 0 {
+			add(lastBarrier, false)
+		}
+
+		if ann.hasDeps && len(ann.depends) == 0 && i > 0 {
+			add(i-1, true)
+		}
+		for _, ref := range ann.depends {
+			j, err := resolveRef(ref, names, i)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s: invalid %s", stmt.Pos, annotationDepends)
+			}
+			add(j, true)
+		}
+
+		if ann.name != "" {
+			if _, ok := names[ann.name]; ok {
+				return nil, errors.Errorf("%s: duplicate statement name %q", stmt.Pos, ann.name)
+			}
+			names[ann.name] = i
+		}
+
+		log.Printf("Statement %d depends on %v\n", i+1, deps[i])
+	}
+
+	return deps, nil
+}
+
+// resolveRef resolves a reference to a statement, which is either a name or a 1-based index,
+// into a 0-based index. It must refer to a statement before the i-th statement.
+func resolveRef(ref string, names map[string]int, i int) (int, error) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > i {
+			return 0, errors.Errorf("statement %d is not a statement before this one", n)
+		}
+		return n - 1, nil
+	}
+
+	j, ok := names[ref]
+	if !ok {
+		return 0, errors.Errorf("statement named %q is not found before this one", ref)
+	}
+	return j, nil
+}
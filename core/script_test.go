@@ -0,0 +1,89 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/skatsuta/athenai/splitter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencies(t *testing.T) {
+	// wait returns a dependency only waited for, and require one required to succeed
+	wait := func(i int) dependency { return dependency{index: i} }
+	require := func(i int) dependency { return dependency{index: i, required: true} }
+
+	tests := []struct {
+		script string
+		want   [][]dependency
+	}{
+		{
+			script: "SELECT 1; SELECT 2; SHOW TABLES",
+			want:   [][]dependency{nil, nil, nil},
+		},
+		{
+			script: "SELECT 1; SELECT 2; CREATE TABLE t (id int); SELECT 3; SELECT 4; DROP TABLE t; SELECT 5",
+			want:   [][]dependency{nil, nil, {wait(0), wait(1)}, {wait(2)}, {wait(2)}, {wait(2), wait(3), wait(4)}, {wait(5)}},
+		},
+		{
+			script: "create database db; insert into db.t select 1; select 2",
+			want:   [][]dependency{nil, {wait(0)}, {wait(1)}},
+		},
+		{
+			script: "SELECT 1; -- @barrier\nSELECT 2; SELECT 3",
+			want:   [][]dependency{nil, {wait(0)}, {wait(1)}},
+		},
+		{
+			script: "-- @name users\nSELECT 1; SELECT 2;\n-- @depends users\nSELECT 3; /* @depends */ SELECT 4; -- @depends 1, 2\nSELECT 5",
+			want:   [][]dependency{nil, nil, {require(0)}, {require(2)}, {require(0), require(1)}},
+		},
+		{
+			script: "SELECT 1; CREATE TABLE t (id int);\n-- @depends 2\nSELECT 2",
+			want:   [][]dependency{nil, {wait(0)}, {require(1)}},
+		},
+	}
+
+	for _, tt := range tests {
+		stmts, err := splitter.Split(tt.script)
+		assert.NoError(t, err)
+
+		got, err := dependencies(stmts)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, "Script: %q", tt.script)
+	}
+}
+
+func TestDependenciesError(t *testing.T) {
+	tests := []struct {
+		script string
+		want   string
+	}{
+		{
+			script: "-- @depends users\nSELECT 1",
+			want:   `statement named "users" is not found`,
+		},
+		{
+			script: "SELECT 1; -- @depends 3\nSELECT 2; SELECT 3",
+			want:   "statement 3 is not a statement before this one",
+		},
+		{
+			script: "-- @name a\nSELECT 1; -- @name a\nSELECT 2",
+			want:   `duplicate statement name "a"`,
+		},
+		{
+			script: "-- @name\nSELECT 1",
+			want:   "requires exactly one name",
+		},
+	}
+
+	for _, tt := range tests {
+		stmts, err := splitter.Split(tt.script)
+		assert.NoError(t, err)
+
+		_, err = dependencies(stmts)
+
+		if assert.Error(t, err, "Script: %q", tt.script) {
+			assert.Contains(t, err.Error(), tt.want, "Script: %q", tt.script)
+		}
+	}
+}
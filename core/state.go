@@ -0,0 +1,47 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/pkg/errors"
+)
+
+// StateNames are the names of groups of query execution states accepted by ParseStates.
+var StateNames = []string{"all", "succeeded", "failed", "cancelled", "running"}
+
+// stateGroups maps names of groups to the query execution states in them.
+var stateGroups = map[string][]string{
+	"succeeded": {athena.QueryExecutionStateSucceeded},
+	"failed":    {athena.QueryExecutionStateFailed},
+	"cancelled": {athena.QueryExecutionStateCancelled},
+	"canceled":  {athena.QueryExecutionStateCancelled},
+	"running":   {athena.QueryExecutionStateQueued, athena.QueryExecutionStateRunning},
+	"all": {
+		athena.QueryExecutionStateSucceeded,
+		athena.QueryExecutionStateFailed,
+		athena.QueryExecutionStateCancelled,
+		athena.QueryExecutionStateQueued,
+		athena.QueryExecutionStateRunning,
+	},
+}
+
+// ParseStates converts names of groups of states, such as "failed" or "running", into
+// the query execution states in them. Names are case-insensitive.
+func ParseStates(names []string) ([]string, error) {
+	var states []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		group, ok := stateGroups[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, errors.Errorf("invalid state %q. Valid values: %s", name, strings.Join(StateNames, ", "))
+		}
+		for _, state := range group {
+			if !seen[state] {
+				seen[state] = true
+				states = append(states, state)
+			}
+		}
+	}
+	return states, nil
+}
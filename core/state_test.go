@@ -0,0 +1,31 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStates(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{names: nil, want: nil},
+		{names: []string{"succeeded"}, want: []string{"SUCCEEDED"}},
+		{names: []string{"Failed", "CANCELED"}, want: []string{"FAILED", "CANCELLED"}},
+		{names: []string{"running"}, want: []string{"QUEUED", "RUNNING"}},
+		{names: []string{"failed", "all"}, want: []string{"FAILED", "SUCCEEDED", "CANCELLED", "QUEUED", "RUNNING"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStates(tt.names)
+		assert.NoError(t, err, "Names: %q", tt.names)
+		assert.Equal(t, tt.want, got, "Names: %q", tt.names)
+	}
+
+	_, err := ParseStates([]string{"done"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `invalid state "done"`)
+	}
+}
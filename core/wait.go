@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/exec"
+)
+
+// getQueryExecution gets the query execution whose ID is id.
+func (a *Athenai) getQueryExecution(ctx context.Context, id string) (*athena.QueryExecution, error) {
+	out, err := a.client.GetQueryExecutionWithContext(ctx, &athena.GetQueryExecutionInput{QueryExecutionId: &id})
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetQueryExecution API error for QueryExecutionId %s", id)
+	}
+	return out.QueryExecution, nil
+}
+
+// WaitQueryExecutions waits for the query executions whose IDs are ids, which may have been
+// started elsewhere, to complete, and then prints their results or the reasons of their failures
+// in the order of ids.
+//
+// As in RunQuery, the query executions are stopped if the user cancels waiting with Ctrl-C.
+// If detach is true, they are left running instead.
+func (a *Athenai) WaitQueryExecutions(detach bool, ids ...string) {
+	// Trap SIGINT signal
+	signal.Notify(a.signalCh, os.Interrupt)
+	defer signal.Stop(a.signalCh)
+	// Context to propagate cancellation initiated by user
+	userCancelCtx, userCancelFunc := context.WithCancel(context.Background())
+	// Context to notify cancellation process is complete
+	cancelingCtx, cancelingFunc := context.WithCancel(context.Background())
+	defer func() {
+		userCancelFunc()
+		cancelingFunc()
+	}()
+
+	// Buffered since the results may be being printed when canceled
+	canceledCh := make(chan struct{}, 1)
+
+	// Watcher goroutine to cancel waiting
+	go func() {
+		select {
+		case <-a.signalCh: // User has canceled waiting
+			log.Println("Starting cancellation initiated by user")
+			userCancelFunc()
+			a.printE("\n")
+			if !a.cfg.Silent && !detach {
+				go a.showProgressMsg(cancelingCtx, cancelingMsg)
+			}
+			canceledCh <- struct{}{}
+		case <-userCancelCtx.Done(): // Exit normally
+		}
+	}()
+
+	wait := (*exec.Query).Wait
+	if detach {
+		wait = (*exec.Query).Attach
+	}
+
+	// Print progress messages. Results are still streamed after all query executions have
+	// completed, so they are stopped separately from userCancelCtx
+	progressCtx, stopProgress := context.WithCancel(userCancelCtx)
+	defer stopProgress()
+	if !a.cfg.Silent {
+		go a.showProgressMsg(progressCtx, waitingMsg)
+	}
+
+	// Wait for each query execution concurrently
+	l := len(ids)
+	chs := make([]chan *Either, l)
+	var wg sync.WaitGroup
+	wg.Add(l)
+	for i, id := range ids {
+		ch := make(chan *Either, 1)
+		chs[i] = ch
+		go func(id string) {
+			defer wg.Done()
+			qx, err := a.getQueryExecution(userCancelCtx, id)
+			if err != nil {
+				ch <- &Either{Right: err}
+				return
+			}
+			a.fetchQueryResults(userCancelCtx, qx, ch, wait)
+		}(id) // Capture locally in order to use it in goroutines
+	}
+
+	go func() {
+		wg.Wait()
+		stopProgress() // All query executions have completed; Stop showing the progress messages
+	}()
+
+	for i, ch := range chs {
+		var et *Either
+		select {
+		case <-canceledCh: // Stop showing results if canceled
+			// Wait for the query executions to be stopped
+			for ; i < l; i++ {
+				<-chs[i]
+			}
+			a.printE("\n")
+			return
+		case et = <-ch:
+		}
+		a.printResultOrErr(et, i+1, "")
+	}
+
+	log.Println("All query executions have completed")
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/bytes"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitQueryExecutions(t *testing.T) {
+	results := []*stub.Result{
+		{
+			ID:          "TestWaitQueryExecutions_Succeeded",
+			Query:       "CREATE TABLE new_table AS SELECT * FROM cloudfront_logs",
+			RunningTime: 30 * time.Millisecond,
+			ResultSet:   athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"rows"}, {"42"}})},
+		},
+		{
+			ID:         "TestWaitQueryExecutions_Failed",
+			Query:      "SELECT * FROM missing",
+			FinalState: stub.Failed,
+			Reason:     "Table sampledb.missing does not exist",
+		},
+	}
+
+	tests := []struct {
+		ids     []string
+		wants   []string
+		wantErr string
+	}{
+		{
+			ids:   []string{"TestWaitQueryExecutions_Succeeded"},
+			wants: []string{"Query: CREATE TABLE new_table AS SELECT * FROM cloudfront_logs;", "42"},
+		},
+		{
+			ids:   []string{"TestWaitQueryExecutions_Failed"},
+			wants: []string{"Query: SELECT * FROM missing;", "State: FAILED", "Reason: Table sampledb.missing does not exist"},
+		},
+		{
+			ids:     []string{"TestWaitQueryExecutions_Unknown"},
+			wantErr: "QueryExecution TestWaitQueryExecutions_Unknown was not found",
+		},
+	}
+
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		a := New(stub.NewClient(results...), &Config{Silent: true}, &out).
+			WithStderr(&errOut).
+			WithWaitInterval(testWaitInterval)
+		a.WaitQueryExecutions(false, tt.ids...)
+
+		for _, want := range tt.wants {
+			assert.Contains(t, out.String(), want, "IDs: %q", tt.ids)
+		}
+		if tt.wantErr != "" {
+			assert.Contains(t, errOut.String(), tt.wantErr, "IDs: %q", tt.ids)
+		}
+	}
+}
+
+func TestWaitQueryExecutionsOrder(t *testing.T) {
+	client := stub.NewClient(
+		&stub.Result{ID: "TestWaitQueryExecutionsOrder_Slow", Query: "SHOW DATABASES", RunningTime: 30 * time.Millisecond},
+		&stub.Result{ID: "TestWaitQueryExecutionsOrder_Fast", Query: "SHOW TABLES"},
+	)
+	var out bytes.Buffer
+	a := New(client, &Config{Silent: true}, &out).WithWaitInterval(testWaitInterval)
+	a.WaitQueryExecutions(false, "TestWaitQueryExecutionsOrder_Slow", "TestWaitQueryExecutionsOrder_Fast")
+	got := out.String()
+
+	assert.Regexp(t, `(?s)SHOW DATABASES.*SHOW TABLES`, got)
+}
+
+func TestWaitQueryExecutionsCanceled(t *testing.T) {
+	tests := []struct {
+		detach    bool
+		want      string
+		wantState stub.FinalState
+	}{
+		{detach: false, want: cancelingMsg, wantState: stub.Cancelled},
+		{detach: true, wantState: stub.Succeeded},
+	}
+
+	for _, tt := range tests {
+		r := &stub.Result{ID: "TestWaitQueryExecutionsCanceled", Query: "SELECT * FROM large_table", RunningTime: time.Minute}
+		var out bytes.Buffer
+		a := New(stub.NewClient(r), &Config{}, &out).
+			WithStderr(&out).
+			WithWaitInterval(testWaitInterval)
+
+		timer := time.NewTimer(20 * time.Millisecond)
+		go func() {
+			<-timer.C
+			a.signalCh <- os.Interrupt // Send SIGINT signal to cancel after delay
+		}()
+		a.WaitQueryExecutions(tt.detach, r.ID)
+		got := out.String()
+
+		assert.Contains(t, got, tt.want, "Detach: %t", tt.detach)
+		assert.NotContains(t, got, "Query: "+r.Query, "Detach: %t", tt.detach)
+		assert.Equal(t, tt.wantState, r.FinalState, "Detach: %t", tt.detach)
+	}
+}
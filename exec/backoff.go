@@ -0,0 +1,63 @@
+package exec
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultBackoff is a default polling strategy to wait for query executions.
+var DefaultBackoff = Backoff{
+	Initial:    DefaultWaitInterval,
+	Multiplier: 1.5,
+	Max:        10 * time.Second,
+	Jitter:     0.1,
+}
+
+// Backoff is a strategy of exponential backoff with jitter.
+// The n-th interval (n >= 0) is `min(Initial * Multiplier^n, Max)` randomized by ±Jitter.
+type Backoff struct {
+	// Initial is the first interval.
+	Initial time.Duration
+	// Multiplier is a factor by which the interval is multiplied every time.
+	// Values less than 1 are treated as 1, which means a constant interval.
+	Multiplier float64
+	// Max is the upper bound of intervals before jitter is applied. Zero means no limit.
+	Max time.Duration
+	// Jitter is a fraction between 0 and 1 by which each interval is randomized.
+	Jitter float64
+}
+
+// ConstantBackoff returns a Backoff whose interval is always d.
+func ConstantBackoff(d time.Duration) Backoff {
+	return Backoff{Initial: d, Multiplier: 1, Max: d}
+}
+
+// Interval returns the n-th (0-based) interval without jitter.
+func (b Backoff) Interval(n int) time.Duration {
+	interval := float64(b.Initial)
+	for i := 0; i < n; i++ {
+		if b.Multiplier <= 1 || (b.Max > 0 && interval >= float64(b.Max)) {
+			break
+		}
+		interval *= b.Multiplier
+	}
+	if b.Max > 0 && interval > float64(b.Max) {
+		interval = float64(b.Max)
+	}
+	return time.Duration(interval)
+}
+
+// Duration returns the n-th (0-based) interval with jitter applied.
+func (b Backoff) Duration(n int) time.Duration {
+	d := b.Interval(n)
+	jitter := b.Jitter
+	if jitter <= 0 {
+		return d
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	// Randomize d within [d * (1 - jitter), d * (1 + jitter))
+	delta := (rand.Float64()*2 - 1) * jitter * float64(d)
+	return d + time.Duration(delta)
+}
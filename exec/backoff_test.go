@@ -0,0 +1,41 @@
+package exec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffInterval(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Multiplier: 2, Max: time.Second}
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+
+	for n, w := range want {
+		assert.Equal(t, w, b.Interval(n), "n: %d", n)
+	}
+}
+
+func TestBackoffConstant(t *testing.T) {
+	b := ConstantBackoff(300 * time.Millisecond)
+
+	for n := 0; n < 5; n++ {
+		assert.Equal(t, 300*time.Millisecond, b.Duration(n), "n: %d", n)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := Backoff{Initial: time.Second, Multiplier: 1, Jitter: 0.2}
+
+	for n := 0; n < 100; n++ {
+		d := b.Duration(n)
+		assert.True(t, d >= 800*time.Millisecond && d <= 1200*time.Millisecond, "n: %d, duration: %s", n, d)
+	}
+}
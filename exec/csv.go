@@ -0,0 +1,138 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// csvReader reads records from a CSV file written by Athena as query results.
+//
+// Unlike encoding/csv, it distinguishes NULL from an empty string: Athena quotes every value
+// except NULL, so an unquoted empty field is read as NULL (nil) while a quoted one `""` is read
+// as an empty string.
+type csvReader struct {
+	r    *bufio.Reader
+	line int
+	buf  bytes.Buffer
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	return &csvReader{r: bufio.NewReader(r), line: 1}
+}
+
+// Read reads a record. It returns io.EOF if there are no more records.
+func (c *csvReader) Read() ([]*string, error) {
+	if _, err := c.r.Peek(1); err == io.EOF {
+		return nil, io.EOF
+	}
+
+	var record []*string
+	for {
+		field, last, err := c.readField()
+		if err != nil {
+			return nil, err
+		}
+		record = append(record, field)
+		if last {
+			return record, nil
+		}
+	}
+}
+
+// readField reads a field and returns true as last if it is the last field of a record.
+func (c *csvReader) readField() (field *string, last bool, err error) {
+	c.buf.Reset()
+
+	b, err := c.r.ReadByte()
+	if err == io.EOF {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to read CSV")
+	}
+
+	if b != '"' {
+		// Unquoted field
+		for {
+			switch b {
+			case ',':
+				return c.unquoted(), false, nil
+			case '\n':
+				c.line++
+				return c.unquoted(), true, nil
+			case '\r':
+				if next, err := c.r.Peek(1); err == nil && next[0] == '\n' {
+					break // Ignore CR of CRLF
+				}
+				c.buf.WriteByte(b)
+			default:
+				c.buf.WriteByte(b)
+			}
+			if b, err = c.r.ReadByte(); err == io.EOF {
+				return c.unquoted(), true, nil
+			} else if err != nil {
+				return nil, false, errors.Wrap(err, "failed to read CSV")
+			}
+		}
+	}
+
+	// Quoted field
+	start := c.line
+	for {
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			return nil, false, errors.Errorf("line %d: unterminated quoted field in CSV", start)
+		}
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to read CSV")
+		}
+		if b == '\n' {
+			c.line++
+		}
+		if b != '"' {
+			c.buf.WriteByte(b)
+			continue
+		}
+
+		next, err := c.r.ReadByte()
+		switch {
+		case err == io.EOF:
+			return c.quoted(), true, nil
+		case err != nil:
+			return nil, false, errors.Wrap(err, "failed to read CSV")
+		case next == '"':
+			c.buf.WriteByte('"') // Escaped quote
+		case next == ',':
+			return c.quoted(), false, nil
+		case next == '\n':
+			c.line++
+			return c.quoted(), true, nil
+		case next == '\r':
+			if n, err := c.r.ReadByte(); err == nil && n == '\n' {
+				c.line++
+				return c.quoted(), true, nil
+			}
+			return nil, false, errors.Errorf("line %d: unexpected CR after quoted field in CSV", c.line)
+		default:
+			return nil, false, errors.Errorf("line %d: unexpected %q after quoted field in CSV", c.line, next)
+		}
+	}
+}
+
+// unquoted returns the buffered unquoted field, which is NULL if it is empty.
+func (c *csvReader) unquoted() *string {
+	if c.buf.Len() == 0 {
+		return nil
+	}
+	s := c.buf.String()
+	return &s
+}
+
+// quoted returns the buffered quoted field.
+func (c *csvReader) quoted() *string {
+	s := c.buf.String()
+	return &s
+}
@@ -0,0 +1,72 @@
+package exec
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		src  string
+		want [][]*string
+	}{
+		{"", nil},
+		{
+			"\"a\",\"b\"\n\"1\",\"x\"\n",
+			[][]*string{{aws.String("a"), aws.String("b")}, {aws.String("1"), aws.String("x")}},
+		},
+		{
+			"\"a\",,\"\"\r\n,\"c\",\r\n",
+			[][]*string{{aws.String("a"), nil, aws.String("")}, {nil, aws.String("c"), nil}},
+		},
+		{
+			"\"say \"\"hi\"\"\",\"multi\nline, with comma\"",
+			[][]*string{{aws.String(`say "hi"`), aws.String("multi\nline, with comma")}},
+		},
+		{
+			"plain,1\n",
+			[][]*string{{aws.String("plain"), aws.String("1")}},
+		},
+	}
+
+	for _, tt := range tests {
+		r := newCSVReader(strings.NewReader(tt.src))
+		var got [][]*string
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if !assert.NoError(t, err, "Src: %q", tt.src) {
+				break
+			}
+			got = append(got, record)
+		}
+
+		assert.Equal(t, tt.want, got, "Src: %q", tt.src)
+	}
+}
+
+func TestCSVReaderError(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`"unterminated`, "line 1: unterminated quoted field"},
+		{"\"a\"\n\"b\"x", `line 2: unexpected 'x' after quoted field`},
+	}
+
+	for _, tt := range tests {
+		r := newCSVReader(strings.NewReader(tt.src))
+		var err error
+		for err == nil {
+			_, err = r.Read()
+		}
+
+		assert.Contains(t, err.Error(), tt.want, "Src: %q", tt.src)
+	}
+}
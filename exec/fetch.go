@@ -0,0 +1,252 @@
+package exec
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/pkg/errors"
+)
+
+// Modes to fetch results of query executions.
+const (
+	// FetchAuto fetches results from Amazon S3 if possible, and falls back to GetQueryResults API
+	// if it is not possible or fails.
+	FetchAuto = "auto"
+	// FetchS3 fetches results of SELECT statements from Amazon S3. Results of the other statements,
+	// which are not written in CSV, are fetched with GetQueryResults API.
+	FetchS3 = "s3"
+	// FetchAPI fetches results with GetQueryResults API.
+	FetchAPI = "api"
+)
+
+// FetchModes is a list of the valid modes to fetch results.
+var FetchModes = []string{FetchAuto, FetchS3, FetchAPI}
+
+// IsValidFetchMode returns true if mode is a valid mode to fetch results, otherwise false.
+// An empty mode is valid and means FetchAuto.
+func IsValidFetchMode(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	for _, m := range FetchModes {
+		if mode == m {
+			return true
+		}
+	}
+	return false
+}
+
+// WithS3 makes q fetch the results from the output location in Amazon S3 with client according
+// to mode instead of paging through GetQueryResults API, which is much faster for large results.
+func (q *Query) WithS3(client ObjectGetter, mode string) *Query {
+	q.s3 = client
+	q.fetch = mode
+	return q
+}
+
+// outputLocation returns the location in S3 where the results of qx are stored.
+func outputLocation(qx *athena.QueryExecution) string {
+	if qx == nil || qx.ResultConfiguration == nil {
+		return ""
+	}
+	return aws.StringValue(qx.ResultConfiguration.OutputLocation)
+}
+
+// encryptedOnClient returns true if the results of qx are encrypted on the client side.
+func encryptedOnClient(qx *athena.QueryExecution) bool {
+	if qx == nil || qx.ResultConfiguration == nil || qx.ResultConfiguration.EncryptionConfiguration == nil {
+		return false
+	}
+	return aws.StringValue(qx.ResultConfiguration.EncryptionConfiguration.EncryptionOption) == athena.EncryptionOptionCseKms
+}
+
+// useS3 decides whether q fetches the results from S3.
+func (q *Query) useS3() (bool, error) {
+	switch q.fetch {
+	case FetchAPI:
+		return false, nil
+	case FetchS3:
+		if q.s3 == nil {
+			return false, errors.New("S3 client is not set to fetch results from S3")
+		}
+	case FetchAuto, "":
+		if q.s3 == nil {
+			return false, nil
+		}
+	default:
+		return false, errors.Errorf("unknown mode to fetch results %q", q.fetch)
+	}
+
+	if loc := outputLocation(q.info); !strings.HasSuffix(loc, ".csv") {
+		// Results of statements other than SELECT are written in other formats than CSV
+		log.Printf("Output location %q is not a CSV file. Using GetQueryResults API\n", loc)
+		return false, nil
+	}
+	if encryptedOnClient(q.info) {
+		if q.fetch == FetchS3 {
+			return false, errors.Errorf("results encrypted with %s cannot be fetched from S3. Fetch them with GetQueryResults API instead",
+				athena.EncryptionOptionCseKms)
+		}
+		log.Printf("Results are encrypted with %s. Using GetQueryResults API\n", athena.EncryptionOptionCseKms)
+		return false, nil
+	}
+	return true, nil
+}
+
+// parseS3URL parses a URL such as `s3://bucket/path/to/key` into the bucket and the key.
+func parseS3URL(s3URL string) (bucket, key string, err error) {
+	u, err := url.Parse(s3URL)
+	if err != nil {
+		return "", "", errors.Wrap(err, "invalid S3 URL")
+	}
+	if u.Scheme != "s3" || u.Host == "" || len(u.Path) <= 1 {
+		return "", "", errors.Errorf("invalid S3 URL %q. It must be in s3://bucket/key format", s3URL)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// fetchResults fetches the results with fetch into the result of q.
+//
+// If q streams the results, it returns once the first page has been fetched, and the rest of
+// the rows are fetched while being read through the result. Otherwise it returns after all the
+// rows have been fetched into memory.
+func (q *Query) fetchResults(ctx context.Context, fetch pageFetcher) error {
+	if !q.stream {
+		rs := &athena.ResultSet{}
+		err := fetch(ctx, func(page *athena.ResultSet) error {
+			if rs.ResultSetMetadata == nil {
+				rs.ResultSetMetadata = page.ResultSetMetadata
+			}
+			rs.Rows = append(rs.Rows, page.Rows...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		q.rs = rs
+		return nil
+	}
+
+	it := newRowIterator(ctx, fetch)
+	rs, err := it.first()
+	if err != nil {
+		it.Close()
+		return err
+	}
+	q.rs, q.iter = rs, it
+	return nil
+}
+
+// fetchFromAPI fetches the results page by page with GetQueryResults API.
+func (q *Query) fetchFromAPI(ctx context.Context, emit func(page *athena.ResultSet) error) error {
+	params := &athena.GetQueryResultsInput{
+		QueryExecutionId: &q.id,
+		MaxResults:       aws.Int64(maxResults),
+	}
+
+	var emitErr error
+	callback := func(page *athena.GetQueryResultsOutput, lastPage bool) bool {
+		rs := page.ResultSet
+		if rs == nil {
+			rs = &athena.ResultSet{}
+		}
+		emitErr = emit(rs)
+		return emitErr == nil && !lastPage
+	}
+
+	err := q.client.GetQueryResultsPagesWithContext(q.countRetries(ctx), params, callback)
+	if err != nil {
+		if cerr, ok := err.(awserr.Error); ok && cerr.Code() == request.CanceledErrorCode {
+			return &CanceledError{Query: q.query, ID: q.id}
+		}
+		return errors.Wrap(err, "GetQueryResults API error")
+	}
+	if emitErr != nil {
+		if ctx.Err() != nil {
+			return &CanceledError{Query: q.query, ID: q.id}
+		}
+		return emitErr
+	}
+	return nil
+}
+
+// fetchFromS3 fetches the results by reading the CSV file in the output location, and emits
+// every maxResults rows as a page.
+// The metadata of the columns, which is not written in the file, is got with a GetQueryResults
+// API call for a single row.
+func (q *Query) fetchFromS3(ctx context.Context, emit func(page *athena.ResultSet) error) error {
+	loc := outputLocation(q.info)
+	bucket, key, err := parseS3URL(loc)
+	if err != nil {
+		return err
+	}
+
+	out, err := q.client.GetQueryResultsWithContext(q.countRetries(ctx), &athena.GetQueryResultsInput{
+		QueryExecutionId: &q.id,
+		MaxResults:       aws.Int64(1),
+	})
+	if err != nil {
+		if cerr, ok := err.(awserr.Error); ok && cerr.Code() == request.CanceledErrorCode {
+			return &CanceledError{Query: q.query, ID: q.id}
+		}
+		return errors.Wrap(err, "GetQueryResults API error")
+	}
+
+	log.Printf("Fetching results of query execution %s from %s\n", q.id, loc)
+	body, err := q.s3.GetObject(ctx, bucket, key)
+	if err != nil {
+		if ctx.Err() != nil {
+			return &CanceledError{Query: q.query, ID: q.id}
+		}
+		return errors.Wrap(err, "GetObject API error")
+	}
+	defer body.Close()
+
+	page := &athena.ResultSet{}
+	if out.ResultSet != nil {
+		page.ResultSetMetadata = out.ResultSet.ResultSetMetadata
+	}
+	total := 0
+	r := newCSVReader(body)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return &CanceledError{Query: q.query, ID: q.id}
+			}
+			return errors.Wrapf(err, "failed to read results from %s", loc)
+		}
+
+		row := &athena.Row{Data: make([]*athena.Datum, len(record))}
+		for i, v := range record {
+			row.Data[i] = &athena.Datum{VarCharValue: v}
+		}
+		page.Rows = append(page.Rows, row)
+		total++
+
+		if len(page.Rows) == maxResults {
+			if err := emit(page); err != nil {
+				return &CanceledError{Query: q.query, ID: q.id}
+			}
+			page = &athena.ResultSet{}
+		}
+	}
+	// Emit the last page even if it is empty, since the first page has the metadata
+	if len(page.Rows) > 0 || total == 0 {
+		if err := emit(page); err != nil {
+			return &CanceledError{Query: q.query, ID: q.id}
+		}
+	}
+	log.Printf("Fetched %d rows of query execution %s from S3\n", total, q.id)
+	return nil
+}
@@ -0,0 +1,245 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+const testResultsCSV = `"id","name"
+"1","alice"
+"2",
+"3",""
+`
+
+var testColumnInfo = []*athena.ColumnInfo{
+	{Name: aws.String("id"), Type: aws.String("integer")},
+	{Name: aws.String("name"), Type: aws.String("varchar")},
+}
+
+// newFetchTestQuery creates a new Query whose query execution has succeeded with the results
+// stored at location.
+func newFetchTestQuery(id, location, encrypt string, apiRows [][]string) *Query {
+	client := stub.NewGetQueryResultsStub(&stub.Result{
+		ID:    id,
+		Query: "SELECT id, name FROM users",
+		ResultSet: athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: testColumnInfo},
+			Rows:              testhelper.CreateRows(apiRows),
+		},
+	})
+	qx := &athena.QueryExecution{
+		QueryExecutionId:    aws.String(id),
+		Query:               aws.String("SELECT id, name FROM users"),
+		ResultConfiguration: testhelper.CreateResultConfig(location),
+		Status:              &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateSucceeded)},
+	}
+	if encrypt != "" {
+		qx.ResultConfiguration.EncryptionConfiguration = &athena.EncryptionConfiguration{EncryptionOption: aws.String(encrypt)}
+	}
+	return NewQueryFromQx(client, cfg, qx)
+}
+
+func TestGetResultsFromS3(t *testing.T) {
+	srv := stub.NewS3Server()
+	defer srv.Close()
+	srv.PutObject("s3://bucket/prefix/TestGetResultsFromS3.csv", testResultsCSV)
+	srv.PutObject("s3://bucket/prefix/TestGetResultsFromS3.txt", "elb_logs\n")
+
+	apiRows := [][]string{{"id", "name"}, {"api", "api"}}
+	tests := []struct {
+		mode     string
+		location string
+		encrypt  string
+		wantRows [][]string
+		wantGets int
+	}{
+		{
+			mode:     FetchS3,
+			location: "s3://bucket/prefix/TestGetResultsFromS3.csv",
+			wantRows: [][]string{{"id", "name"}, {"1", "alice"}, {"2", ""}, {"3", ""}},
+			wantGets: 1,
+		},
+		{
+			mode:     FetchAuto,
+			location: "s3://bucket/prefix/TestGetResultsFromS3.csv",
+			wantRows: [][]string{{"id", "name"}, {"1", "alice"}, {"2", ""}, {"3", ""}},
+			wantGets: 1,
+		},
+		{
+			mode:     FetchAPI,
+			location: "s3://bucket/prefix/TestGetResultsFromS3.csv",
+			wantRows: apiRows,
+		},
+		{
+			// Results of statements other than SELECT are not CSV
+			mode:     FetchS3,
+			location: "s3://bucket/prefix/TestGetResultsFromS3.txt",
+			wantRows: apiRows,
+		},
+		{
+			mode:     FetchAuto,
+			location: "s3://bucket/prefix/TestGetResultsFromS3.csv",
+			encrypt:  athena.EncryptionOptionCseKms,
+			wantRows: apiRows,
+		},
+		{
+			// Fall back to GetQueryResults API
+			mode:     FetchAuto,
+			location: "s3://bucket/prefix/nonexistent.csv",
+			wantRows: apiRows,
+			wantGets: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		before := srv.Gets()
+		q := newFetchTestQuery("TestGetResultsFromS3", tt.location, tt.encrypt, apiRows).WithS3(newTestS3Client(t, srv.URL), tt.mode)
+		err := q.GetResults(context.Background())
+
+		assert.NoError(t, err, "Mode: %s, Location: %s", tt.mode, tt.location)
+		assert.Equal(t, tt.wantRows, q.Rows(), "Mode: %s, Location: %s", tt.mode, tt.location)
+		assert.Equal(t, tt.wantGets, srv.Gets()-before, "Mode: %s, Location: %s", tt.mode, tt.location)
+		assert.Equal(t, "id", q.Columns()[0].Name, "Mode: %s, Location: %s", tt.mode, tt.location)
+	}
+}
+
+func TestGetResultsFromS3Null(t *testing.T) {
+	srv := stub.NewS3Server()
+	defer srv.Close()
+	location := "s3://bucket/TestGetResultsFromS3Null.csv"
+	srv.PutObject(location, testResultsCSV)
+
+	q := newFetchTestQuery("TestGetResultsFromS3Null", location, "", nil).WithS3(newTestS3Client(t, srv.URL), FetchS3)
+	err := q.GetResults(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, Cell{Value: "alice"}, q.Cell(1, 1))
+	assert.Equal(t, Cell{Null: true}, q.Cell(2, 1))
+	assert.Equal(t, Cell{Value: ""}, q.Cell(3, 1))
+}
+
+func TestGetResultsFromS3Error(t *testing.T) {
+	srv := stub.NewS3Server()
+	defer srv.Close()
+	srv.PutObject("s3://bucket/broken.csv", `"unterminated`)
+
+	tests := []struct {
+		location string
+		encrypt  string
+		useS3    bool
+		want     string
+	}{
+		{location: "s3://bucket/nonexistent.csv", useS3: true, want: "NoSuchKey"},
+		{location: "s3://bucket/broken.csv", useS3: true, want: "unterminated quoted field"},
+		{location: "s3://bucket/result.csv", encrypt: athena.EncryptionOptionCseKms, useS3: true, want: "cannot be fetched from S3"},
+		{location: "s3://bucket/result.csv", want: "S3 client is not set"},
+	}
+
+	for _, tt := range tests {
+		q := newFetchTestQuery("TestGetResultsFromS3Error", tt.location, tt.encrypt, nil).WithS3(nil, FetchS3)
+		if tt.useS3 {
+			q.WithS3(newTestS3Client(t, srv.URL), FetchS3)
+		}
+		err := q.GetResults(context.Background())
+
+		if assert.Error(t, err, "Location: %s", tt.location) {
+			assert.Contains(t, err.Error(), tt.want, "Location: %s", tt.location)
+		}
+	}
+}
+
+func TestGetResultsFromS3Streaming(t *testing.T) {
+	srv := stub.NewS3Server()
+	defer srv.Close()
+
+	// More rows than a page
+	var buf bytes.Buffer
+	buf.WriteString("\"id\",\"name\"\n")
+	for i := 0; i < 2500; i++ {
+		fmt.Fprintf(&buf, "\"%d\",\"user%d\"\n", i, i)
+	}
+	large := buf.String()
+	srv.PutObject("s3://bucket/large.csv", large)
+	// Broken after the first page
+	srv.PutObject("s3://bucket/broken_later.csv", large+`"unterminated`)
+	// Broken in the first page
+	srv.PutObject("s3://bucket/broken_first.csv", `"unterminated`)
+
+	apiRows := [][]string{{"id", "name"}, {"api", "api"}}
+	tests := []struct {
+		location string
+		mode     string
+		wantRows int
+		wantErr  string
+	}{
+		{location: "s3://bucket/large.csv", mode: FetchS3, wantRows: 2501},
+		// Rows in the pages emitted before the failure are read
+		{location: "s3://bucket/broken_later.csv", mode: FetchAuto, wantRows: 2000, wantErr: "unterminated quoted field"},
+		// Falls back to GetQueryResults API since no rows have been read yet
+		{location: "s3://bucket/broken_first.csv", mode: FetchAuto, wantRows: len(apiRows)},
+	}
+
+	for _, tt := range tests {
+		q := newFetchTestQuery("TestGetResultsFromS3Streaming", tt.location, "", apiRows).
+			WithS3(newTestS3Client(t, srv.URL), tt.mode).WithStreaming()
+		err := q.GetResults(context.Background())
+		assert.NoError(t, err, "Location: %s", tt.location)
+
+		n := 0
+		err = q.EachRow(func(row []string, _ []bool) bool {
+			n++
+			return true
+		})
+		q.Close()
+
+		assert.Equal(t, tt.wantRows, n, "Location: %s", tt.location)
+		if tt.wantErr == "" {
+			assert.NoError(t, err, "Location: %s", tt.location)
+			continue
+		}
+		if assert.Error(t, err, "Location: %s", tt.location) {
+			assert.Contains(t, err.Error(), tt.wantErr, "Location: %s", tt.location)
+		}
+		assert.Equal(t, err, q.Err(), "Location: %s", tt.location)
+	}
+}
+
+func TestParseS3URL(t *testing.T) {
+	tests := []struct {
+		url     string
+		bucket  string
+		key     string
+		wantErr bool
+	}{
+		{url: "s3://bucket/key.csv", bucket: "bucket", key: "key.csv"},
+		{url: "s3://bucket/path/to/key.csv", bucket: "bucket", key: "path/to/key.csv"},
+		{url: "s3://bucket/", wantErr: true},
+		{url: "https://bucket/key.csv", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		bucket, key, err := parseS3URL(tt.url)
+		if tt.wantErr {
+			assert.Error(t, err, "URL: %s", tt.url)
+			continue
+		}
+		assert.NoError(t, err, "URL: %s", tt.url)
+		assert.Equal(t, tt.bucket, bucket, "URL: %s", tt.url)
+		assert.Equal(t, tt.key, key, "URL: %s", tt.url)
+	}
+}
+
+func TestIsValidFetchMode(t *testing.T) {
+	for _, mode := range []string{"", FetchAuto, FetchS3, FetchAPI} {
+		assert.True(t, IsValidFetchMode(mode), "Mode: %q", mode)
+	}
+	assert.False(t, IsValidFetchMode("http"))
+}
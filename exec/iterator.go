@@ -0,0 +1,122 @@
+package exec
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// pageFetcher fetches results page by page, calling emit with each page in order.
+// Only the first page needs to have the metadata of the columns.
+type pageFetcher func(ctx context.Context, emit func(page *athena.ResultSet) error) error
+
+// RowIterator iterates over rows of results which are fetched page by page in background.
+// Fetching the next page waits until the rows of the previous one have been read, so only a few
+// pages are held in memory at a time regardless of the size of the results.
+//
+// RowIterator is NOT goroutine-safe so must be used in a single goroutine.
+type RowIterator struct {
+	pages  chan *athena.ResultSet
+	cancel context.CancelFunc
+	err    error // Written by the fetcher before pages is closed
+
+	rows     []*athena.Row
+	i        int
+	finished bool // Set once pages has been closed
+	closed   bool
+}
+
+// newRowIterator starts fetching results with fetch in background and returns a RowIterator over
+// their rows. It must be closed after use.
+func newRowIterator(ctx context.Context, fetch pageFetcher) *RowIterator {
+	ctx, cancel := context.WithCancel(ctx)
+	it := &RowIterator{
+		pages:  make(chan *athena.ResultSet, 1),
+		cancel: cancel,
+		i:      -1,
+	}
+
+	go func() {
+		it.err = fetch(ctx, func(page *athena.ResultSet) error {
+			select {
+			case it.pages <- page:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(it.pages)
+	}()
+	return it
+}
+
+// first waits for the first page and returns it without its rows, which are read with Next.
+// It returns nil and the error if fetching the first page has failed.
+func (it *RowIterator) first() (*athena.ResultSet, error) {
+	page, ok := <-it.pages
+	if !ok {
+		it.finished = true
+		return nil, it.err
+	}
+	it.rows = page.Rows
+	return &athena.ResultSet{ResultSetMetadata: page.ResultSetMetadata}, nil
+}
+
+// Next advances the iterator to the next row, which then will be available through Row and Cell.
+// It returns false when there are no more rows or fetching them has failed. Err should be
+// checked after Next returns false.
+func (it *RowIterator) Next() bool {
+	if it.closed {
+		return false
+	}
+	for it.i+1 >= len(it.rows) {
+		page, ok := <-it.pages
+		if !ok {
+			it.finished = true
+			it.rows, it.i = nil, -1
+			return false
+		}
+		it.rows, it.i = page.Rows, -1
+	}
+	it.i++
+	return true
+}
+
+// Row returns the current row. NULL is represented as an empty string. Use Cell to distinguish them.
+func (it *RowIterator) Row() []string {
+	if it.i < 0 || it.i >= len(it.rows) {
+		return nil
+	}
+	row, _ := values(it.rows[it.i])
+	return row
+}
+
+// Cell returns the value at the j-th column in the current row.
+// It returns NULL if the position is out of range.
+func (it *RowIterator) Cell(j int) Cell {
+	if it.i < 0 || it.i >= len(it.rows) {
+		return Cell{Null: true}
+	}
+	return cell(it.rows[it.i], j)
+}
+
+// Err returns the error which has occurred while fetching the rows, if any.
+// It returns nil until all the rows have been read, or if the iterator has been closed before that.
+func (it *RowIterator) Err() error {
+	if !it.finished {
+		return nil
+	}
+	return it.err
+}
+
+// Close stops fetching the rest of the rows.
+func (it *RowIterator) Close() {
+	if it.closed {
+		return
+	}
+	it.closed = true
+	it.cancel()
+	// Wait for the fetcher to stop
+	for range it.pages {
+	}
+	it.rows, it.i = nil, -1
+}
@@ -0,0 +1,113 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+// pagesFetcher returns a pageFetcher which emits pages of rows in order and then returns err.
+func pagesFetcher(pages [][][]string, err error) pageFetcher {
+	return func(ctx context.Context, emit func(page *athena.ResultSet) error) error {
+		for i, rows := range pages {
+			page := &athena.ResultSet{Rows: testhelper.CreateRows(rows)}
+			if i == 0 {
+				page.ResultSetMetadata = &athena.ResultSetMetadata{ColumnInfo: testColumnInfo}
+			}
+			if err := emit(page); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+func TestRowIterator(t *testing.T) {
+	pages := [][][]string{
+		{{"id", "name"}, {"1", "alice"}},
+		{},
+		{{"2", "bob"}},
+	}
+	it := newRowIterator(context.Background(), pagesFetcher(pages, nil))
+	defer it.Close()
+
+	rs, err := it.first()
+	assert.NoError(t, err)
+	assert.Equal(t, testColumnInfo, rs.ResultSetMetadata.ColumnInfo)
+	assert.Empty(t, rs.Rows)
+
+	var rows [][]string
+	for it.Next() {
+		rows = append(rows, it.Row())
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, [][]string{{"id", "name"}, {"1", "alice"}, {"2", "bob"}}, rows)
+	assert.False(t, it.Next(), "Next after the last row")
+	assert.Nil(t, it.Row())
+}
+
+func TestRowIteratorCell(t *testing.T) {
+	it := newRowIterator(context.Background(), func(ctx context.Context, emit func(*athena.ResultSet) error) error {
+		return emit(&athena.ResultSet{Rows: []*athena.Row{
+			{Data: []*athena.Datum{{VarCharValue: aws.String("")}, {}}},
+		}})
+	})
+	defer it.Close()
+
+	_, err := it.first()
+	assert.NoError(t, err)
+	assert.Equal(t, Cell{Null: true}, it.Cell(0), "Before Next")
+	assert.True(t, it.Next())
+	assert.Equal(t, Cell{Value: ""}, it.Cell(0))
+	assert.Equal(t, Cell{Null: true}, it.Cell(1))
+	assert.Equal(t, Cell{Null: true}, it.Cell(2))
+}
+
+func TestRowIteratorError(t *testing.T) {
+	wantErr := errors.New("connection reset")
+
+	// Failure before the first page
+	it := newRowIterator(context.Background(), pagesFetcher(nil, wantErr))
+	rs, err := it.first()
+	assert.Nil(t, rs)
+	assert.Equal(t, wantErr, err)
+	it.Close()
+
+	// Failure after the first page is reported after all the fetched rows have been read
+	it = newRowIterator(context.Background(), pagesFetcher([][][]string{{{"1", "alice"}}}, wantErr))
+	defer it.Close()
+	_, err = it.first()
+	assert.NoError(t, err)
+	assert.True(t, it.Next())
+	assert.NoError(t, it.Err(), "Err before all the rows have been read")
+	assert.False(t, it.Next())
+	assert.Equal(t, wantErr, it.Err())
+}
+
+func TestRowIteratorClose(t *testing.T) {
+	stopped := make(chan error, 1)
+	it := newRowIterator(context.Background(), func(ctx context.Context, emit func(*athena.ResultSet) error) error {
+		// Fetch pages endlessly until the iterator is closed
+		for {
+			if err := emit(&athena.ResultSet{Rows: testhelper.CreateRows([][]string{{"1"}})}); err != nil {
+				stopped <- err
+				return err
+			}
+		}
+	})
+
+	_, err := it.first()
+	assert.NoError(t, err)
+	assert.True(t, it.Next())
+	it.Close()
+	it.Close() // Closing twice is no-op
+
+	assert.Equal(t, context.Canceled, <-stopped)
+	assert.False(t, it.Next(), "Next after Close")
+	assert.NoError(t, it.Err(), "Err after Close")
+}
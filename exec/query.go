@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol"
 	"github.com/aws/aws-sdk-go/service/athena"
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
 	"github.com/pkg/errors"
@@ -42,6 +44,62 @@ func (e *CanceledError) String() string {
 	return e.Error()
 }
 
+// TimeoutError represents an error that a query execution has been stopped since it had not
+// completed within the timeout.
+type TimeoutError struct {
+	Query   string
+	ID      string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("query execution %s has been stopped since it did not complete within %s", e.ID, e.Timeout)
+}
+
+func (e *TimeoutError) String() string {
+	return e.Error()
+}
+
+// BudgetExceededError represents an error that a query execution has been stopped since it had
+// scanned more data than the budget.
+type BudgetExceededError struct {
+	Query   string
+	ID      string
+	Scanned int64 // DataScannedInBytes when the query execution was stopped
+	Budget  int64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("query execution %s has been stopped since it scanned %d bytes, exceeding the budget of %d bytes",
+		e.ID, e.Scanned, e.Budget)
+}
+
+func (e *BudgetExceededError) String() string {
+	return e.Error()
+}
+
+// FailedError represents an error that a query execution has failed.
+type FailedError struct {
+	Query  string
+	ID     string
+	Reason string // StateChangeReason of the query execution
+	// Attempts are the IDs of all the query executions submitted for the query if it has been
+	// resubmitted, otherwise empty.
+	Attempts []string
+}
+
+func (e *FailedError) Error() string {
+	msg := fmt.Sprintf("query execution %s has failed. Reason: %s", e.ID, e.Reason)
+	if len(e.Attempts) > 1 {
+		msg += fmt.Sprintf(" (%d attempts: %s)", len(e.Attempts), strings.Join(e.Attempts, ", "))
+	}
+	return msg
+}
+
+func (e *FailedError) String() string {
+	return e.Error()
+}
+
 // QueryConfig is configurations for query executions.
 type QueryConfig struct {
 	Database string
@@ -56,21 +114,28 @@ type Query struct {
 	*QueryConfig
 	*Result
 
-	client       athenaiface.AthenaAPI
-	waitInterval time.Duration
-	query        string
-	id           string
+	client      athenaiface.AthenaAPI
+	backoff     Backoff
+	timeout     time.Duration
+	retryFailed RetryFailedPolicy
+	detach      bool  // Leaves the query execution running if waiting for it is canceled
+	maxScan     int64 // Maximum bytes to scan. Zero means no limit
+	s3          ObjectGetter
+	fetch       string // Mode to fetch results
+	stream      bool   // Streams the results instead of fetching all of them into memory
+	query       string
+	id          string
 }
 
 // NewQuery creates a new Query struct.
 // `query` string must be a single SQL statement rather than multiple ones joined by semicolons.
 func NewQuery(client athenaiface.AthenaAPI, cfg *QueryConfig, query string) *Query {
 	q := &Query{
-		QueryConfig:  cfg,
-		Result:       &Result{},
-		client:       client,
-		waitInterval: DefaultWaitInterval,
-		query:        query,
+		QueryConfig: cfg,
+		Result:      &Result{},
+		client:      client,
+		backoff:     DefaultBackoff,
+		query:       query,
 	}
 	log.Printf("Created Query: %#v\n", q)
 	return q
@@ -79,26 +144,71 @@ func NewQuery(client athenaiface.AthenaAPI, cfg *QueryConfig, query string) *Que
 // NewQueryFromQx creates a new Query struct from information about a query execution.
 func NewQueryFromQx(client athenaiface.AthenaAPI, cfg *QueryConfig, qx *athena.QueryExecution) *Query {
 	q := &Query{
-		QueryConfig:  cfg,
-		Result:       &Result{info: qx},
-		client:       client,
-		waitInterval: DefaultWaitInterval,
-		query:        aws.StringValue(qx.Query),
-		id:           aws.StringValue(qx.QueryExecutionId),
+		QueryConfig: cfg,
+		Result:      &Result{info: qx},
+		client:      client,
+		backoff:     DefaultBackoff,
+		query:       aws.StringValue(qx.Query),
+		id:          aws.StringValue(qx.QueryExecutionId),
 	}
 	log.Printf("Created Query: %#v\n", q)
 	return q
 }
 
-// WithWaitInterval sets wait interval to q.
+// WithWaitInterval makes q poll the query execution at the constant interval.
 func (q *Query) WithWaitInterval(interval time.Duration) *Query {
-	q.waitInterval = interval
+	q.backoff = ConstantBackoff(interval)
+	return q
+}
+
+// WithBackoff sets a polling strategy to q.
+func (q *Query) WithBackoff(b Backoff) *Query {
+	q.backoff = b
 	return q
 }
 
+// WithTimeout sets the maximum total time to wait for the query execution to q.
+// Zero means no timeout.
+func (q *Query) WithTimeout(timeout time.Duration) *Query {
+	q.timeout = timeout
+	return q
+}
+
+// WithMaxScan makes q stop the query execution once it has scanned more than maxScan bytes.
+// Zero means no limit.
+func (q *Query) WithMaxScan(maxScan int64) *Query {
+	q.maxScan = maxScan
+	return q
+}
+
+// WithRetryFailed sets a policy to resubmit the query execution which has failed to q.
+func (q *Query) WithRetryFailed(p RetryFailedPolicy) *Query {
+	q.retryFailed = p
+	return q
+}
+
+// WithStreaming makes q stream the results: GetResults returns once the first page of them has
+// been fetched, and the rest are fetched page by page while being read with
+// (*Result).EachRow, which keeps memory use flat no matter how large the results are.
+// The result must be closed after use.
+func (q *Query) WithStreaming() *Query {
+	q.stream = true
+	return q
+}
+
+// countRetries returns a copy of ctx in which the retries of API calls are counted up in
+// the result of q.
+func (q *Query) countRetries(ctx context.Context) context.Context {
+	return withRetryCounter(ctx, &q.Result.retries)
+}
+
 // Start starts the specified query but does not wait for it to complete.
 func (q *Query) Start(ctx context.Context) error {
+	// The token is generated once per submission and reused by retries of the API call, so
+	// that Athena never runs the query twice even if a response to a successful call is lost.
+	// Resubmissions of failed query executions get their own tokens by calling Start again.
 	params := &athena.StartQueryExecutionInput{
+		ClientRequestToken:  aws.String(protocol.GetIdempotencyToken()),
 		QueryString:         &q.query,
 		ResultConfiguration: &athena.ResultConfiguration{OutputLocation: &q.Location},
 	}
@@ -112,7 +222,7 @@ func (q *Query) Start(ctx context.Context) error {
 		}
 	}
 
-	qx, err := q.client.StartQueryExecutionWithContext(ctx, params)
+	qx, err := q.client.StartQueryExecutionWithContext(q.countRetries(ctx), params)
 	if err != nil {
 		if cerr, ok := err.(awserr.Error); ok && cerr.Code() == request.CanceledErrorCode {
 			return &CanceledError{Query: q.query}
@@ -121,32 +231,37 @@ func (q *Query) Start(ctx context.Context) error {
 	}
 
 	q.id = aws.StringValue(qx.QueryExecutionId)
+	q.attemptIDs = append(q.attemptIDs, q.id)
 	log.Printf("Query execution ID: %s\n", q.id)
 	return nil
 }
 
 // Wait waits for the query execution until its state has become SUCCEEDED, FAILED or CANCELLED.
 //
-// If the given Context has been canceled, it calls StopQueryExecution API and tries to cancel
-// the query execution.
+// If the given Context has been canceled, the timeout has been exceeded or the query execution has
+// scanned more data than the budget, it calls StopQueryExecution API and tries to cancel the query
+// execution. In the latter two cases it returns TimeoutError and BudgetExceededError respectively.
 func (q *Query) Wait(ctx context.Context) error {
 	if q.id == "" {
 		return errors.New("query execution has not started yet or already failed to start")
 	}
 
-	input := &athena.GetQueryExecutionInput{QueryExecutionId: &q.id}
-	for {
-		select {
-		case <-ctx.Done(): // Query execution has been canceled by user
-			_, err := q.client.StopQueryExecution(&athena.StopQueryExecutionInput{QueryExecutionId: &q.id})
-			if err != nil {
-				return errors.Wrap(err, "StopQueryExecution API error")
-			}
-		default: // No op here by default
-		}
+	var timeoutCh <-chan time.Time
+	if q.timeout > 0 {
+		timer := time.NewTimer(q.timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+	doneCh := ctx.Done()
+	timedOut := false
+	stopped := false
+	var exceeded int64 // Data scanned when the budget has been exceeded
 
-		// Call the API without context since do not want context to cancel the API call
-		qxo, err := q.client.GetQueryExecution(input)
+	// Call the APIs without ctx since do not want ctx to cancel the API calls
+	apiCtx := q.countRetries(context.Background())
+	input := &athena.GetQueryExecutionInput{QueryExecutionId: &q.id}
+	for n := 0; ; n++ {
+		qxo, err := q.client.GetQueryExecutionWithContext(apiCtx, input)
 		if err != nil {
 			return errors.Wrap(err, "GetQueryExecution API error")
 		}
@@ -161,55 +276,134 @@ func (q *Query) Wait(ctx context.Context) error {
 			return nil
 		case athena.QueryExecutionStateFailed:
 			reason := aws.StringValue(qx.Status.StateChangeReason)
-			return errors.Errorf("query execution %s has failed. Reason: %s", q.id, reason)
+			return &FailedError{Query: q.query, ID: q.id, Reason: reason}
 		case athena.QueryExecutionStateCancelled:
+			if exceeded > 0 {
+				return &BudgetExceededError{Query: q.query, ID: q.id, Scanned: exceeded, Budget: q.maxScan}
+			}
+			if timedOut {
+				return &TimeoutError{Query: q.query, ID: q.id, Timeout: q.timeout}
+			}
 			return &CanceledError{Query: q.query, ID: q.id}
 		}
 
-		log.Printf("Query execution %s has not finished yet; Sleeping %s\n", q.id, q.waitInterval)
-		time.Sleep(q.waitInterval)
+		if scanned := scannedBytes(qx); !stopped && q.maxScan > 0 && scanned > q.maxScan {
+			log.Printf("Stopping query execution %s since it has scanned %d bytes, exceeding the budget of %d bytes\n",
+				q.id, scanned, q.maxScan)
+			exceeded = scanned
+		} else {
+			interval := q.backoff.Duration(n)
+			log.Printf("Query execution %s has not finished yet; Sleeping %s\n", q.id, interval)
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+				continue
+			case <-doneCh: // Query execution has been canceled by user
+				if q.detach {
+					timer.Stop()
+					log.Printf("Detaching from query execution %s since waiting has been canceled\n", q.id)
+					return &CanceledError{Query: q.query, ID: q.id}
+				}
+				log.Printf("Stopping query execution %s since it has been canceled\n", q.id)
+			case <-timeoutCh:
+				log.Printf("Stopping query execution %s since it has timed out after %s\n", q.id, q.timeout)
+				timedOut = true
+			}
+			timer.Stop()
+		}
+
+		// Stop the query execution only once, and then keep polling from the initial interval
+		// until it is cancelled
+		stopped = true
+		doneCh, timeoutCh = nil, nil
+		n = -1
+		_, err = q.client.StopQueryExecutionWithContext(apiCtx, &athena.StopQueryExecutionInput{QueryExecutionId: &q.id})
+		if err != nil {
+			return errors.Wrap(err, "StopQueryExecution API error")
+		}
 	}
 }
 
+// scannedBytes returns the bytes of data which qx has scanned so far.
+func scannedBytes(qx *athena.QueryExecution) int64 {
+	if qx.Statistics == nil {
+		return 0
+	}
+	return aws.Int64Value(qx.Statistics.DataScannedInBytes)
+}
+
+// Attach waits for the query execution, which has been started elsewhere, like Wait. Unlike Wait,
+// it does not stop the query execution but just returns CanceledError if the given Context has
+// been canceled.
+func (q *Query) Attach(ctx context.Context) error {
+	q.detach = true
+	defer func() { q.detach = false }()
+	return q.Wait(ctx)
+}
+
 // GetResults gets the results of the query execution.
+// If q has an S3 client, the results may be fetched from S3 according to the mode set with WithS3.
+// If q streams the results, failures after the first page are reported by (*Result).EachRow
+// instead, and never fall back to GetQueryResults API.
 func (q *Query) GetResults(ctx context.Context) error {
 	if q.id == "" {
 		return errors.New("query execution has not started yet or already failed to start")
 	}
 
-	params := &athena.GetQueryResultsInput{
-		QueryExecutionId: &q.id,
-		MaxResults:       aws.Int64(maxResults),
+	useS3, err := q.useS3()
+	if err != nil {
+		return err
 	}
-
-	rs := &athena.ResultSet{}
-	callback := func(page *athena.GetQueryResultsOutput, lastPage bool) bool {
-		if rs.ResultSetMetadata == nil {
-			rs.ResultSetMetadata = page.ResultSet.ResultSetMetadata
+	if useS3 {
+		err := q.fetchResults(ctx, q.fetchFromS3)
+		if err == nil {
+			return nil
 		}
-		rs.Rows = append(rs.Rows, page.ResultSet.Rows...)
-		return !lastPage
-	}
-
-	if err := q.client.GetQueryResultsPagesWithContext(ctx, params, callback); err != nil {
-		if cerr, ok := err.(awserr.Error); ok && cerr.Code() == request.CanceledErrorCode {
-			return &CanceledError{Query: q.query, ID: q.id}
+		if _, ok := err.(*CanceledError); ok {
+			return err
+		}
+		if q.fetch == FetchS3 {
+			return errors.Wrap(err, "failed to fetch results from S3")
 		}
-		return errors.Wrap(err, "GetQueryResults API error")
+		log.Printf("Falling back to GetQueryResults API since fetching results from S3 has failed: %s\n", err)
 	}
 
-	q.rs = rs
-	return nil
+	return q.fetchResults(ctx, q.fetchFromAPI)
 }
 
 // Run starts the specified query, waits for it to complete and fetch the results.
+// If the query execution has failed for a reason to retry, it resubmits the query according to
+// the RetryFailedPolicy of q.
 func (q *Query) Run(ctx context.Context) (*Result, error) {
-	if err := q.Start(ctx); err != nil {
-		return nil, errors.Wrap(err, "failed to start query execution")
-	}
-	if err := q.Wait(ctx); err != nil {
-		return nil, errors.Wrap(err, "error while waiting for the query execution")
+	for attempt := 0; ; attempt++ {
+		if err := q.Start(ctx); err != nil {
+			return nil, errors.Wrap(err, "failed to start query execution")
+		}
+
+		err := q.Wait(ctx)
+		if err == nil {
+			break
+		}
+		ferr, ok := err.(*FailedError)
+		if ok && len(q.attemptIDs) > 1 {
+			ferr.Attempts = q.attemptIDs
+		}
+		if !ok || attempt >= q.retryFailed.MaxRetries || !q.retryFailed.Retryable(ferr) {
+			return nil, errors.Wrap(err, "error while waiting for the query execution")
+		}
+
+		interval := q.backoff.Duration(attempt)
+		log.Printf("Resubmitting query %q in %s (retry %d of %d): %s\n", q.query, interval,
+			attempt+1, q.retryFailed.MaxRetries, err)
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &CanceledError{Query: q.query, ID: q.id}
+		}
 	}
+
 	if err := q.GetResults(ctx); err != nil {
 		return nil, errors.Wrap(err, "failed to get query results")
 	}
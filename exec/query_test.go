@@ -2,13 +2,17 @@ package exec
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/athena"
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/pkg/errors"
 	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/skatsuta/athenai/internal/testhelper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -128,6 +132,7 @@ func TestWaitFailedError(t *testing.T) {
 	tests := []struct {
 		id     string
 		query  string
+		reason string
 		errMsg string
 		want   string
 	}{
@@ -147,6 +152,12 @@ func TestWaitFailedError(t *testing.T) {
 			query: "SELECT * FROM test_wait_error_table",
 			want:  "failed",
 		},
+		{
+			id:     "TestWaitFailedError_QueryFailedWithReason",
+			query:  "SELECT * FROM test_wait_error_table",
+			reason: "Query exhausted resources at this scale factor",
+			want:   "Reason: Query exhausted resources at this scale factor",
+		},
 	}
 
 	for _, tt := range tests {
@@ -154,6 +165,7 @@ func TestWaitFailedError(t *testing.T) {
 			ID:         tt.id,
 			Query:      tt.query,
 			FinalState: stub.Failed,
+			Reason:     tt.reason,
 			ErrMsg:     tt.errMsg,
 		})
 		q := newQuery(client, cfg, tt.query)
@@ -163,9 +175,98 @@ func TestWaitFailedError(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), tt.want, "ID: %s, Query: %q, ErrMsg: %q", tt.id, tt.query, tt.errMsg)
+		if ferr, ok := err.(*FailedError); ok {
+			assert.Equal(t, tt.id, ferr.ID)
+			assert.Equal(t, tt.reason, ferr.Reason)
+		}
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	id := "TestWaitTimeout"
+	query := "SELECT * FROM test_wait_timeout_table"
+	timeout := 50 * time.Millisecond
+	client := stub.NewClient(&stub.Result{ID: id, Query: query, RunningTime: time.Minute})
+	q := newQuery(client, cfg, query).WithTimeout(timeout)
+	q.id = id
+
+	err := q.Wait(context.Background())
+
+	if assert.IsType(t, &TimeoutError{}, err) {
+		terr := err.(*TimeoutError)
+		assert.Equal(t, id, terr.ID)
+		assert.Equal(t, timeout, terr.Timeout)
+	}
+	assert.Equal(t, athena.QueryExecutionStateCancelled, aws.StringValue(q.Info().Status.State))
+}
+
+func TestWaitBudgetExceeded(t *testing.T) {
+	tests := []struct {
+		id          string
+		scanned     int64
+		runningTime time.Duration
+		wantErr     bool
+	}{
+		{id: "TestWaitBudgetExceeded_Exceeded", scanned: 2000, runningTime: time.Minute, wantErr: true},
+		{id: "TestWaitBudgetExceeded_WithinBudget", scanned: 1000, runningTime: 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		query := "SELECT * FROM test_wait_budget_table"
+		client := stub.NewClient(&stub.Result{ID: tt.id, Query: query, ScannedBytes: tt.scanned, RunningTime: tt.runningTime})
+		q := newQuery(client, cfg, query).WithMaxScan(1000)
+		q.id = tt.id
+
+		err := q.Wait(context.Background())
+
+		if !tt.wantErr {
+			assert.NoError(t, err, "ID: %s", tt.id)
+			continue
+		}
+		if assert.IsType(t, &BudgetExceededError{}, err, "ID: %s", tt.id) {
+			berr := err.(*BudgetExceededError)
+			assert.Equal(t, tt.scanned, berr.Scanned)
+			assert.Equal(t, int64(1000), berr.Budget)
+			assert.Contains(t, berr.Error(), "exceeding the budget of 1000 bytes")
+		}
+		assert.Equal(t, athena.QueryExecutionStateCancelled, aws.StringValue(q.Info().Status.State))
 	}
 }
 
+func TestWaitCanceledWhileSleeping(t *testing.T) {
+	id := "TestWaitCanceledWhileSleeping"
+	query := "SELECT * FROM test_wait_canceled_table"
+	client := stub.NewClient(&stub.Result{ID: id, Query: query, RunningTime: time.Minute})
+	b := Backoff{Initial: testWaitInterval, Multiplier: 1000, Max: time.Minute}
+	q := NewQuery(client, cfg, query).WithBackoff(b)
+	q.id = id
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	err := q.Wait(ctx)
+
+	assert.IsType(t, &CanceledError{}, err)
+	assert.True(t, time.Since(start) < 10*time.Second, "Wait should wake up as soon as canceled")
+}
+
+func TestAttachCanceled(t *testing.T) {
+	id := "TestAttachCanceled"
+	query := "SELECT * FROM test_attach_canceled_table"
+	r := &stub.Result{ID: id, Query: query, RunningTime: time.Minute}
+	client := stub.NewClient(r)
+	q := NewQuery(client, cfg, query).WithWaitInterval(testWaitInterval)
+	q.id = id
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err := q.Attach(ctx)
+
+	assert.IsType(t, &CanceledError{}, err)
+	assert.Equal(t, stub.Succeeded, r.FinalState, "Attach should not stop the query execution")
+	assert.False(t, q.detach)
+}
+
 func TestGetResults(t *testing.T) {
 	tests := []struct {
 		id       string
@@ -246,6 +347,39 @@ func TestFromQxGetResults(t *testing.T) {
 	}
 }
 
+func TestGetResultsStreaming(t *testing.T) {
+	id := "TestGetResultsStreaming"
+	query := "SELECT * FROM cloudfront_logs LIMIT 15"
+	client := stub.NewGetQueryResultsStub(&stub.Result{
+		ID:    id,
+		Query: query,
+		ResultSet: athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{ColumnInfo: testColumnInfo},
+			Rows:              testhelper.CreateRows([][]string{{"1", "a"}, {"2", "b"}, {"3", "c"}, {"4", "d"}, {"5", "e"}}),
+		},
+	})
+	client.MaxPages = 3
+
+	q := newQuery(client, cfg, query).WithStreaming()
+	q.id = id
+	err := q.GetResults(context.Background())
+	defer q.Close()
+
+	assert.NoError(t, err)
+	assert.Empty(t, q.rs.Rows, "Rows should not be held in memory")
+	assert.Equal(t, "id", q.Columns()[0].Name, "Metadata should be available before reading rows")
+
+	n := 0
+	err = q.EachRow(func(row []string, nulls []bool) bool {
+		assert.Equal(t, []bool{false, false}, nulls)
+		n++
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 15, n)
+	assert.Empty(t, q.rs.Rows, "Rows should not be held in memory")
+}
+
 func TestGetResultsError(t *testing.T) {
 	tests := []struct {
 		id     string
@@ -335,3 +469,98 @@ func TestRunCanceledError(t *testing.T) {
 		assert.Contains(t, err.Error(), tt.want, "ID: %s, Query: %q", tt.id, tt.query)
 	}
 }
+
+// resubmitClient returns a new query execution ID on every StartQueryExecution API call, and
+// the query executions fail with reason until the number of the calls exceeds fails.
+type resubmitClient struct {
+	*stub.Client
+	reason string
+	fails  int
+	starts int
+	tokens map[string]bool
+}
+
+func (c *resubmitClient) StartQueryExecutionWithContext(ctx aws.Context, input *athena.StartQueryExecutionInput, opts ...request.Option) (*athena.StartQueryExecutionOutput, error) {
+	c.starts++
+	if c.tokens == nil {
+		c.tokens = make(map[string]bool)
+	}
+	c.tokens[aws.StringValue(input.ClientRequestToken)] = true
+	return &athena.StartQueryExecutionOutput{QueryExecutionId: aws.String(fmt.Sprintf("attempt%d", c.starts))}, nil
+}
+
+func (c *resubmitClient) GetQueryExecutionWithContext(ctx aws.Context, input *athena.GetQueryExecutionInput, opts ...request.Option) (*athena.GetQueryExecutionOutput, error) {
+	status := &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateSucceeded)}
+	if c.starts <= c.fails {
+		status.State = aws.String(athena.QueryExecutionStateFailed)
+		status.StateChangeReason = &c.reason
+	}
+	return &athena.GetQueryExecutionOutput{
+		QueryExecution: &athena.QueryExecution{QueryExecutionId: input.QueryExecutionId, Status: status},
+	}, nil
+}
+
+func TestRunRetryFailed(t *testing.T) {
+	tests := []struct {
+		reason       string
+		fails        int
+		maxRetries   int
+		wantAttempts []string
+		wantErr      string
+	}{
+		{
+			reason:       "Query exhausted resources at this scale factor",
+			fails:        2,
+			maxRetries:   2,
+			wantAttempts: []string{"attempt1", "attempt2", "attempt3"},
+		},
+		{
+			reason:       "Please reduce your request rate. (Service: Amazon S3; Status Code: 503; Error Code: SlowDown)",
+			fails:        2,
+			maxRetries:   1,
+			wantAttempts: []string{"attempt1", "attempt2"},
+			wantErr:      "2 attempts: attempt1, attempt2",
+		},
+		{
+			reason:       "SYNTAX_ERROR: line 1:8: Column 'foo' cannot be resolved",
+			fails:        2,
+			maxRetries:   2,
+			wantAttempts: []string{"attempt1"},
+			wantErr:      "Reason: SYNTAX_ERROR",
+		},
+	}
+
+	policy, err := NewRetryFailedPolicy(0, DefaultRetryFailedPatterns)
+	assert.NoError(t, err)
+
+	for _, tt := range tests {
+		query := "SELECT * FROM test_run_retry_failed_table"
+		client := &resubmitClient{
+			Client: stub.NewClient(&stub.Result{ID: "attempt3", Query: query}),
+			reason: tt.reason,
+			fails:  tt.fails,
+		}
+		policy.MaxRetries = tt.maxRetries
+		q := newQuery(client, cfg, query).WithRetryFailed(policy)
+
+		r, err := q.Run(context.Background())
+
+		assert.Equal(t, tt.wantAttempts, q.AttemptIDs(), "Reason: %q", tt.reason)
+		assert.Len(t, client.tokens, len(tt.wantAttempts), "resubmissions should have their own ClientRequestTokens")
+		if tt.wantErr == "" {
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+			continue
+		}
+		if assert.Error(t, err) {
+			assert.IsType(t, &FailedError{}, errors.Cause(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+	}
+}
+
+func TestNewRetryFailedPolicyError(t *testing.T) {
+	_, err := NewRetryFailedPolicy(1, []string{"("})
+
+	assert.Error(t, err)
+}
@@ -1,15 +1,39 @@
 package exec
 
 import (
+	"sync/atomic"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/athena"
 )
 
+// Column represents metadata of a column in a result.
+type Column struct {
+	Name      string
+	Type      string
+	Precision int64
+	Scale     int64
+	// Nullable is one of NOT_NULL, NULLABLE or UNKNOWN.
+	Nullable string
+}
+
+// Cell represents a value in a result. It distinguishes NULL from an empty string.
+type Cell struct {
+	Value string
+	Null  bool
+}
+
 // Result represents results of a query execution.
 // This struct must implement print.Result interface.
 type Result struct {
-	info *athena.QueryExecution
-	rs   *athena.ResultSet
+	info    *athena.QueryExecution
+	rs      *athena.ResultSet
+	retries int64 // Accessed atomically
+
+	attemptIDs []string
+
+	// iter reads the rows not in rs yet if the results are streamed.
+	iter *RowIterator
 }
 
 // Info returns information of a query execution.
@@ -17,20 +41,166 @@ func (r *Result) Info() *athena.QueryExecution {
 	return r.info
 }
 
+// Retries returns the number of API calls which have been retried for the query execution.
+func (r *Result) Retries() int {
+	if r == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&r.retries))
+}
+
+// AttemptIDs returns the IDs of all the query executions submitted for the query,
+// including the failed ones which have been resubmitted.
+func (r *Result) AttemptIDs() []string {
+	if r == nil {
+		return nil
+	}
+	return r.attemptIDs
+}
+
+// ColumnInfo returns information of the columns in the result.
+func (r *Result) ColumnInfo() []*athena.ColumnInfo {
+	if r == nil || r.rs == nil || r.rs.ResultSetMetadata == nil {
+		return nil
+	}
+	return r.rs.ResultSetMetadata.ColumnInfo
+}
+
+// Columns returns metadata of the columns in the result.
+func (r *Result) Columns() []Column {
+	infos := r.ColumnInfo()
+	if infos == nil {
+		return nil
+	}
+
+	cols := make([]Column, len(infos))
+	for i, info := range infos {
+		cols[i] = Column{
+			Name:      aws.StringValue(info.Name),
+			Type:      aws.StringValue(info.Type),
+			Precision: aws.Int64Value(info.Precision),
+			Scale:     aws.Int64Value(info.Scale),
+			Nullable:  aws.StringValue(info.Nullable),
+		}
+	}
+	return cols
+}
+
 // Rows returns an array of all rows of the result which contain arrays of columns.
+// NULL is represented as an empty string. Use Cell or IsNull to distinguish them.
+//
+// If the rows are streamed, it reads all the rest of them into memory. Use EachRow instead to
+// read them one by one.
 func (r *Result) Rows() [][]string {
 	if r == nil || r.rs == nil {
 		return nil
 	}
+	r.buffer()
 
 	rows := make([][]string, 0, len(r.rs.Rows))
 	for _, row := range r.rs.Rows {
-		rw := make([]string, len(row.Data))
-		for i, d := range row.Data {
-			rw[i] = aws.StringValue(d.VarCharValue)
-		}
+		rw, _ := values(row)
 		rows = append(rows, rw)
 	}
 
 	return rows
 }
+
+// HasRows returns true if the rows of the result have been fetched, even if there are no rows.
+// It returns false if the query execution has not succeeded.
+func (r *Result) HasRows() bool {
+	return r != nil && r.rs != nil
+}
+
+// EachRow calls fn with each row of the result and whether each value in it is NULL, in order
+// until fn returns false. Streamed rows are read while calling fn without being held in memory,
+// so they can be read only once. It returns an error if fetching them has failed.
+func (r *Result) EachRow(fn func(row []string, nulls []bool) bool) error {
+	if r == nil || r.rs == nil {
+		return nil
+	}
+	for _, row := range r.rs.Rows {
+		if !fn(values(row)) {
+			return nil
+		}
+	}
+	if r.iter == nil {
+		return nil
+	}
+	for r.iter.Next() {
+		if !fn(values(r.iter.rows[r.iter.i])) {
+			return nil
+		}
+	}
+	return r.iter.Err()
+}
+
+// Err returns the error which has occurred while fetching streamed rows, if any.
+func (r *Result) Err() error {
+	if r == nil || r.iter == nil {
+		return nil
+	}
+	return r.iter.Err()
+}
+
+// Close stops fetching the rest of streamed rows. It does nothing if the rows are not streamed.
+func (r *Result) Close() {
+	if r != nil && r.iter != nil {
+		r.iter.Close()
+	}
+}
+
+// buffer reads all the rows remaining in the iterator into memory.
+func (r *Result) buffer() {
+	if r.iter == nil {
+		return
+	}
+	for r.iter.Next() {
+		r.rs.Rows = append(r.rs.Rows, r.iter.rows[r.iter.i])
+	}
+}
+
+// Cell returns the value at the j-th column in the i-th row.
+// It returns NULL if the position is out of range.
+func (r *Result) Cell(i, j int) Cell {
+	if r == nil || r.rs == nil || i < 0 {
+		return Cell{Null: true}
+	}
+	if i >= len(r.rs.Rows) {
+		r.buffer()
+	}
+	if i >= len(r.rs.Rows) {
+		return Cell{Null: true}
+	}
+	return cell(r.rs.Rows[i], j)
+}
+
+// cell returns the value at the j-th column in row.
+func cell(row *athena.Row, j int) Cell {
+	if row == nil || j < 0 || j >= len(row.Data) || row.Data[j] == nil || row.Data[j].VarCharValue == nil {
+		return Cell{Null: true}
+	}
+	return Cell{Value: *row.Data[j].VarCharValue}
+}
+
+// values returns the values in row and whether each of them is NULL.
+func values(row *athena.Row) ([]string, []bool) {
+	if row == nil {
+		return nil, nil
+	}
+	vals := make([]string, len(row.Data))
+	nulls := make([]bool, len(row.Data))
+	for j, d := range row.Data {
+		if d == nil || d.VarCharValue == nil {
+			nulls[j] = true
+			continue
+		}
+		vals[j] = *d.VarCharValue
+	}
+	return vals, nulls
+}
+
+// IsNull returns true if the value at the j-th column in the i-th row is NULL, otherwise false.
+func (r *Result) IsNull(i, j int) bool {
+	return r.Cell(i, j).Null
+}
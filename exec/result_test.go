@@ -1,6 +1,7 @@
 package exec
 
 import (
+	"context"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -67,3 +68,160 @@ func TestRows(t *testing.T) {
 		assert.Equal(t, tt.expected, actual, "Result: %#v", tt.result)
 	}
 }
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		result   *Result
+		expected []Column
+	}{
+		{
+			result:   &Result{rs: &athena.ResultSet{}},
+			expected: nil,
+		},
+		{
+			result: &Result{
+				rs: &athena.ResultSet{
+					ResultSetMetadata: &athena.ResultSetMetadata{
+						ColumnInfo: []*athena.ColumnInfo{
+							{
+								Name:     aws.String("id"),
+								Type:     aws.String("bigint"),
+								Nullable: aws.String(athena.ColumnNullableNotNull),
+							},
+							{
+								Name:      aws.String("price"),
+								Type:      aws.String("decimal"),
+								Precision: aws.Int64(10),
+								Scale:     aws.Int64(2),
+								Nullable:  aws.String(athena.ColumnNullableNullable),
+							},
+						},
+					},
+				},
+			},
+			expected: []Column{
+				{Name: "id", Type: "bigint", Nullable: "NOT_NULL"},
+				{Name: "price", Type: "decimal", Precision: 10, Scale: 2, Nullable: "NULLABLE"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		actual := tt.result.Columns()
+
+		assert.Equal(t, tt.expected, actual, "Result: %#v", tt.result)
+	}
+}
+
+func TestCell(t *testing.T) {
+	r := &Result{
+		rs: &athena.ResultSet{
+			Rows: []*athena.Row{
+				{
+					Data: []*athena.Datum{
+						{VarCharValue: aws.String("foo")},
+						{VarCharValue: aws.String("")},
+						{},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		i, j     int
+		expected Cell
+	}{
+		{0, 0, Cell{Value: "foo"}},
+		{0, 1, Cell{Value: ""}},
+		{0, 2, Cell{Null: true}},
+		{0, 3, Cell{Null: true}},
+		{1, 0, Cell{Null: true}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, r.Cell(tt.i, tt.j), "Position: (%d, %d)", tt.i, tt.j)
+		assert.Equal(t, tt.expected.Null, r.IsNull(tt.i, tt.j), "Position: (%d, %d)", tt.i, tt.j)
+	}
+
+	// Rows represents NULL as an empty string
+	assert.Equal(t, [][]string{{"foo", "", ""}}, r.Rows())
+}
+
+// newStreamedResult creates a new Result whose first page is buffered and the rest are streamed.
+func newStreamedResult(pages ...[][]string) *Result {
+	it := newRowIterator(context.Background(), pagesFetcher(pages, nil))
+	rs, err := it.first()
+	if err != nil {
+		panic(err)
+	}
+	return &Result{rs: rs, iter: it}
+}
+
+func TestEachRow(t *testing.T) {
+	buffered := &Result{rs: &athena.ResultSet{Rows: []*athena.Row{
+		{Data: []*athena.Datum{{VarCharValue: aws.String("1")}, {}}},
+		{Data: []*athena.Datum{{VarCharValue: aws.String("2")}, {VarCharValue: aws.String("")}}},
+	}}}
+
+	tests := []struct {
+		result    *Result
+		limit     int
+		wantRows  [][]string
+		wantNulls [][]bool
+	}{
+		{
+			result:    buffered,
+			limit:     10,
+			wantRows:  [][]string{{"1", ""}, {"2", ""}},
+			wantNulls: [][]bool{{false, true}, {false, false}},
+		},
+		{
+			result:    buffered,
+			limit:     1,
+			wantRows:  [][]string{{"1", ""}},
+			wantNulls: [][]bool{{false, true}},
+		},
+		{
+			result:    newStreamedResult([][]string{{"1"}}, [][]string{{"2"}, {"3"}}),
+			limit:     10,
+			wantRows:  [][]string{{"1"}, {"2"}, {"3"}},
+			wantNulls: [][]bool{{false}, {false}, {false}},
+		},
+		{
+			result:    newStreamedResult([][]string{{"1"}}, [][]string{{"2"}, {"3"}}),
+			limit:     2,
+			wantRows:  [][]string{{"1"}, {"2"}},
+			wantNulls: [][]bool{{false}, {false}},
+		},
+		{
+			result: &Result{},
+		},
+	}
+
+	for _, tt := range tests {
+		var rows [][]string
+		var nulls [][]bool
+		err := tt.result.EachRow(func(row []string, ns []bool) bool {
+			rows = append(rows, row)
+			nulls = append(nulls, ns)
+			return len(rows) < tt.limit
+		})
+		tt.result.Close()
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.wantRows, rows, "Result: %#v", tt.result)
+		assert.Equal(t, tt.wantNulls, nulls, "Result: %#v", tt.result)
+	}
+}
+
+func TestRowsStreamed(t *testing.T) {
+	r := newStreamedResult([][]string{{"1"}}, [][]string{{"2"}, {"3"}})
+	defer r.Close()
+
+	assert.True(t, r.HasRows())
+	assert.Equal(t, Cell{Value: "3"}, r.Cell(2, 0), "Cell should read the streamed rows")
+	assert.Equal(t, [][]string{{"1"}, {"2"}, {"3"}}, r.Rows())
+	assert.NoError(t, r.Err())
+	assert.False(t, (&Result{}).HasRows())
+}
@@ -0,0 +1,358 @@
+package exec
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/pkg/errors"
+)
+
+// DefaultRetryableCodes are error codes of throttled and transient API errors.
+var DefaultRetryableCodes = []string{
+	"ThrottlingException",
+	"Throttling",
+	"RequestLimitExceeded",
+	athena.ErrCodeTooManyRequestsException,
+	athena.ErrCodeInternalServerException,
+	"ServiceUnavailable",
+	"ServiceUnavailableException",
+	request.ErrCodeResponseTimeout,
+	"RequestError", // Network errors such as connection resets
+}
+
+// DefaultRetryPolicy is a default policy to retry API calls.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	Backoff: Backoff{
+		Initial:    200 * time.Millisecond,
+		Multiplier: 2,
+		Max:        5 * time.Second,
+		Jitter:     0.2,
+	},
+	Codes: DefaultRetryableCodes,
+}
+
+// DefaultRetryFailedPatterns are patterns of reasons of transient query execution failures.
+var DefaultRetryFailedPatterns = []string{
+	`exhausted resources`,
+	`slow ?down`,
+	`internal (server )?error`,
+	`service unavailable`,
+}
+
+// RetryFailedPolicy is a policy to resubmit query executions which have failed for
+// transient reasons.
+type RetryFailedPolicy struct {
+	// MaxRetries is the maximum number of resubmissions. Zero disables them.
+	MaxRetries int
+	// Patterns are matched against reasons of failed query executions.
+	Patterns []*regexp.Regexp
+}
+
+// NewRetryFailedPolicy creates a new RetryFailedPolicy.
+// patterns are regular expressions, which are compiled case-insensitively.
+func NewRetryFailedPolicy(maxRetries int, patterns []string) (RetryFailedPolicy, error) {
+	p := RetryFailedPolicy{MaxRetries: maxRetries, Patterns: make([]*regexp.Regexp, len(patterns))}
+	for i, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return RetryFailedPolicy{}, errors.Wrapf(err, "invalid pattern %q", pattern)
+		}
+		p.Patterns[i] = re
+	}
+	return p, nil
+}
+
+// Retryable returns true if err is a FailedError whose reason matches one of the patterns,
+// otherwise false.
+func (p RetryFailedPolicy) Retryable(err error) bool {
+	ferr, ok := err.(*FailedError)
+	if !ok {
+		return false
+	}
+	for _, re := range p.Patterns {
+		if re.MatchString(ferr.Reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryPolicy is a policy to retry API calls which have failed.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	// Values less than 2 disable retries.
+	MaxAttempts int
+	// Backoff is a strategy to wait between attempts.
+	Backoff Backoff
+	// Codes are error codes to retry.
+	Codes []string
+}
+
+// Retryable returns true if err has one of the error codes to retry, otherwise false.
+func (p RetryPolicy) Retryable(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	for _, code := range p.Codes {
+		if aerr.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryCounterKey is a context key for a counter of retries.
+type retryCounterKey struct{}
+
+// withRetryCounter returns a copy of ctx in which RetryClient counts retries up in cnt.
+func withRetryCounter(ctx context.Context, cnt *int64) context.Context {
+	return context.WithValue(ctx, retryCounterKey{}, cnt)
+}
+
+// RetryClient is an Athena client which retries API calls according to a RetryPolicy.
+// Paginated operations retry each page, so pages which have been already received are never
+// passed to callback functions twice.
+// API operations not overridden by RetryClient are called without retries.
+type RetryClient struct {
+	athenaiface.AthenaAPI
+	policy RetryPolicy
+}
+
+// NewRetryClient creates a new RetryClient which wraps client.
+func NewRetryClient(client athenaiface.AthenaAPI, policy RetryPolicy) *RetryClient {
+	return &RetryClient{AthenaAPI: client, policy: policy}
+}
+
+// do calls fn until it succeeds, fails with an error not to retry or the number of attempts
+// reaches the limit. It gives up retrying as soon as ctx is canceled.
+func (c *RetryClient) do(ctx aws.Context, op string, fn func() error) error {
+	for n := 0; ; n++ {
+		err := fn()
+		if err == nil || n+1 >= c.policy.MaxAttempts || !c.policy.Retryable(err) {
+			return err
+		}
+
+		interval := c.policy.Backoff.Duration(n)
+		log.Printf("%s API error (attempt %d of %d); Retrying in %s: %s\n", op, n+1, c.policy.MaxAttempts, interval, err)
+		if cnt, ok := ctx.Value(retryCounterKey{}).(*int64); ok {
+			atomic.AddInt64(cnt, 1)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// StartQueryExecution calls StartQueryExecution API with retries.
+func (c *RetryClient) StartQueryExecution(input *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+	return c.StartQueryExecutionWithContext(aws.BackgroundContext(), input)
+}
+
+// StartQueryExecutionWithContext calls StartQueryExecution API with retries.
+// Since errors such as timeouts do not tell whether the query execution has been started,
+// all the attempts send the same ClientRequestToken, which is generated if input has none,
+// so that Athena starts the query execution at most once.
+func (c *RetryClient) StartQueryExecutionWithContext(ctx aws.Context, input *athena.StartQueryExecutionInput, opts ...request.Option) (out *athena.StartQueryExecutionOutput, err error) {
+	if input.ClientRequestToken == nil {
+		in := *input
+		in.ClientRequestToken = aws.String(protocol.GetIdempotencyToken())
+		input = &in
+	}
+	err = c.do(ctx, "StartQueryExecution", func() error {
+		out, err = c.AthenaAPI.StartQueryExecutionWithContext(ctx, input, opts...)
+		return err
+	})
+	return
+}
+
+// StopQueryExecution calls StopQueryExecution API with retries.
+func (c *RetryClient) StopQueryExecution(input *athena.StopQueryExecutionInput) (*athena.StopQueryExecutionOutput, error) {
+	return c.StopQueryExecutionWithContext(aws.BackgroundContext(), input)
+}
+
+// StopQueryExecutionWithContext calls StopQueryExecution API with retries.
+func (c *RetryClient) StopQueryExecutionWithContext(ctx aws.Context, input *athena.StopQueryExecutionInput, opts ...request.Option) (out *athena.StopQueryExecutionOutput, err error) {
+	err = c.do(ctx, "StopQueryExecution", func() error {
+		out, err = c.AthenaAPI.StopQueryExecutionWithContext(ctx, input, opts...)
+		return err
+	})
+	return
+}
+
+// GetQueryExecution calls GetQueryExecution API with retries.
+func (c *RetryClient) GetQueryExecution(input *athena.GetQueryExecutionInput) (*athena.GetQueryExecutionOutput, error) {
+	return c.GetQueryExecutionWithContext(aws.BackgroundContext(), input)
+}
+
+// GetQueryExecutionWithContext calls GetQueryExecution API with retries.
+func (c *RetryClient) GetQueryExecutionWithContext(ctx aws.Context, input *athena.GetQueryExecutionInput, opts ...request.Option) (out *athena.GetQueryExecutionOutput, err error) {
+	err = c.do(ctx, "GetQueryExecution", func() error {
+		out, err = c.AthenaAPI.GetQueryExecutionWithContext(ctx, input, opts...)
+		return err
+	})
+	return
+}
+
+// BatchGetQueryExecution calls BatchGetQueryExecution API with retries.
+func (c *RetryClient) BatchGetQueryExecution(input *athena.BatchGetQueryExecutionInput) (*athena.BatchGetQueryExecutionOutput, error) {
+	return c.BatchGetQueryExecutionWithContext(aws.BackgroundContext(), input)
+}
+
+// BatchGetQueryExecutionWithContext calls BatchGetQueryExecution API with retries.
+func (c *RetryClient) BatchGetQueryExecutionWithContext(ctx aws.Context, input *athena.BatchGetQueryExecutionInput, opts ...request.Option) (out *athena.BatchGetQueryExecutionOutput, err error) {
+	err = c.do(ctx, "BatchGetQueryExecution", func() error {
+		out, err = c.AthenaAPI.BatchGetQueryExecutionWithContext(ctx, input, opts...)
+		return err
+	})
+	return
+}
+
+// ListQueryExecutions calls ListQueryExecutions API with retries.
+func (c *RetryClient) ListQueryExecutions(input *athena.ListQueryExecutionsInput) (*athena.ListQueryExecutionsOutput, error) {
+	return c.ListQueryExecutionsWithContext(aws.BackgroundContext(), input)
+}
+
+// ListQueryExecutionsWithContext calls ListQueryExecutions API with retries.
+func (c *RetryClient) ListQueryExecutionsWithContext(ctx aws.Context, input *athena.ListQueryExecutionsInput, opts ...request.Option) (out *athena.ListQueryExecutionsOutput, err error) {
+	err = c.do(ctx, "ListQueryExecutions", func() error {
+		out, err = c.AthenaAPI.ListQueryExecutionsWithContext(ctx, input, opts...)
+		return err
+	})
+	return
+}
+
+// ListQueryExecutionsPages iterates over the pages of ListQueryExecutions API with retries.
+func (c *RetryClient) ListQueryExecutionsPages(input *athena.ListQueryExecutionsInput, fn func(*athena.ListQueryExecutionsOutput, bool) bool) error {
+	return c.ListQueryExecutionsPagesWithContext(aws.BackgroundContext(), input, fn)
+}
+
+// ListQueryExecutionsPagesWithContext iterates over the pages of ListQueryExecutions API
+// with retries.
+func (c *RetryClient) ListQueryExecutionsPagesWithContext(ctx aws.Context, input *athena.ListQueryExecutionsInput, fn func(*athena.ListQueryExecutionsOutput, bool) bool, opts ...request.Option) error {
+	in := *input
+	for {
+		out, err := c.ListQueryExecutionsWithContext(ctx, &in, opts...)
+		if err != nil {
+			return err
+		}
+		lastPage := aws.StringValue(out.NextToken) == ""
+		if !fn(out, lastPage) || lastPage {
+			return nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+// GetQueryResults calls GetQueryResults API with retries.
+func (c *RetryClient) GetQueryResults(input *athena.GetQueryResultsInput) (*athena.GetQueryResultsOutput, error) {
+	return c.GetQueryResultsWithContext(aws.BackgroundContext(), input)
+}
+
+// GetQueryResultsWithContext calls GetQueryResults API with retries.
+func (c *RetryClient) GetQueryResultsWithContext(ctx aws.Context, input *athena.GetQueryResultsInput, opts ...request.Option) (out *athena.GetQueryResultsOutput, err error) {
+	err = c.do(ctx, "GetQueryResults", func() error {
+		out, err = c.AthenaAPI.GetQueryResultsWithContext(ctx, input, opts...)
+		return err
+	})
+	return
+}
+
+// GetQueryResultsPages iterates over the pages of GetQueryResults API with retries.
+func (c *RetryClient) GetQueryResultsPages(input *athena.GetQueryResultsInput, fn func(*athena.GetQueryResultsOutput, bool) bool) error {
+	return c.GetQueryResultsPagesWithContext(aws.BackgroundContext(), input, fn)
+}
+
+// GetQueryResultsPagesWithContext iterates over the pages of GetQueryResults API with retries.
+func (c *RetryClient) GetQueryResultsPagesWithContext(ctx aws.Context, input *athena.GetQueryResultsInput, fn func(*athena.GetQueryResultsOutput, bool) bool, opts ...request.Option) error {
+	in := *input
+	for {
+		out, err := c.GetQueryResultsWithContext(ctx, &in, opts...)
+		if err != nil {
+			return err
+		}
+		lastPage := aws.StringValue(out.NextToken) == ""
+		if !fn(out, lastPage) || lastPage {
+			return nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+// GetNamedQuery calls GetNamedQuery API with retries.
+func (c *RetryClient) GetNamedQuery(input *athena.GetNamedQueryInput) (*athena.GetNamedQueryOutput, error) {
+	return c.GetNamedQueryWithContext(aws.BackgroundContext(), input)
+}
+
+// GetNamedQueryWithContext calls GetNamedQuery API with retries.
+func (c *RetryClient) GetNamedQueryWithContext(ctx aws.Context, input *athena.GetNamedQueryInput, opts ...request.Option) (out *athena.GetNamedQueryOutput, err error) {
+	err = c.do(ctx, "GetNamedQuery", func() error {
+		out, err = c.AthenaAPI.GetNamedQueryWithContext(ctx, input, opts...)
+		return err
+	})
+	return
+}
+
+// BatchGetNamedQuery calls BatchGetNamedQuery API with retries.
+func (c *RetryClient) BatchGetNamedQuery(input *athena.BatchGetNamedQueryInput) (*athena.BatchGetNamedQueryOutput, error) {
+	return c.BatchGetNamedQueryWithContext(aws.BackgroundContext(), input)
+}
+
+// BatchGetNamedQueryWithContext calls BatchGetNamedQuery API with retries.
+func (c *RetryClient) BatchGetNamedQueryWithContext(ctx aws.Context, input *athena.BatchGetNamedQueryInput, opts ...request.Option) (out *athena.BatchGetNamedQueryOutput, err error) {
+	err = c.do(ctx, "BatchGetNamedQuery", func() error {
+		out, err = c.AthenaAPI.BatchGetNamedQueryWithContext(ctx, input, opts...)
+		return err
+	})
+	return
+}
+
+// ListNamedQueries calls ListNamedQueries API with retries.
+func (c *RetryClient) ListNamedQueries(input *athena.ListNamedQueriesInput) (*athena.ListNamedQueriesOutput, error) {
+	return c.ListNamedQueriesWithContext(aws.BackgroundContext(), input)
+}
+
+// ListNamedQueriesWithContext calls ListNamedQueries API with retries.
+func (c *RetryClient) ListNamedQueriesWithContext(ctx aws.Context, input *athena.ListNamedQueriesInput, opts ...request.Option) (out *athena.ListNamedQueriesOutput, err error) {
+	err = c.do(ctx, "ListNamedQueries", func() error {
+		out, err = c.AthenaAPI.ListNamedQueriesWithContext(ctx, input, opts...)
+		return err
+	})
+	return
+}
+
+// ListNamedQueriesPages iterates over the pages of ListNamedQueries API with retries.
+func (c *RetryClient) ListNamedQueriesPages(input *athena.ListNamedQueriesInput, fn func(*athena.ListNamedQueriesOutput, bool) bool) error {
+	return c.ListNamedQueriesPagesWithContext(aws.BackgroundContext(), input, fn)
+}
+
+// ListNamedQueriesPagesWithContext iterates over the pages of ListNamedQueries API with retries.
+func (c *RetryClient) ListNamedQueriesPagesWithContext(ctx aws.Context, input *athena.ListNamedQueriesInput, fn func(*athena.ListNamedQueriesOutput, bool) bool, opts ...request.Option) error {
+	in := *input
+	for {
+		out, err := c.ListNamedQueriesWithContext(ctx, &in, opts...)
+		if err != nil {
+			return err
+		}
+		lastPage := aws.StringValue(out.NextToken) == ""
+		if !fn(out, lastPage) || lastPage {
+			return nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
@@ -0,0 +1,169 @@
+package exec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     ConstantBackoff(time.Millisecond),
+	Codes:       DefaultRetryableCodes,
+}
+
+// flakyClient fails StartQueryExecution, GetQueryResults and ListNamedQueries API calls `fails`
+// times each.
+type flakyClient struct {
+	*stub.Client
+	err          error
+	fails        int
+	startCalls   int
+	startTokens  []string
+	resultsCalls int
+	listCalls    int
+}
+
+func (c *flakyClient) StartQueryExecutionWithContext(ctx aws.Context, input *athena.StartQueryExecutionInput, opts ...request.Option) (*athena.StartQueryExecutionOutput, error) {
+	c.startCalls++
+	c.startTokens = append(c.startTokens, aws.StringValue(input.ClientRequestToken))
+	if c.startCalls <= c.fails {
+		return nil, c.err
+	}
+	return c.Client.StartQueryExecutionWithContext(ctx, input, opts...)
+}
+
+func (c *flakyClient) GetQueryResultsWithContext(ctx aws.Context, input *athena.GetQueryResultsInput, opts ...request.Option) (*athena.GetQueryResultsOutput, error) {
+	c.resultsCalls++
+	if c.resultsCalls <= c.fails {
+		return nil, c.err
+	}
+	return c.Client.GetQueryResultsWithContext(ctx, input, opts...)
+}
+
+func (c *flakyClient) ListNamedQueriesWithContext(ctx aws.Context, input *athena.ListNamedQueriesInput, opts ...request.Option) (*athena.ListNamedQueriesOutput, error) {
+	c.listCalls++
+	if c.listCalls <= c.fails {
+		return nil, c.err
+	}
+	return c.Client.ListNamedQueriesWithContext(ctx, input, opts...)
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: awserr.New("ThrottlingException", "Rate exceeded", nil), want: true},
+		{err: awserr.New(athena.ErrCodeTooManyRequestsException, "Too many requests", nil), want: true},
+		{err: awserr.New(athena.ErrCodeInvalidRequestException, "Invalid request", nil), want: false},
+		{err: errors.New("ThrottlingException"), want: false},
+		{err: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, DefaultRetryPolicy.Retryable(tt.err), "Error: %#v", tt.err)
+	}
+}
+
+func TestRetryClientRun(t *testing.T) {
+	id := "TestRetryClientRun"
+	query := "SELECT * FROM test_retry_client_table"
+	client := &flakyClient{
+		Client: stub.NewClient(&stub.Result{ID: id, Query: query, ResultSet: athena.ResultSet{
+			Rows: testhelper.CreateRows([][]string{{"a"}, {"1"}}),
+		}}),
+		err:   awserr.New("ThrottlingException", "Rate exceeded", nil),
+		fails: 2,
+	}
+	client.MaxPages = 2
+	q := newQuery(NewRetryClient(client, testRetryPolicy), cfg, query)
+
+	r, err := q.Run(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, client.startCalls)
+	assert.NotEmpty(t, client.startTokens[0])
+	assert.Equal(t, []string{client.startTokens[0], client.startTokens[0], client.startTokens[0]}, client.startTokens,
+		"retries should reuse the same ClientRequestToken")
+	assert.Equal(t, 4, client.resultsCalls, "pages already received should not be fetched again")
+	assert.Len(t, r.Rows(), 4)
+	assert.Equal(t, 4, r.Retries())
+}
+
+func TestRetryClientListNamedQueries(t *testing.T) {
+	client := &flakyClient{
+		Client: stub.NewClient().WithNamedQueries(&athena.NamedQuery{NamedQueryId: aws.String("TestRetryClientListNamedQueries")}),
+		err:    awserr.New("ThrottlingException", "Rate exceeded", nil),
+		fails:  2,
+	}
+
+	var ids []string
+	err := NewRetryClient(client, testRetryPolicy).ListNamedQueriesPages(&athena.ListNamedQueriesInput{},
+		func(page *athena.ListNamedQueriesOutput, lastPage bool) bool {
+			ids = append(ids, aws.StringValueSlice(page.NamedQueryIds)...)
+			return true
+		})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, client.listCalls)
+	assert.Equal(t, []string{"TestRetryClientListNamedQueries"}, ids)
+}
+
+func TestRetryClientError(t *testing.T) {
+	tests := []struct {
+		err       error
+		fails     int
+		wantCalls int
+	}{
+		{
+			err:       awserr.New("ThrottlingException", "Rate exceeded", nil),
+			fails:     5,
+			wantCalls: 3,
+		},
+		{
+			err:       awserr.New(athena.ErrCodeInvalidRequestException, "Invalid request", nil),
+			fails:     1,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		client := &flakyClient{Client: stub.NewClient(), err: tt.err, fails: tt.fails}
+		q := newQuery(NewRetryClient(client, testRetryPolicy), cfg, "SELECT 1")
+
+		err := q.Start(context.Background())
+
+		assert.Error(t, err)
+		assert.Equal(t, tt.wantCalls, client.startCalls, "Error: %#v", tt.err)
+	}
+}
+
+func TestRetryClientCanceled(t *testing.T) {
+	client := &flakyClient{
+		Client: stub.NewClient(),
+		err:    awserr.New("ThrottlingException", "Rate exceeded", nil),
+		fails:  5,
+	}
+	policy := testRetryPolicy
+	policy.Backoff = ConstantBackoff(time.Minute)
+	q := newQuery(NewRetryClient(client, policy), cfg, "SELECT 1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := q.Start(ctx)
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, client.startCalls)
+	assert.True(t, time.Since(start) < 10*time.Second, "retries should be given up as soon as canceled")
+}
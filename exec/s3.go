@@ -0,0 +1,140 @@
+package exec
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/pkg/errors"
+)
+
+// ObjectGetter gets objects in Amazon S3.
+type ObjectGetter interface {
+	// GetObject gets the content of the object at key in bucket. It must be closed after use.
+	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+}
+
+// S3Client is a minimal client of Amazon S3 which only gets objects such as query results.
+//
+// The S3 service package of the vendored AWS SDK cannot be built since the protocol encoders it
+// depends on are missing, so S3Client sends requests through the request pipeline of AWS SDK
+// instead. Credentials, endpoint resolution, signing, retries and logging are all done by it.
+type S3Client struct {
+	*client.Client
+}
+
+// NewS3Client creates a new S3Client with the configurations of sess.
+// If endpoint is not empty, requests are sent to it with path-style URLs such as
+// `http://localhost:9000/bucket/key` instead of the endpoint of Amazon S3 in the region,
+// which is useful to use S3-compatible storages.
+func NewS3Client(sess *session.Session, endpoint string) (*S3Client, error) {
+	if endpoint != "" {
+		if u, err := url.Parse(endpoint); err != nil || u.Host == "" {
+			return nil, errors.Errorf("invalid S3 endpoint %q", endpoint)
+		}
+	}
+
+	cfg := aws.NewConfig().WithEndpoint(endpoint).WithS3ForcePathStyle(endpoint != "")
+	cc := sess.ClientConfig("s3", cfg)
+	c := &S3Client{
+		Client: client.New(
+			*cc.Config,
+			metadata.ClientInfo{
+				ServiceName:   "s3",
+				SigningName:   cc.SigningName,
+				SigningRegion: cc.SigningRegion,
+				Endpoint:      cc.Endpoint,
+				APIVersion:    "2006-03-01",
+			},
+			cc.Handlers,
+		),
+	}
+	c.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	c.Handlers.Unmarshal.PushBack(unmarshalObject)
+	c.Handlers.UnmarshalError.PushBack(unmarshalS3Error)
+
+	log.Printf("Created S3 client: endpoint = %s, region = %s\n", cc.Endpoint, cc.SigningRegion)
+	return c, nil
+}
+
+// objectURL returns the URL of the object at key in bucket.
+func (c *S3Client) objectURL(bucket, key string) (*url.URL, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid S3 endpoint %q", c.Endpoint)
+	}
+	// Bucket names with dots cannot be used as hosts with TLS
+	if aws.BoolValue(c.Config.S3ForcePathStyle) || strings.Contains(bucket, ".") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket + "/" + key
+	} else {
+		u.Host = bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return u, nil
+}
+
+// getObjectOutput is the output of GetObject API.
+type getObjectOutput struct {
+	Body io.ReadCloser
+}
+
+// GetObject gets the content of the object at key in bucket with GetObject API.
+// It must be closed after use.
+func (c *S3Client) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	u, err := c.objectURL(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &request.Operation{Name: "GetObject", HTTPMethod: http.MethodGet, HTTPPath: "/"}
+	out := &getObjectOutput{}
+	req := c.NewRequest(op, nil, out)
+	req.HTTPRequest.URL = u
+	req.SetContext(ctx)
+
+	log.Printf("Getting S3 object: %s\n", u)
+	if err := req.Send(); err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// unmarshalObject passes the body of a GetObject response to the output as is.
+func unmarshalObject(r *request.Request) {
+	if out, ok := r.Data.(*getObjectOutput); ok {
+		out.Body = r.HTTPResponse.Body
+	}
+}
+
+// s3Error is an error response of Amazon S3.
+type s3Error struct {
+	Code      string `xml:"Code"`
+	Message   string `xml:"Message"`
+	RequestID string `xml:"RequestId"`
+}
+
+// unmarshalS3Error unmarshals an error response of Amazon S3 into the error of r.
+func unmarshalS3Error(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+	body, _ := ioutil.ReadAll(r.HTTPResponse.Body)
+	var serr s3Error
+	if xml.Unmarshal(body, &serr) != nil || serr.Code == "" {
+		serr.Code, serr.Message = http.StatusText(r.HTTPResponse.StatusCode), strings.TrimSpace(string(body))
+	}
+	if serr.RequestID == "" {
+		serr.RequestID = r.HTTPResponse.Header.Get("X-Amz-Request-Id")
+	}
+	r.Error = awserr.NewRequestFailure(awserr.New(serr.Code, serr.Message, nil), r.HTTPResponse.StatusCode, serr.RequestID)
+}
@@ -0,0 +1,84 @@
+package exec
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/skatsuta/athenai/internal/stub"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSession(region string) *session.Session {
+	return session.Must(session.NewSession(aws.NewConfig().
+		WithRegion(region).
+		WithCredentials(credentials.NewStaticCredentials("AKID", "SECRET", ""))))
+}
+
+// newTestS3Client creates a new S3Client which sends requests to endpoint.
+func newTestS3Client(t *testing.T, endpoint string) *S3Client {
+	c, err := NewS3Client(newTestSession("us-east-1"), endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestS3ClientGetObject(t *testing.T) {
+	srv := stub.NewS3Server()
+	defer srv.Close()
+	srv.PutObject("s3://bucket/path/to/result.csv", `"a"`+"\n")
+
+	c := newTestS3Client(t, srv.URL)
+	body, err := c.GetObject(context.Background(), "bucket", "path/to/result.csv")
+
+	if assert.NoError(t, err) {
+		defer body.Close()
+		b, err := ioutil.ReadAll(body)
+		assert.NoError(t, err)
+		assert.Equal(t, `"a"`+"\n", string(b))
+	}
+
+	_, err = c.GetObject(context.Background(), "bucket", "nonexistent.csv")
+	if assert.Error(t, err) {
+		rerr, ok := err.(awserr.RequestFailure)
+		if assert.True(t, ok, "Error: %#v", err) {
+			assert.Equal(t, "NoSuchKey", rerr.Code())
+			assert.Equal(t, 404, rerr.StatusCode())
+		}
+	}
+}
+
+func TestS3ClientObjectURL(t *testing.T) {
+	tests := []struct {
+		region   string
+		endpoint string
+		bucket   string
+		want     string
+	}{
+		{region: "us-east-1", bucket: "bucket", want: "https://bucket.s3.amazonaws.com/path/to/key.csv"},
+		{region: "ap-northeast-1", bucket: "bucket", want: "https://bucket.s3-ap-northeast-1.amazonaws.com/path/to/key.csv"},
+		{region: "ap-northeast-1", bucket: "my.bucket", want: "https://s3-ap-northeast-1.amazonaws.com/my.bucket/path/to/key.csv"},
+		{region: "us-east-1", endpoint: "http://localhost:9000", bucket: "bucket", want: "http://localhost:9000/bucket/path/to/key.csv"},
+		{region: "us-east-1", endpoint: "http://localhost:9000/s3/", bucket: "bucket", want: "http://localhost:9000/s3/bucket/path/to/key.csv"},
+	}
+
+	for _, tt := range tests {
+		c, err := NewS3Client(newTestSession(tt.region), tt.endpoint)
+
+		if !assert.NoError(t, err, "Region: %s, Endpoint: %s", tt.region, tt.endpoint) {
+			continue
+		}
+		u, err := c.objectURL(tt.bucket, "path/to/key.csv")
+		if assert.NoError(t, err, "Region: %s, Endpoint: %s", tt.region, tt.endpoint) {
+			assert.Equal(t, tt.want, u.String(), "Region: %s, Endpoint: %s", tt.region, tt.endpoint)
+		}
+	}
+
+	_, err := NewS3Client(newTestSession("us-east-1"), "localhost")
+	assert.Error(t, err)
+}
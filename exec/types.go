@@ -0,0 +1,224 @@
+package exec
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Layouts of date and timestamp values returned by Athena.
+const (
+	DateLayout      = "2006-01-02"
+	TimestampLayout = "2006-01-02 15:04:05.999999999"
+)
+
+// BaseType returns a type name without parameters, e.g. `decimal` for `decimal(10,2)`.
+func BaseType(typ string) string {
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		typ = typ[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(typ))
+}
+
+// IsHeader returns true if row consists of the names of cols.
+// Athena returns the column names as the first row of the results of SELECT statements.
+func IsHeader(cols []Column, row []string) bool {
+	if len(cols) != len(row) {
+		return false
+	}
+	for i, col := range cols {
+		if col.Name != row[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseBigint parses a value of integer types (tinyint, smallint, integer and bigint).
+func ParseBigint(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid bigint value %q", s)
+	}
+	return n, nil
+}
+
+// ParseDouble parses a value of floating-point types (real, float and double).
+// It accepts NaN, Infinity and -Infinity as well.
+func ParseDouble(s string) (float64, error) {
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(+1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid double value %q", s)
+	}
+	return f, nil
+}
+
+// ParseDecimal parses a value of decimal type without losing its precision.
+func ParseDecimal(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, errors.Errorf("invalid decimal value %q", s)
+	}
+	return r, nil
+}
+
+// ParseBoolean parses a value of boolean type.
+func ParseBoolean(s string) (bool, error) {
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, errors.Errorf("invalid boolean value %q", s)
+}
+
+// ParseDate parses a value of date type.
+func ParseDate(s string) (time.Time, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "invalid date value %q", s)
+	}
+	return t, nil
+}
+
+// ParseTimestamp parses a value of timestamp type. A value of timestamp with time zone type,
+// which has a time zone name such as `UTC` or `Asia/Tokyo` or an offset such as `+09:00` at
+// the end, is also accepted. Timestamps without time zone are parsed in UTC.
+func ParseTimestamp(s string) (time.Time, error) {
+	loc := time.UTC
+	value := s
+	if i := strings.LastIndex(s, " "); i > len(DateLayout) {
+		// Timestamp with time zone
+		l, err := parseTimeZone(s[i+1:])
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "invalid time zone in timestamp value %q", s)
+		}
+		loc = l
+		value = s[:i]
+	}
+
+	t, err := time.ParseInLocation(TimestampLayout, value, loc)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "invalid timestamp value %q", s)
+	}
+	return t, nil
+}
+
+// parseTimeZone parses a time zone name such as `Asia/Tokyo` or an offset such as `+09:00`.
+func parseTimeZone(zone string) (*time.Location, error) {
+	if !strings.HasPrefix(zone, "+") && !strings.HasPrefix(zone, "-") {
+		return time.LoadLocation(zone)
+	}
+
+	t, err := time.Parse("-07:00", zone)
+	if err != nil {
+		return nil, err
+	}
+	_, offset := t.Zone()
+	return time.FixedZone(zone, offset), nil
+}
+
+// ParseArray parses a value of array type such as `[1, 2, 3]` into its elements.
+// Nested arrays, maps and rows are returned as they are so that they can be parsed recursively.
+//
+// Athena does not quote strings in arrays, so strings containing `, ` cannot be parsed correctly.
+func ParseArray(s string) ([]string, error) {
+	inner, err := unwrap(s, '[', ']')
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid array value %q", s)
+	}
+	return splitElements(inner), nil
+}
+
+// ParseMap parses a value of map type such as `{a=1, b=2}` into its keys and values.
+//
+// Athena does not quote strings in maps, so strings containing `, ` or `=` cannot be parsed
+// correctly.
+func ParseMap(s string) (map[string]string, error) {
+	fields, err := ParseRow(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid map value %q", s)
+	}
+
+	m := make(map[string]string, len(fields))
+	for _, f := range fields {
+		m[f.Name] = f.Value
+	}
+	return m, nil
+}
+
+// RowField is a field of a value of row type.
+type RowField struct {
+	Name  string
+	Value string
+}
+
+// ParseRow parses a value of row type such as `{x=1, y=foo}` into its fields in order.
+//
+// Athena does not quote strings in rows, so strings containing `, ` or `=` cannot be parsed
+// correctly.
+func ParseRow(s string) ([]RowField, error) {
+	inner, err := unwrap(s, '{', '}')
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid row value %q", s)
+	}
+
+	elems := splitElements(inner)
+	fields := make([]RowField, len(elems))
+	for i, elem := range elems {
+		kv := strings.SplitN(elem, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid field %q in row value %q", elem, s)
+		}
+		fields[i] = RowField{Name: kv[0], Value: kv[1]}
+	}
+	return fields, nil
+}
+
+// unwrap removes the opening and closing brackets from s.
+func unwrap(s string, open, close byte) (string, error) {
+	l := len(s)
+	if l < 2 || s[0] != open || s[l-1] != close {
+		return "", errors.Errorf("not enclosed in %c%c", open, close)
+	}
+	return s[1 : l-1], nil
+}
+
+// splitElements splits s by `, ` at the top level, that is, outside of any brackets.
+func splitElements(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	var elems []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 && i+1 < len(s) && s[i+1] == ' ' {
+				elems = append(elems, s[start:i])
+				start = i + 2
+				i++
+			}
+		}
+	}
+	return append(elems, s[start:])
+}
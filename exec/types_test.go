@@ -0,0 +1,171 @@
+package exec
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseType(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want string
+	}{
+		{"varchar", "varchar"},
+		{"decimal(10,2)", "decimal"},
+		{" CHAR(3) ", "char"},
+		{"array<integer>", "array<integer>"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, BaseType(tt.typ), "Type: %q", tt.typ)
+	}
+}
+
+func TestIsHeader(t *testing.T) {
+	cols := []Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "varchar"}}
+
+	assert.True(t, IsHeader(cols, []string{"id", "name"}))
+	assert.False(t, IsHeader(cols, []string{"1", "foo"}))
+	assert.False(t, IsHeader(cols, []string{"id"}))
+}
+
+func TestParseBigint(t *testing.T) {
+	got, err := ParseBigint("-9223372036854775808")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MinInt64), got)
+
+	_, err = ParseBigint("1.5")
+	assert.Error(t, err)
+}
+
+func TestParseDouble(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"1.5", 1.5},
+		{"1.0E10", 1e10},
+		{"Infinity", math.Inf(+1)},
+		{"-Infinity", math.Inf(-1)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDouble(tt.s)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, "Value: %q", tt.s)
+	}
+
+	got, err := ParseDouble("NaN")
+	assert.NoError(t, err)
+	assert.True(t, math.IsNaN(got))
+
+	_, err = ParseDouble("foo")
+	assert.Error(t, err)
+}
+
+func TestParseDecimal(t *testing.T) {
+	got, err := ParseDecimal("12345678901234567890.12")
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678901234567890.12", got.FloatString(2))
+
+	_, err = ParseDecimal("12,3")
+	assert.Error(t, err)
+}
+
+func TestParseBoolean(t *testing.T) {
+	got, err := ParseBoolean("true")
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	got, err = ParseBoolean("false")
+	assert.NoError(t, err)
+	assert.False(t, got)
+
+	_, err = ParseBoolean("1")
+	assert.Error(t, err)
+}
+
+func TestParseDate(t *testing.T) {
+	got, err := ParseDate("2017-07-01")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC), got)
+
+	_, err = ParseDate("2017/07/01")
+	assert.Error(t, err)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("time zone database is not available:", err)
+	}
+
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"2017-07-01 12:34:56", time.Date(2017, 7, 1, 12, 34, 56, 0, time.UTC)},
+		{"2017-07-01 12:34:56.789", time.Date(2017, 7, 1, 12, 34, 56, 789000000, time.UTC)},
+		{"2017-07-01 12:34:56.789 UTC", time.Date(2017, 7, 1, 12, 34, 56, 789000000, time.UTC)},
+		{"2017-07-01 12:34:56.789 Asia/Tokyo", time.Date(2017, 7, 1, 12, 34, 56, 789000000, tokyo)},
+		{"2017-07-01 12:34:56.789 +09:00", time.Date(2017, 7, 1, 3, 34, 56, 789000000, time.UTC)},
+		{"2017-07-01 12:34:56.789 -05:30", time.Date(2017, 7, 1, 18, 4, 56, 789000000, time.UTC)},
+		{"2017-07-01 12:34:56 +00:00", time.Date(2017, 7, 1, 12, 34, 56, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimestamp(tt.s)
+
+		assert.NoError(t, err)
+		assert.True(t, tt.want.Equal(got), "Value: %q, Got: %s", tt.s, got)
+	}
+
+	for _, s := range []string{"2017-07-01 12:34:56 Nowhere/Unknown", "2017-07-01 12:34:56 +0900"} {
+		_, err = ParseTimestamp(s)
+		assert.Error(t, err, "Value: %q", s)
+	}
+}
+
+func TestParseArray(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"[]", []string{}},
+		{"[1, 2, 3]", []string{"1", "2", "3"}},
+		{"[[1, 2], [3]]", []string{"[1, 2]", "[3]"}},
+		{"[{a=1, b=2}, {a=3, b=4}]", []string{"{a=1, b=2}", "{a=3, b=4}"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseArray(tt.s)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, "Value: %q", tt.s)
+	}
+
+	_, err := ParseArray("{1, 2}")
+	assert.Error(t, err)
+}
+
+func TestParseMap(t *testing.T) {
+	got, err := ParseMap("{a=1, b=[2, 3]}")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "[2, 3]"}, got)
+
+	_, err = ParseMap("{a}")
+	assert.Error(t, err)
+}
+
+func TestParseRow(t *testing.T) {
+	got, err := ParseRow("{x=1, y=foo, z={p=2}}")
+	assert.NoError(t, err)
+	assert.Equal(t, []RowField{{"x", "1"}, {"y", "foo"}, {"z", "{p=2}"}}, got)
+
+	_, err = ParseRow("[x=1]")
+	assert.Error(t, err)
+}
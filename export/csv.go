@@ -0,0 +1,44 @@
+package export
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/exec"
+)
+
+// csvGzipWriter writes rows in gzip-compressed CSV format with a header of the column names.
+// NULL is written as an empty field.
+type csvGzipWriter struct {
+	zw *gzip.Writer
+	w  *csv.Writer
+}
+
+func newCSVGzipWriter(w io.Writer, cols []exec.Column) (*csvGzipWriter, error) {
+	zw := gzip.NewWriter(w)
+	cw := &csvGzipWriter{zw: zw, w: csv.NewWriter(zw)}
+
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = col.Name
+	}
+	if err := cw.w.Write(header); err != nil {
+		return nil, errors.Wrap(err, "failed to write CSV header")
+	}
+	return cw, nil
+}
+
+func (cw *csvGzipWriter) Write(row []string, _ []bool) error {
+	return cw.w.Write(row)
+}
+
+// Close flushes the rows and ends the gzip stream. It does not close the underlying writer.
+func (cw *csvGzipWriter) Close() error {
+	cw.w.Flush()
+	if err := cw.w.Error(); err != nil {
+		return errors.Wrap(err, "failed to write CSV")
+	}
+	return cw.zw.Close()
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/skatsuta/athenai/exec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVGzipWriter(t *testing.T) {
+	tests := []struct {
+		cols  []exec.Column
+		rows  [][]string
+		nulls [][]bool
+		want  string
+	}{
+		{
+			cols:  []exec.Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "varchar"}},
+			rows:  [][]string{{"1", "alice"}, {"2", ""}, {"3", "a,\"b\"\nc"}},
+			nulls: [][]bool{{false, false}, {false, true}, {false, false}},
+			want:  "id,name\n1,alice\n2,\n3,\"a,\"\"b\"\"\nc\"\n",
+		},
+		{
+			cols: []exec.Column{{Name: "id", Type: "integer"}},
+			want: "id\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w, err := newCSVGzipWriter(&buf, tt.cols)
+		assert.NoError(t, err)
+		for i, row := range tt.rows {
+			assert.NoError(t, w.Write(row, tt.nulls[i]))
+		}
+		assert.NoError(t, w.Close())
+
+		zr, err := gzip.NewReader(&buf)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		got, err := ioutil.ReadAll(zr)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, string(got))
+	}
+}
@@ -0,0 +1,357 @@
+// Package export exports results of query executions to local files in machine-readable formats.
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/exec"
+)
+
+// Formats of exported files.
+const (
+	FormatCSVGzip = "csv.gz"
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+// Formats are all the available formats of exported files.
+var Formats = []string{FormatCSVGzip, FormatJSONL, FormatParquet}
+
+// IsValidFormat returns true if format is one of Formats, otherwise false.
+func IsValidFormat(format string) bool {
+	for _, f := range Formats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Placeholders in patterns of file paths.
+const (
+	placeholderIndex = "{index}"
+	placeholderID    = "{id}"
+)
+
+// ManifestFileName is the name of the manifest file written in the export directory.
+const ManifestFileName = "manifest.json"
+
+// Result represents results of a query execution to export.
+type Result interface {
+	Info() *athena.QueryExecution
+	Columns() []exec.Column
+	EachRow(fn func(row []string, nulls []bool) bool) error
+}
+
+// rowWriter writes rows to a file in a format.
+type rowWriter interface {
+	Write(row []string, nulls []bool) error
+	Close() error
+}
+
+// newRowWriter creates a new rowWriter which writes rows of cols to w in format.
+func newRowWriter(w io.Writer, format string, cols []exec.Column) (rowWriter, error) {
+	switch format {
+	case FormatCSVGzip:
+		return newCSVGzipWriter(w, cols)
+	case FormatJSONL:
+		return newJSONLWriter(w, cols)
+	case FormatParquet:
+		return newParquetWriter(w, cols)
+	}
+	return nil, errors.Errorf("unknown export format %q", format)
+}
+
+// Column is a column of an exported file.
+type Column struct {
+	Name string `json:"Name"`
+	Type string `json:"Type"`
+}
+
+// Entry is an entry of the manifest, which describes a file exported from the results of
+// a query execution.
+type Entry struct {
+	Index            int      `json:"Index"` // Index of the statement starting from 1
+	Query            string   `json:"Query"`
+	QueryExecutionID string   `json:"QueryExecutionId"`
+	Format           string   `json:"Format"`
+	Path             string   `json:"Path"`
+	RowCount         int64    `json:"RowCount"`
+	Columns          []Column `json:"Columns"`
+}
+
+// manifest is the content of a manifest file.
+type manifest struct {
+	Results []*Entry `json:"Results"`
+}
+
+// Exporter exports the rows of each result to its own file, and lists the files in a manifest.
+// Exporter is goroutine-safe.
+type Exporter struct {
+	pattern  string // Pattern of file paths
+	format   string
+	manifest string // Path to the manifest file
+
+	mu      sync.Mutex
+	entries []*Entry
+	count   int // Number of statements whose indices have been reserved
+}
+
+// New creates a new Exporter which exports results to files in dir, named after the index of
+// the statement and the query execution ID, e.g. `1-<QueryExecutionId>.csv.gz`.
+// If format is empty, it is gzip-compressed CSV.
+func New(dir, format string) (*Exporter, error) {
+	if format == "" {
+		format = FormatCSVGzip
+	}
+	if !IsValidFormat(format) {
+		return nil, errors.Errorf("invalid export format %q. Valid values: %s", format, strings.Join(Formats, ", "))
+	}
+	dir, err := homedir.Expand(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to expand path")
+	}
+
+	pattern := filepath.Join(dir, placeholderIndex+"-"+placeholderID+"."+format)
+	return &Exporter{
+		pattern:  pattern,
+		format:   format,
+		manifest: filepath.Join(dir, ManifestFileName),
+	}, nil
+}
+
+// NewWithPattern creates a new Exporter which exports results to files at the paths made from
+// pattern by replacing `{index}` with the index of the statement and `{id}` with the query
+// execution ID. The manifest is written in the directory of pattern up to the first placeholder.
+//
+// If format is empty, it is guessed from the extension of pattern, and defaults to
+// gzip-compressed CSV.
+func NewWithPattern(pattern, format string) (*Exporter, error) {
+	if !strings.Contains(pattern, placeholderIndex) && !strings.Contains(pattern, placeholderID) {
+		return nil, errors.Errorf("pattern %q must contain %s or %s to name each file", pattern, placeholderIndex, placeholderID)
+	}
+	if format == "" {
+		format = guessFormat(pattern)
+	}
+	if !IsValidFormat(format) {
+		return nil, errors.Errorf("invalid export format %q. Valid values: %s", format, strings.Join(Formats, ", "))
+	}
+	pattern, err := homedir.Expand(pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to expand path")
+	}
+
+	return &Exporter{
+		pattern:  pattern,
+		format:   format,
+		manifest: filepath.Join(staticDir(pattern), ManifestFileName),
+	}, nil
+}
+
+// guessFormat guesses the format from the extension of path.
+func guessFormat(path string) string {
+	for _, f := range Formats {
+		if strings.HasSuffix(path, "."+f) {
+			return f
+		}
+	}
+	return FormatCSVGzip
+}
+
+// staticDir returns the directory of pattern up to the first placeholder.
+func staticDir(pattern string) string {
+	if i := strings.Index(pattern, "{"); i >= 0 {
+		pattern = pattern[:i]
+		if !strings.HasSuffix(pattern, string(filepath.Separator)) {
+			pattern = filepath.Dir(pattern)
+		}
+		return filepath.Clean(pattern)
+	}
+	return filepath.Dir(pattern)
+}
+
+// Format returns the format of exported files.
+func (e *Exporter) Format() string {
+	return e.format
+}
+
+// ManifestPath returns the path to the manifest file.
+func (e *Exporter) ManifestPath() string {
+	return e.manifest
+}
+
+// Reserve reserves indices for n statements and returns the offset to add to their indices
+// (starting from 1) to get those to export them with. Indices keep increasing across runs,
+// e.g. in interactive mode, so that the files and the entries of the manifest do not collide.
+func (e *Exporter) Reserve(n int) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	offset := e.count
+	e.count += n
+	return offset
+}
+
+// path returns the path of the file for the statement at index whose query execution ID is id.
+func (e *Exporter) path(index int, id string) string {
+	r := strings.NewReplacer(placeholderIndex, strconv.Itoa(index), placeholderID, id)
+	return r.Replace(e.pattern)
+}
+
+// Export writes the rows of r, which is the result of the statement at index (starting from 1),
+// to a file, and returns an entry of the manifest for it. The header row of the results is not
+// written as a row.
+//
+// The rows are written as they are read from r, and the file appears at its path only once all
+// of them have been written.
+func (e *Exporter) Export(index int, r Result) (*Entry, error) {
+	info := r.Info()
+	id := aws.StringValue(info.QueryExecutionId)
+	path := e.path(index, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create directory")
+	}
+
+	cols := r.Columns()
+	entry := &Entry{
+		Index:            index,
+		Query:            aws.StringValue(info.Query),
+		QueryExecutionID: id,
+		Format:           e.format,
+		Path:             path,
+		Columns:          make([]Column, len(cols)),
+	}
+	for i, col := range cols {
+		entry.Columns[i] = Column{Name: col.Name, Type: col.Type}
+	}
+
+	log.Printf("Exporting results of query execution %s to %s\n", id, path)
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create file")
+	}
+	defer os.Remove(tmp.Name()) // No-op if it has been renamed
+	defer tmp.Close()
+
+	n, err := e.write(tmp, cols, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to export results of query execution %s", id)
+	}
+	// TempFile creates a file only readable by the owner
+	if err := tmp.Chmod(0644); err != nil {
+		return nil, errors.Wrap(err, "failed to change mode of file")
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close file")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, errors.Wrap(err, "failed to rename file")
+	}
+	entry.RowCount = n
+	log.Printf("Exported %d rows of query execution %s to %s\n", n, id, path)
+
+	e.mu.Lock()
+	e.entries = append(e.entries, entry)
+	e.mu.Unlock()
+	return entry, nil
+}
+
+// write writes the rows of r to f and returns the number of them.
+// If cols is empty, the columns are named after the first row, which is the header.
+func (e *Exporter) write(f io.Writer, cols []exec.Column, r Result) (int64, error) {
+	bw := bufio.NewWriter(f)
+	var w rowWriter
+	var n int64
+	var werr error
+	err := r.EachRow(func(row []string, nulls []bool) bool {
+		if w == nil {
+			if len(cols) == 0 {
+				cols = headerColumns(row)
+			}
+			if w, werr = newRowWriter(bw, e.format, cols); werr != nil {
+				return false
+			}
+			if exec.IsHeader(cols, row) {
+				return true
+			}
+		}
+		if werr = w.Write(row, nulls); werr != nil {
+			werr = errors.Wrapf(werr, "row %d", n+1)
+			return false
+		}
+		n++
+		return true
+	})
+	if werr != nil {
+		return 0, werr
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read results")
+	}
+
+	if w == nil { // No rows
+		if w, err = newRowWriter(bw, e.format, cols); err != nil {
+			return 0, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, errors.Wrap(err, "failed to write file")
+	}
+	return n, nil
+}
+
+// headerColumns returns varchar columns named after the values of header.
+func headerColumns(header []string) []exec.Column {
+	cols := make([]exec.Column, len(header))
+	for i, name := range header {
+		cols[i] = exec.Column{Name: name, Type: "varchar"}
+	}
+	return cols
+}
+
+// WriteManifest writes the manifest file which lists all the files exported so far in the order
+// of the statements.
+func (e *Exporter) WriteManifest() error {
+	e.mu.Lock()
+	m := manifest{Results: make([]*Entry, len(e.entries))}
+	copy(m.Results, e.entries)
+	e.mu.Unlock()
+	sort.SliceStable(m.Results, func(i, j int) bool {
+		return m.Results[i].Index < m.Results[j].Index
+	})
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode manifest")
+	}
+	if err := os.MkdirAll(filepath.Dir(e.manifest), 0755); err != nil {
+		return errors.Wrap(err, "failed to create directory")
+	}
+	if err := ioutil.WriteFile(e.manifest, append(b, '\n'), 0644); err != nil {
+		return errors.Wrap(err, "failed to write manifest")
+	}
+	log.Printf("Wrote manifest of %d exported files to %s\n", len(m.Results), e.manifest)
+	return nil
+}
+
+// String returns a summary of the exported file.
+func (en *Entry) String() string {
+	return fmt.Sprintf("Exported %d rows to %s", en.RowCount, en.Path)
+}
@@ -0,0 +1,291 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/exec"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubResult struct {
+	id    string
+	query string
+	cols  []exec.Column
+	rows  [][]string
+	err   error
+}
+
+func (s *stubResult) Info() *athena.QueryExecution {
+	return &athena.QueryExecution{QueryExecutionId: aws.String(s.id), Query: aws.String(s.query)}
+}
+
+func (s *stubResult) Columns() []exec.Column {
+	return s.cols
+}
+
+func (s *stubResult) EachRow(fn func(row []string, nulls []bool) bool) error {
+	for _, row := range s.rows {
+		nulls := make([]bool, len(row))
+		for i, v := range row {
+			nulls[i] = v == "NULL"
+		}
+		if !fn(row, nulls) {
+			return nil
+		}
+	}
+	return s.err
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		dir          string
+		format       string
+		wantFormat   string
+		wantPath     string
+		wantManifest string
+		wantErr      string
+	}{
+		{"out", "", FormatCSVGzip, "out/1-id.csv.gz", "out/manifest.json", ""},
+		{"out/", FormatParquet, FormatParquet, "out/1-id.parquet", "out/manifest.json", ""},
+		{"out", "xml", "", "", "", `invalid export format "xml"`},
+	}
+
+	for _, tt := range tests {
+		e, err := New(tt.dir, tt.format)
+		if tt.wantErr != "" {
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.wantFormat, e.Format())
+		assert.Equal(t, tt.wantPath, e.path(1, "id"))
+		assert.Equal(t, tt.wantManifest, e.ManifestPath())
+	}
+}
+
+func TestNewWithPattern(t *testing.T) {
+	tests := []struct {
+		pattern      string
+		format       string
+		wantFormat   string
+		wantPath     string
+		wantManifest string
+		wantErr      string
+	}{
+		{"out/q{index}.jsonl", "", FormatJSONL, "out/q2.jsonl", "out/manifest.json", ""},
+		{"out/{id}/result.parquet", "", FormatParquet, "out/id/result.parquet", "out/manifest.json", ""},
+		{"out/{index}.csv", "", FormatCSVGzip, "out/2.csv", "out/manifest.json", ""},
+		{"out/{index}.dat", FormatJSONL, FormatJSONL, "out/2.dat", "out/manifest.json", ""},
+		{"{index}.csv.gz", "", FormatCSVGzip, "2.csv.gz", "manifest.json", ""},
+		{"out/result.csv.gz", "", "", "", "", "must contain {index} or {id}"},
+		{"out/{index}.csv.gz", "xml", "", "", "", `invalid export format "xml"`},
+	}
+
+	for _, tt := range tests {
+		e, err := NewWithPattern(tt.pattern, tt.format)
+		if tt.wantErr != "" {
+			if assert.Error(t, err, "pattern: %s", tt.pattern) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.wantFormat, e.Format(), "pattern: %s", tt.pattern)
+		assert.Equal(t, tt.wantPath, e.path(2, "id"), "pattern: %s", tt.pattern)
+		assert.Equal(t, tt.wantManifest, e.ManifestPath(), "pattern: %s", tt.pattern)
+	}
+}
+
+func TestStaticDir(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"out/{index}.csv.gz", "out"},
+		{"out/q{index}.csv.gz", "out"},
+		{"out/{id}/{index}.csv.gz", "out"},
+		{"/tmp/out/{index}/result.jsonl", "/tmp/out"},
+		{"{index}.csv.gz", "."},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, staticDir(tt.pattern), "pattern: %s", tt.pattern)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	e, err := New("out", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 0, e.Reserve(2))
+	assert.Equal(t, 2, e.Reserve(1))
+	assert.Equal(t, 3, e.Reserve(0))
+	assert.Equal(t, 3, e.Reserve(3))
+}
+
+func TestExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "athenai-export")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	e, err := New(dir, FormatJSONL)
+	assert.NoError(t, err)
+
+	cols := []exec.Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "varchar"}}
+	results := []struct {
+		index    int
+		r        *stubResult
+		wantPath string
+		want     string
+	}{
+		{
+			index: 2,
+			r: &stubResult{
+				id:    "id2",
+				query: "SELECT * FROM users",
+				cols:  cols,
+				rows:  [][]string{{"id", "name"}, {"1", "alice"}, {"2", "NULL"}},
+			},
+			wantPath: filepath.Join(dir, "2-id2.jsonl"),
+			want:     `{"id":1,"name":"alice"}` + "\n" + `{"id":2,"name":null}` + "\n",
+		},
+		{
+			index:    1,
+			r:        &stubResult{id: "id1", query: "SELECT * FROM users LIMIT 0", cols: cols},
+			wantPath: filepath.Join(dir, "1-id1.jsonl"),
+			want:     "",
+		},
+		{
+			index: 3,
+			r: &stubResult{
+				id:    "id3",
+				query: "SELECT 1",
+				rows:  [][]string{{"_col0"}, {"1"}},
+			},
+			wantPath: filepath.Join(dir, "3-id3.jsonl"),
+			want:     `{"_col0":"1"}` + "\n",
+		},
+	}
+
+	for _, tt := range results {
+		entry, err := e.Export(tt.index, tt.r)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, tt.wantPath, entry.Path)
+		assert.Equal(t, tt.r.id, entry.QueryExecutionID)
+		assert.Equal(t, tt.r.query, entry.Query)
+
+		got, err := ioutil.ReadFile(tt.wantPath)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, string(got))
+
+		info, err := os.Stat(tt.wantPath)
+		if assert.NoError(t, err) {
+			assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+		}
+	}
+
+	assert.NoError(t, e.WriteManifest())
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var m manifest
+	assert.NoError(t, json.Unmarshal(b, &m))
+	want := []*Entry{
+		{
+			Index:            1,
+			Query:            "SELECT * FROM users LIMIT 0",
+			QueryExecutionID: "id1",
+			Format:           FormatJSONL,
+			Path:             filepath.Join(dir, "1-id1.jsonl"),
+			RowCount:         0,
+			Columns:          []Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "varchar"}},
+		},
+		{
+			Index:            2,
+			Query:            "SELECT * FROM users",
+			QueryExecutionID: "id2",
+			Format:           FormatJSONL,
+			Path:             filepath.Join(dir, "2-id2.jsonl"),
+			RowCount:         2,
+			Columns:          []Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "varchar"}},
+		},
+		{
+			Index:            3,
+			Query:            "SELECT 1",
+			QueryExecutionID: "id3",
+			Format:           FormatJSONL,
+			Path:             filepath.Join(dir, "3-id3.jsonl"),
+			RowCount:         1,
+			Columns:          []Column{},
+		},
+	}
+	assert.Equal(t, want, m.Results)
+	assert.Equal(t, "Exported 2 rows to "+filepath.Join(dir, "2-id2.jsonl"), m.Results[1].String())
+}
+
+func TestExportError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "athenai-export")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	e, err := NewWithPattern(filepath.Join(dir, "{id}", "result.parquet"), "")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		r       *stubResult
+		wantErr string
+	}{
+		{
+			r: &stubResult{
+				id:   "broken",
+				cols: []exec.Column{{Name: "id", Type: "integer"}},
+				rows: [][]string{{"1"}},
+				err:  errors.New("connection reset"),
+			},
+			wantErr: "failed to export results of query execution broken: failed to read results: connection reset",
+		},
+		{
+			r: &stubResult{
+				id:   "invalid",
+				cols: []exec.Column{{Name: "id", Type: "integer"}},
+				rows: [][]string{{"1"}, {"x"}},
+			},
+			wantErr: `failed to export results of query execution invalid: row 2: column id: invalid integer value "x"`,
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := e.Export(1, tt.r)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+
+		// Neither the file nor the temporary file is left
+		files, err := ioutil.ReadDir(filepath.Join(dir, tt.r.id))
+		assert.NoError(t, err)
+		assert.Empty(t, files, "files for %s", tt.r.id)
+	}
+
+	assert.NoError(t, e.WriteManifest())
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Results": []}`, string(b))
+}
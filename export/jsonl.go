@@ -0,0 +1,88 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/skatsuta/athenai/exec"
+)
+
+// jsonlWriter writes rows in JSON Lines format, a JSON object keyed by column name per line.
+// Values are typed according to the column types, and NULL is written as null.
+type jsonlWriter struct {
+	w    io.Writer
+	cols []exec.Column
+	keys [][]byte // Encoded column names
+	buf  bytes.Buffer
+}
+
+func newJSONLWriter(w io.Writer, cols []exec.Column) (*jsonlWriter, error) {
+	jw := &jsonlWriter{w: w, cols: cols, keys: make([][]byte, len(cols))}
+	for i, col := range cols {
+		key, err := json.Marshal(col.Name)
+		if err != nil {
+			return nil, err
+		}
+		jw.keys[i] = key
+	}
+	return jw, nil
+}
+
+func (jw *jsonlWriter) Write(row []string, nulls []bool) error {
+	jw.buf.Reset()
+	jw.buf.WriteByte('{')
+	for i, col := range jw.cols {
+		if i > 0 {
+			jw.buf.WriteByte(',')
+		}
+		jw.buf.Write(jw.keys[i])
+		jw.buf.WriteByte(':')
+
+		var v interface{}
+		if i < len(row) && !nulls[i] {
+			v = jsonValue(col.Type, row[i])
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		jw.buf.Write(b)
+	}
+	jw.buf.WriteString("}\n")
+	_, err := jw.w.Write(jw.buf.Bytes())
+	return err
+}
+
+// Close does nothing since rows are written as they are given.
+func (jw *jsonlWriter) Close() error {
+	return nil
+}
+
+// jsonValue converts a value v to a JSON value corresponding to the Athena column type typ.
+// If v cannot be converted to the type, it is returned as a string.
+func jsonValue(typ, v string) interface{} {
+	switch exec.BaseType(typ) {
+	case "tinyint", "smallint", "integer", "int", "bigint":
+		if n, err := exec.ParseBigint(v); err == nil {
+			return n
+		}
+	case "float", "real", "double":
+		// NaN and Infinity cannot be represented in JSON
+		if f, err := exec.ParseDouble(v); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	case "decimal":
+		// Keep the precision of decimal values as is
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return json.Number(v)
+		}
+	case "boolean":
+		if b, err := exec.ParseBoolean(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/skatsuta/athenai/exec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLWriter(t *testing.T) {
+	cols := []exec.Column{
+		{Name: "id", Type: "integer"},
+		{Name: "name", Type: "varchar"},
+		{Name: "score", Type: "double"},
+		{Name: "price", Type: "decimal(10,2)"},
+		{Name: "ok", Type: "boolean"},
+	}
+	rows := [][]string{
+		{"1", "alice", "1.5", "12.30", "true"},
+		{"2", "", "NaN", "", "false"},
+	}
+	nulls := [][]bool{
+		{false, false, false, false, false},
+		{false, true, false, true, false},
+	}
+	want := `{"id":1,"name":"alice","score":1.5,"price":12.30,"ok":true}` + "\n" +
+		`{"id":2,"name":null,"score":"NaN","price":null,"ok":false}` + "\n"
+
+	var buf bytes.Buffer
+	w, err := newJSONLWriter(&buf, cols)
+	assert.NoError(t, err)
+	for i, row := range rows {
+		assert.NoError(t, w.Write(row, nulls[i]))
+	}
+	assert.NoError(t, w.Close())
+	assert.Equal(t, want, buf.String())
+}
+
+func TestJSONValue(t *testing.T) {
+	tests := []struct {
+		typ  string
+		v    string
+		want interface{}
+	}{
+		{"tinyint", "-1", int64(-1)},
+		{"bigint", "9223372036854775807", int64(9223372036854775807)},
+		{"integer", "x", "x"},
+		{"real", "1.5", 1.5},
+		{"double", "Infinity", "Infinity"},
+		{"decimal(38,0)", "12345678901234567890123", json.Number("12345678901234567890123")},
+		{"decimal(10,2)", "1/3", "1/3"},
+		{"boolean", "false", false},
+		{"date", "2017-08-01", "2017-08-01"},
+		{"varchar", "1", "1"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, jsonValue(tt.typ, tt.v), "type: %s, value: %s", tt.typ, tt.v)
+	}
+}
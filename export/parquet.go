@@ -0,0 +1,554 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/exec"
+)
+
+const (
+	parquetMagic     = "PAR1"
+	parquetCreatedBy = "athenai"
+
+	// defaultRowGroupBytes is the size of values buffered in memory before they are written as
+	// a row group.
+	defaultRowGroupBytes = 16 << 20
+)
+
+// Physical types of Parquet.
+const (
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetFloat     = 4
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// Converted types of Parquet, which annotate physical types with logical types.
+const (
+	convertedNone            = -1
+	convertedUTF8            = 0
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimestampMillis = 9
+	convertedInt8            = 15
+	convertedInt16           = 16
+)
+
+// Other enums of Parquet.
+const (
+	repetitionOptional = 1
+	encodingPlain      = 0
+	encodingRLE        = 3
+	codecGzip          = 2
+	pageTypeData       = 0
+)
+
+var decimalTypeRegexp = regexp.MustCompile(`^decimal\((\d+),\s*(\d+)\)$`)
+
+// parquetColumn is a column of a Parquet file, which buffers the values of a row group.
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32
+	precision int32 // Only for DECIMAL
+	scale     int32 // Only for DECIMAL
+	encode    func(buf *bytes.Buffer, v string) error
+
+	defs   []bool // Definition levels, i.e. whether each value is not NULL
+	values bytes.Buffer
+	bools  []bool // Values of BOOLEAN columns, which are bit-packed when written
+}
+
+// newParquetColumn creates a new parquetColumn whose type corresponds to the Athena type of col.
+func newParquetColumn(col exec.Column) *parquetColumn {
+	c := &parquetColumn{name: col.Name, converted: convertedNone}
+	switch typ := exec.BaseType(col.Type); typ {
+	case "boolean":
+		c.typ = parquetBoolean
+	case "tinyint":
+		c.typ, c.converted, c.encode = parquetInt32, convertedInt8, encodeInt32
+	case "smallint":
+		c.typ, c.converted, c.encode = parquetInt32, convertedInt16, encodeInt32
+	case "integer", "int":
+		c.typ, c.encode = parquetInt32, encodeInt32
+	case "bigint":
+		c.typ, c.encode = parquetInt64, encodeInt64
+	case "real", "float":
+		c.typ, c.encode = parquetFloat, encodeFloat
+	case "double":
+		c.typ, c.encode = parquetDouble, encodeDouble
+	case "decimal":
+		c.converted = convertedDecimal
+		c.precision, c.scale = decimalPrecision(col)
+		c.typ, c.encode = decimalEncoder(c.precision, c.scale)
+	case "date":
+		c.typ, c.converted, c.encode = parquetInt32, convertedDate, encodeDate
+	case "timestamp", "timestamp with time zone":
+		c.typ, c.converted, c.encode = parquetInt64, convertedTimestampMillis, encodeTimestamp
+	case "varbinary":
+		c.typ, c.encode = parquetByteArray, encodeVarbinary
+	default:
+		// Strings, and complex types such as arrays and maps in their string representations
+		c.typ, c.converted, c.encode = parquetByteArray, convertedUTF8, encodeString
+	}
+	return c
+}
+
+// add adds v to the values, or NULL if null is true.
+func (c *parquetColumn) add(v string, null bool) error {
+	if null {
+		c.defs = append(c.defs, false)
+		return nil
+	}
+	if c.typ == parquetBoolean {
+		b, err := exec.ParseBoolean(v)
+		if err != nil {
+			return err
+		}
+		c.bools = append(c.bools, b)
+	} else if err := c.encode(&c.values, v); err != nil {
+		return err
+	}
+	c.defs = append(c.defs, true)
+	return nil
+}
+
+// size returns the approximate size of the buffered values.
+func (c *parquetColumn) size() int {
+	return len(c.defs)/8 + c.values.Len() + len(c.bools)/8
+}
+
+// page returns the buffered values as the content of a data page, and then resets them.
+func (c *parquetColumn) page() []byte {
+	var page bytes.Buffer
+	levels := encodeLevels(c.defs)
+	binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+	page.Write(levels)
+	if c.typ == parquetBoolean {
+		page.Write(packBools(c.bools))
+	} else {
+		page.Write(c.values.Bytes())
+	}
+
+	c.defs = c.defs[:0]
+	c.values.Reset()
+	c.bools = c.bools[:0]
+	return page.Bytes()
+}
+
+func encodeInt32(buf *bytes.Buffer, v string) error {
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return errors.Wrapf(err, "invalid integer value %q", v)
+	}
+	return binary.Write(buf, binary.LittleEndian, int32(n))
+}
+
+func encodeInt64(buf *bytes.Buffer, v string) error {
+	n, err := exec.ParseBigint(v)
+	if err != nil {
+		return err
+	}
+	return binary.Write(buf, binary.LittleEndian, n)
+}
+
+func encodeFloat(buf *bytes.Buffer, v string) error {
+	f, err := exec.ParseDouble(v)
+	if err != nil {
+		return err
+	}
+	return binary.Write(buf, binary.LittleEndian, math.Float32bits(float32(f)))
+}
+
+func encodeDouble(buf *bytes.Buffer, v string) error {
+	f, err := exec.ParseDouble(v)
+	if err != nil {
+		return err
+	}
+	return binary.Write(buf, binary.LittleEndian, math.Float64bits(f))
+}
+
+// encodeDate encodes a date as the number of days from the Unix epoch.
+func encodeDate(buf *bytes.Buffer, v string) error {
+	t, err := exec.ParseDate(v)
+	if err != nil {
+		return err
+	}
+	days := floorDiv(t.Unix(), 24*60*60)
+	return binary.Write(buf, binary.LittleEndian, int32(days))
+}
+
+// encodeTimestamp encodes a timestamp as the number of milliseconds from the Unix epoch.
+func encodeTimestamp(buf *bytes.Buffer, v string) error {
+	t, err := exec.ParseTimestamp(v)
+	if err != nil {
+		return err
+	}
+	millis := t.Unix()*1000 + int64(t.Nanosecond())/int64(1000000)
+	return binary.Write(buf, binary.LittleEndian, millis)
+}
+
+func encodeString(buf *bytes.Buffer, v string) error {
+	return encodeBytes(buf, []byte(v))
+}
+
+// encodeVarbinary encodes a varbinary value, which Athena returns as hexadecimal bytes separated
+// by spaces such as `68 65 6c 6c 6f`.
+func encodeVarbinary(buf *bytes.Buffer, v string) error {
+	b, err := hex.DecodeString(strings.Replace(v, " ", "", -1))
+	if err != nil {
+		return errors.Wrapf(err, "invalid varbinary value %q", v)
+	}
+	return encodeBytes(buf, b)
+}
+
+func encodeBytes(buf *bytes.Buffer, b []byte) error {
+	binary.Write(buf, binary.LittleEndian, uint32(len(b)))
+	buf.Write(b)
+	return nil
+}
+
+// decimalPrecision returns the precision and the scale of a decimal column. They are taken from
+// the type if the column metadata does not have them.
+func decimalPrecision(col exec.Column) (precision, scale int32) {
+	if col.Precision > 0 {
+		return int32(col.Precision), int32(col.Scale)
+	}
+	if m := decimalTypeRegexp.FindStringSubmatch(strings.ToLower(col.Type)); m != nil {
+		p, _ := strconv.Atoi(m[1])
+		s, _ := strconv.Atoi(m[2])
+		return int32(p), int32(s)
+	}
+	return 38, 0 // The maximum precision of Athena
+}
+
+// decimalEncoder returns the physical type to store decimal values of precision and a function
+// to encode them as unscaled integers.
+func decimalEncoder(precision, scale int32) (int32, func(*bytes.Buffer, string) error) {
+	mul := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil)
+	unscaled := func(v string) (*big.Int, error) {
+		r, err := exec.ParseDecimal(v)
+		if err != nil {
+			return nil, err
+		}
+		r.Mul(r, new(big.Rat).SetInt(mul))
+		if !r.IsInt() {
+			return nil, errors.Errorf("decimal value %q has more digits than scale %d", v, scale)
+		}
+		if new(big.Int).Abs(r.Num()).Cmp(limit) >= 0 {
+			return nil, errors.Errorf("decimal value %q exceeds precision %d", v, precision)
+		}
+		return r.Num(), nil
+	}
+
+	switch {
+	case precision <= 9:
+		return parquetInt32, func(buf *bytes.Buffer, v string) error {
+			n, err := unscaled(v)
+			if err != nil {
+				return err
+			}
+			return binary.Write(buf, binary.LittleEndian, int32(n.Int64()))
+		}
+	case precision <= 18:
+		return parquetInt64, func(buf *bytes.Buffer, v string) error {
+			n, err := unscaled(v)
+			if err != nil {
+				return err
+			}
+			return binary.Write(buf, binary.LittleEndian, n.Int64())
+		}
+	}
+	return parquetByteArray, func(buf *bytes.Buffer, v string) error {
+		n, err := unscaled(v)
+		if err != nil {
+			return err
+		}
+		return encodeBytes(buf, twosComplement(n))
+	}
+}
+
+// twosComplement returns n in big-endian two's complement representation with the minimum bytes.
+func twosComplement(n *big.Int) []byte {
+	if n.Sign() >= 0 {
+		b := n.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return b
+	}
+
+	// -n = ^(n-1) for the bytes of |n|-1
+	abs := new(big.Int).Neg(n)
+	abs.Sub(abs, big.NewInt(1))
+	b := abs.Bytes()
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	if len(b) == 0 || b[0]&0x80 == 0 {
+		b = append([]byte{0xff}, b...)
+	}
+	return b
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// encodeLevels encodes definition levels of bit width 1 with RLE, which makes runs of NULL and
+// non-NULL values.
+func encodeLevels(defs []bool) []byte {
+	var buf bytes.Buffer
+	var b [binary.MaxVarintLen64]byte
+	for i := 0; i < len(defs); {
+		j := i + 1
+		for j < len(defs) && defs[j] == defs[i] {
+			j++
+		}
+		n := binary.PutUvarint(b[:], uint64(j-i)<<1) // The lowest bit 0 means an RLE run
+		buf.Write(b[:n])
+		if defs[i] {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		i = j
+	}
+	return buf.Bytes()
+}
+
+// packBools packs bools into bits in the order of the least significant bit first.
+func packBools(bools []bool) []byte {
+	b := make([]byte, (len(bools)+7)/8)
+	for i, v := range bools {
+		if v {
+			b[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return b
+}
+
+// countingWriter counts the bytes written to w, which are offsets in a Parquet file.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// columnChunk is the metadata of a column in a row group.
+type columnChunk struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// rowGroup is the metadata of a row group.
+type rowGroup struct {
+	numRows   int64
+	totalSize int64
+	chunks    []columnChunk
+}
+
+// parquetWriter writes rows in Parquet format.
+//
+// Every column is optional and written in a single data page per row group with PLAIN encoding,
+// compressed with gzip. Row groups are written every rowGroupBytes so that the rows are not
+// held in memory.
+type parquetWriter struct {
+	w             *countingWriter
+	rowGroupBytes int
+	cols          []*parquetColumn
+	rows          int64 // Rows in the current row group
+	total         int64
+	groups        []rowGroup
+}
+
+func newParquetWriter(w io.Writer, cols []exec.Column) (*parquetWriter, error) {
+	pw := &parquetWriter{
+		w:             &countingWriter{w: w},
+		rowGroupBytes: defaultRowGroupBytes,
+		cols:          make([]*parquetColumn, len(cols)),
+	}
+	for i, col := range cols {
+		pw.cols[i] = newParquetColumn(col)
+	}
+	if _, err := io.WriteString(pw.w, parquetMagic); err != nil {
+		return nil, errors.Wrap(err, "failed to write Parquet header")
+	}
+	return pw, nil
+}
+
+func (pw *parquetWriter) Write(row []string, nulls []bool) error {
+	if len(row) != len(pw.cols) {
+		return errors.Errorf("row has %d values but there are %d columns", len(row), len(pw.cols))
+	}
+	for i, c := range pw.cols {
+		if err := c.add(row[i], nulls[i]); err != nil {
+			return errors.Wrapf(err, "column %s", c.name)
+		}
+	}
+	pw.rows++
+
+	size := 0
+	for _, c := range pw.cols {
+		size += c.size()
+	}
+	if size >= pw.rowGroupBytes {
+		return pw.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group.
+func (pw *parquetWriter) flush() error {
+	if pw.rows == 0 {
+		return nil
+	}
+
+	rg := rowGroup{numRows: pw.rows, chunks: make([]columnChunk, len(pw.cols))}
+	for i, c := range pw.cols {
+		page := c.page()
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		zw.Write(page)
+		if err := zw.Close(); err != nil {
+			return errors.Wrap(err, "failed to compress Parquet page")
+		}
+
+		header := newCompactWriter()
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(compressed.Len()))
+		header.beginStruct(5) // DataPageHeader
+		header.i32(1, int32(pw.rows))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		offset := pw.w.n
+		if _, err := pw.w.Write(header.Bytes()); err != nil {
+			return errors.Wrap(err, "failed to write Parquet page")
+		}
+		if _, err := pw.w.Write(compressed.Bytes()); err != nil {
+			return errors.Wrap(err, "failed to write Parquet page")
+		}
+
+		headerSize := int64(len(header.Bytes()))
+		rg.chunks[i] = columnChunk{
+			offset:           offset,
+			numValues:        pw.rows,
+			uncompressedSize: headerSize + int64(len(page)),
+			compressedSize:   headerSize + int64(compressed.Len()),
+		}
+		rg.totalSize += rg.chunks[i].uncompressedSize
+	}
+
+	pw.groups = append(pw.groups, rg)
+	pw.total += pw.rows
+	pw.rows = 0
+	return nil
+}
+
+// Close writes the rest of the rows and the footer. It does not close the underlying writer.
+func (pw *parquetWriter) Close() error {
+	if err := pw.flush(); err != nil {
+		return err
+	}
+
+	footer := pw.footer()
+	if _, err := pw.w.Write(footer); err != nil {
+		return errors.Wrap(err, "failed to write Parquet footer")
+	}
+	if err := binary.Write(pw.w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return errors.Wrap(err, "failed to write Parquet footer")
+	}
+	if _, err := io.WriteString(pw.w, parquetMagic); err != nil {
+		return errors.Wrap(err, "failed to write Parquet footer")
+	}
+	return nil
+}
+
+// footer returns the encoded FileMetaData of the file.
+func (pw *parquetWriter) footer() []byte {
+	w := newCompactWriter()
+	w.i32(1, 1) // Version
+
+	w.list(2, compactStruct, len(pw.cols)+1) // Schema
+	w.beginElem()
+	w.str(4, "schema")
+	w.i32(5, int32(len(pw.cols)))
+	w.endStruct()
+	for _, c := range pw.cols {
+		w.beginElem()
+		w.i32(1, c.typ)
+		w.i32(3, repetitionOptional)
+		w.str(4, c.name)
+		if c.converted != convertedNone {
+			w.i32(6, c.converted)
+		}
+		if c.converted == convertedDecimal {
+			w.i32(7, c.scale)
+			w.i32(8, c.precision)
+		}
+		w.endStruct()
+	}
+
+	w.i64(3, pw.total)
+
+	w.list(4, compactStruct, len(pw.groups)) // Row groups
+	for _, rg := range pw.groups {
+		w.beginElem()
+		w.list(1, compactStruct, len(rg.chunks))
+		for i, cc := range rg.chunks {
+			c := pw.cols[i]
+			w.beginElem()
+			w.i64(2, cc.offset)
+			w.beginStruct(3) // ColumnMetaData
+			w.i32(1, c.typ)
+			w.list(2, compactI32, 2)
+			w.elemI32(encodingPlain)
+			w.elemI32(encodingRLE)
+			w.list(3, compactBinary, 1)
+			w.elemString(c.name)
+			w.i32(4, codecGzip)
+			w.i64(5, cc.numValues)
+			w.i64(6, cc.uncompressedSize)
+			w.i64(7, cc.compressedSize)
+			w.i64(9, cc.offset)
+			w.endStruct()
+			w.endStruct()
+		}
+		w.i64(2, rg.totalSize)
+		w.i64(3, rg.numRows)
+		w.endStruct()
+	}
+
+	w.str(6, parquetCreatedBy)
+	w.endStruct()
+	return w.Bytes()
+}
@@ -0,0 +1,454 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/skatsuta/athenai/exec"
+	"github.com/stretchr/testify/assert"
+)
+
+// parquetFile is a Parquet file decoded by readParquet.
+type parquetFile struct {
+	meta   compactStructValue // FileMetaData
+	values [][]interface{}    // Values of each column in all the row groups. NULL is nil.
+}
+
+// readParquet decodes a Parquet file written by parquetWriter, which has a single data page per
+// column chunk and PLAIN encoded values.
+func readParquet(b []byte) (*parquetFile, error) {
+	if len(b) < 12 || string(b[:4]) != parquetMagic || string(b[len(b)-4:]) != parquetMagic {
+		return nil, errors.New("missing magic bytes")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	r := &compactReader{b: b[len(b)-8-footerLen : len(b)-8]}
+	meta, err := r.readStruct()
+	if err != nil {
+		return nil, errors.Wrap(err, "footer")
+	}
+
+	schema := meta[2].([]interface{})
+	f := &parquetFile{meta: meta, values: make([][]interface{}, len(schema)-1)}
+	for _, rg := range meta[4].([]interface{}) {
+		for i, cc := range rg.(compactStructValue)[1].([]interface{}) {
+			cm := cc.(compactStructValue)[3].(compactStructValue)
+			values, err := readColumnChunk(b, cm)
+			if err != nil {
+				return nil, errors.Wrapf(err, "column %d", i)
+			}
+			f.values[i] = append(f.values[i], values...)
+		}
+	}
+	return f, nil
+}
+
+func readColumnChunk(b []byte, cm compactStructValue) ([]interface{}, error) {
+	offset := int(cm[9].(int64))
+	r := &compactReader{b: b, pos: offset}
+	header, err := r.readStruct()
+	if err != nil {
+		return nil, errors.Wrap(err, "page header")
+	}
+	if size := int64(r.pos-offset) + header[3].(int64); size != cm[7].(int64) {
+		return nil, errors.Errorf("compressed size is %d but %d in metadata", size, cm[7])
+	}
+
+	compressed := b[r.pos : r.pos+int(header[3].(int64))]
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	page, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(page)) != header[2].(int64) {
+		return nil, errors.Errorf("uncompressed page size is %d but %d in header", len(page), header[2])
+	}
+
+	n := int(header[5].(compactStructValue)[1].(int64))
+	levelsLen := int(binary.LittleEndian.Uint32(page))
+	defs, err := decodeLevels(page[4:4+levelsLen], n)
+	if err != nil {
+		return nil, err
+	}
+
+	data := page[4+levelsLen:]
+	values := make([]interface{}, n)
+	bit := 0
+	for i := range values {
+		if !defs[i] {
+			continue
+		}
+		switch cm[1].(int64) {
+		case parquetBoolean:
+			values[i] = data[bit/8]&(1<<uint(bit%8)) != 0
+			bit++
+		case parquetInt32:
+			values[i] = int32(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case parquetInt64:
+			values[i] = int64(binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case parquetFloat:
+			values[i] = math.Float32frombits(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case parquetDouble:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case parquetByteArray:
+			l := binary.LittleEndian.Uint32(data)
+			values[i] = string(data[4 : 4+l])
+			data = data[4+l:]
+		}
+	}
+	return values, nil
+}
+
+// decodeLevels decodes n definition levels of bit width 1 encoded with the RLE/bit-packing hybrid.
+func decodeLevels(b []byte, n int) ([]bool, error) {
+	var defs []bool
+	for len(b) > 0 {
+		h, l := binary.Uvarint(b)
+		if l <= 0 {
+			return nil, errors.New("invalid run header")
+		}
+		b = b[l:]
+		if h&1 == 0 { // RLE run
+			for i := uint64(0); i < h>>1; i++ {
+				defs = append(defs, b[0] == 1)
+			}
+			b = b[1:]
+		} else { // Bit-packed groups of 8 values
+			for i := uint64(0); i < h>>1*8; i++ {
+				defs = append(defs, b[i/8]&(1<<(i%8)) != 0)
+			}
+			b = b[h>>1:]
+		}
+	}
+	if len(defs) < n {
+		return nil, errors.Errorf("%d definition levels for %d values", len(defs), n)
+	}
+	return defs[:n], nil
+}
+
+func writeParquet(cols []exec.Column, rows [][]string, rowGroupBytes int) ([]byte, error) {
+	var buf bytes.Buffer
+	pw, err := newParquetWriter(&buf, cols)
+	if err != nil {
+		return nil, err
+	}
+	if rowGroupBytes > 0 {
+		pw.rowGroupBytes = rowGroupBytes
+	}
+	for _, row := range rows {
+		nulls := make([]bool, len(row))
+		for i, v := range row {
+			nulls[i] = v == "NULL"
+		}
+		if err := pw.Write(row, nulls); err != nil {
+			return nil, err
+		}
+	}
+	if err := pw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// testParquetColumns are columns of all the types of which testParquetRows are written.
+var testParquetColumns = []exec.Column{
+	{Name: "b", Type: "boolean"},
+	{Name: "ti", Type: "tinyint"},
+	{Name: "si", Type: "smallint"},
+	{Name: "i", Type: "integer"},
+	{Name: "bi", Type: "bigint"},
+	{Name: "r", Type: "real"},
+	{Name: "d", Type: "double"},
+	{Name: "dec1", Type: "decimal", Precision: 5, Scale: 2},
+	{Name: "dec2", Type: "decimal(18,3)"},
+	{Name: "dec3", Type: "decimal(38,0)"},
+	{Name: "dt", Type: "date"},
+	{Name: "ts", Type: "timestamp"},
+	{Name: "tz", Type: "timestamp with time zone"},
+	{Name: "vb", Type: "varbinary"},
+	{Name: "s", Type: "varchar"},
+	{Name: "a", Type: "array(integer)"},
+}
+
+// testParquetRows are rows of testParquetColumns including NULLs and edge cases.
+var testParquetRows = [][]string{
+	{"true", "-1", "300", "70000", "5000000000", "1.5", "-2.25", "123.45", "-1.5", "-129", "1970-01-02", "1970-01-01 00:00:01.234", "1970-01-01 09:00:01.234 +09:00", "68 69", "hello", "[1, 2]"},
+	{"NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL", "NULL"},
+	{"false", "1", "2", "3", "4", "NaN", "Infinity", "0", "0.001", "128", "1969-12-31", "1969-12-31 23:59:59.999", "1970-01-01 00:00:00.000 UTC", "", "", "[]"},
+}
+
+func TestParquetWriter(t *testing.T) {
+	cols, rows := testParquetColumns, testParquetRows
+
+	b, err := writeParquet(cols, rows, 0)
+	assert.NoError(t, err)
+	f, err := readParquet(b)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, int64(1), f.meta[1], "version")
+	assert.Equal(t, int64(3), f.meta[3], "num_rows")
+	assert.Equal(t, parquetCreatedBy, f.meta[6], "created_by")
+	assert.Len(t, f.meta[4], 1, "row_groups")
+
+	schema := f.meta[2].([]interface{})
+	assert.Equal(t, compactStructValue{4: "schema", 5: int64(len(cols))}, schema[0])
+	wantSchema := []compactStructValue{
+		{1: int64(parquetBoolean), 3: int64(1), 4: "b"},
+		{1: int64(parquetInt32), 3: int64(1), 4: "ti", 6: int64(convertedInt8)},
+		{1: int64(parquetInt32), 3: int64(1), 4: "si", 6: int64(convertedInt16)},
+		{1: int64(parquetInt32), 3: int64(1), 4: "i"},
+		{1: int64(parquetInt64), 3: int64(1), 4: "bi"},
+		{1: int64(parquetFloat), 3: int64(1), 4: "r"},
+		{1: int64(parquetDouble), 3: int64(1), 4: "d"},
+		{1: int64(parquetInt32), 3: int64(1), 4: "dec1", 6: int64(convertedDecimal), 7: int64(2), 8: int64(5)},
+		{1: int64(parquetInt64), 3: int64(1), 4: "dec2", 6: int64(convertedDecimal), 7: int64(3), 8: int64(18)},
+		{1: int64(parquetByteArray), 3: int64(1), 4: "dec3", 6: int64(convertedDecimal), 7: int64(0), 8: int64(38)},
+		{1: int64(parquetInt32), 3: int64(1), 4: "dt", 6: int64(convertedDate)},
+		{1: int64(parquetInt64), 3: int64(1), 4: "ts", 6: int64(convertedTimestampMillis)},
+		{1: int64(parquetInt64), 3: int64(1), 4: "tz", 6: int64(convertedTimestampMillis)},
+		{1: int64(parquetByteArray), 3: int64(1), 4: "vb"},
+		{1: int64(parquetByteArray), 3: int64(1), 4: "s", 6: int64(convertedUTF8)},
+		{1: int64(parquetByteArray), 3: int64(1), 4: "a", 6: int64(convertedUTF8)},
+	}
+	for i, want := range wantSchema {
+		assert.Equal(t, want, schema[i+1], "schema of column %s", cols[i].Name)
+	}
+
+	want := [][]interface{}{
+		{true, nil, false},
+		{int32(-1), nil, int32(1)},
+		{int32(300), nil, int32(2)},
+		{int32(70000), nil, int32(3)},
+		{int64(5000000000), nil, int64(4)},
+		{float32(1.5), nil, float32(math.NaN())},
+		{-2.25, nil, math.Inf(1)},
+		{int32(12345), nil, int32(0)},
+		{int64(-1500), nil, int64(1)},
+		{"\xff\x7f", nil, "\x00\x80"},
+		{int32(1), nil, int32(-1)},
+		{int64(1234), nil, int64(-1)},
+		{int64(1234), nil, int64(0)},
+		{"hi", nil, ""},
+		{"hello", nil, ""},
+		{"[1, 2]", nil, "[]"},
+	}
+	for i, w := range want {
+		if i == 5 { // NaN is not equal to itself
+			assert.Equal(t, w[:2], f.values[i][:2], "values of column %s", cols[i].Name)
+			assert.True(t, math.IsNaN(float64(f.values[i][2].(float32))))
+			continue
+		}
+		assert.Equal(t, w, f.values[i], "values of column %s", cols[i].Name)
+	}
+}
+
+// pyarrowScript prints the schema and the values of the Parquet file given as the argument, as
+// read by PyArrow, in JSON. Values are printed in their Python string representations, except
+// that timestamps are printed as integers so as not to depend on the time zone.
+const pyarrowScript = `
+import json, sys
+import pyarrow as pa
+import pyarrow.parquet as pq
+
+table = pq.read_table(sys.argv[1])
+cols = []
+for field in table.schema:
+    typ, col = field.type, table.column(field.name)
+    if pa.types.is_timestamp(typ):
+        name, col = "timestamp[%s]" % typ.unit, col.cast(pa.int64())
+    elif pa.types.is_decimal(typ):
+        name = "decimal(%d, %d)" % (typ.precision, typ.scale)
+    else:
+        name = str(typ)
+    values = [None if v is None else str(v) for v in col.to_pylist()]
+    cols.append({"Name": field.name, "Type": name, "Values": values})
+print(json.dumps({"NumRows": table.num_rows, "Columns": cols}))
+`
+
+// TestParquetWriterInterop checks that files written by parquetWriter are read by PyArrow as
+// expected. It is run only if ATHENAI_TEST_PYARROW is set, since it needs PyArrow installed locally.
+func TestParquetWriterInterop(t *testing.T) {
+	if os.Getenv("ATHENAI_TEST_PYARROW") == "" {
+		t.Skip("ATHENAI_TEST_PYARROW is not set")
+	}
+	if err := osexec.Command("python3", "-c", "import pyarrow.parquet").Run(); err != nil {
+		t.Fatal("PyArrow is not available:", err)
+	}
+
+	dir, err := ioutil.TempDir("", "athenai-parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	type column struct {
+		Name   string
+		Type   string
+		Values []*string
+	}
+	str := func(s string) *string { return &s }
+	want := []column{
+		{"b", "bool", []*string{str("True"), nil, str("False")}},
+		{"ti", "int8", []*string{str("-1"), nil, str("1")}},
+		{"si", "int16", []*string{str("300"), nil, str("2")}},
+		{"i", "int32", []*string{str("70000"), nil, str("3")}},
+		{"bi", "int64", []*string{str("5000000000"), nil, str("4")}},
+		{"r", "float", []*string{str("1.5"), nil, str("nan")}},
+		{"d", "double", []*string{str("-2.25"), nil, str("inf")}},
+		{"dec1", "decimal(5, 2)", []*string{str("123.45"), nil, str("0.00")}},
+		{"dec2", "decimal(18, 3)", []*string{str("-1.500"), nil, str("0.001")}},
+		{"dec3", "decimal(38, 0)", []*string{str("-129"), nil, str("128")}},
+		{"dt", "date32[day]", []*string{str("1970-01-02"), nil, str("1969-12-31")}},
+		{"ts", "timestamp[ms]", []*string{str("1234"), nil, str("-1")}},
+		{"tz", "timestamp[ms]", []*string{str("1234"), nil, str("0")}},
+		{"vb", "binary", []*string{str("b'hi'"), nil, str("b''")}},
+		{"s", "string", []*string{str("hello"), nil, str("")}},
+		{"a", "string", []*string{str("[1, 2]"), nil, str("[]")}},
+	}
+
+	for _, rowGroupBytes := range []int{0, 1} {
+		b, err := writeParquet(testParquetColumns, testParquetRows, rowGroupBytes)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		path := filepath.Join(dir, "test.parquet")
+		if err := ioutil.WriteFile(path, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := osexec.Command("python3", "-c", pyarrowScript, path).Output()
+		if !assert.NoError(t, err, "Row group size: %d", rowGroupBytes) {
+			if ee, ok := err.(*osexec.ExitError); ok {
+				t.Logf("%s", ee.Stderr)
+			}
+			continue
+		}
+		var got struct {
+			NumRows int
+			Columns []column
+		}
+		if !assert.NoError(t, json.Unmarshal(out, &got)) {
+			continue
+		}
+		assert.Equal(t, len(testParquetRows), got.NumRows, "Row group size: %d", rowGroupBytes)
+		assert.Equal(t, want, got.Columns, "Row group size: %d", rowGroupBytes)
+	}
+}
+
+func TestParquetWriterRowGroups(t *testing.T) {
+	cols := []exec.Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "varchar"}}
+	rows := [][]string{{"1", "alice"}, {"2", "NULL"}, {"3", "carol"}}
+
+	// Every row makes a row group
+	b, err := writeParquet(cols, rows, 1)
+	assert.NoError(t, err)
+	f, err := readParquet(b)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, int64(3), f.meta[3], "num_rows")
+	groups := f.meta[4].([]interface{})
+	assert.Len(t, groups, 3)
+	for _, rg := range groups {
+		assert.Equal(t, int64(1), rg.(compactStructValue)[3], "num_rows of row group")
+	}
+	assert.Equal(t, []interface{}{int32(1), int32(2), int32(3)}, f.values[0])
+	assert.Equal(t, []interface{}{"alice", nil, "carol"}, f.values[1])
+}
+
+func TestParquetWriterNoRows(t *testing.T) {
+	b, err := writeParquet([]exec.Column{{Name: "id", Type: "integer"}}, nil, 0)
+	assert.NoError(t, err)
+	f, err := readParquet(b)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(0), f.meta[3], "num_rows")
+	assert.Empty(t, f.meta[4], "row_groups")
+	assert.Len(t, f.meta[2], 2, "schema")
+}
+
+func TestParquetWriterError(t *testing.T) {
+	tests := []struct {
+		typ     string
+		value   string
+		wantErr string
+	}{
+		{"integer", "3000000000", `column c: invalid integer value "3000000000"`},
+		{"boolean", "yes", "column c:"},
+		{"date", "2017/01/01", "column c:"},
+		{"decimal(5,2)", "1.234", `column c: decimal value "1.234" has more digits than scale 2`},
+		{"decimal(3,1)", "12345678", `column c: decimal value "12345678" exceeds precision 3`},
+		{"decimal(18,0)", "99999999999999999999", `column c: decimal value "99999999999999999999" exceeds precision 18`},
+		{"varbinary", "zz", `column c: invalid varbinary value "zz"`},
+	}
+
+	for _, tt := range tests {
+		_, err := writeParquet([]exec.Column{{Name: "c", Type: tt.typ}}, [][]string{{tt.value}}, 0)
+		if assert.Error(t, err, "type: %s, value: %s", tt.typ, tt.value) {
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+	}
+
+	_, err := writeParquet([]exec.Column{{Name: "c", Type: "integer"}}, [][]string{{"1", "2"}}, 0)
+	assert.EqualError(t, err, "row has 2 values but there are 1 columns")
+}
+
+func TestTwosComplement(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x00, 0x80}},
+		{-1, []byte{0xff}},
+		{-128, []byte{0x80}},
+		{-129, []byte{0xff, 0x7f}},
+		{-256, []byte{0xff, 0x00}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, twosComplement(big.NewInt(tt.n)), "n: %d", tt.n)
+	}
+}
+
+func TestEncodeLevels(t *testing.T) {
+	tests := []struct {
+		defs []bool
+		want []byte
+	}{
+		{nil, nil},
+		{[]bool{true}, []byte{0x02, 0x01}},
+		{[]bool{true, true, false}, []byte{0x04, 0x01, 0x02, 0x00}},
+		{make([]bool, 64), []byte{0x80, 0x01, 0x00}},
+	}
+
+	for _, tt := range tests {
+		got := encodeLevels(tt.defs)
+		assert.Equal(t, tt.want, got, "defs: %v", tt.defs)
+		defs, err := decodeLevels(got, len(tt.defs))
+		assert.NoError(t, err)
+		assert.Equal(t, len(tt.defs), len(defs))
+	}
+}
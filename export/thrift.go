@@ -0,0 +1,108 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Types of fields in Thrift compact protocol.
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter encodes a Thrift struct in compact protocol, which Parquet uses for its metadata.
+// See https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+type compactWriter struct {
+	buf  bytes.Buffer
+	last []int16 // IDs of the last fields written in the structs being written
+}
+
+// newCompactWriter creates a new compactWriter which writes the fields of a top-level struct.
+func newCompactWriter() *compactWriter {
+	return &compactWriter{last: []int16{0}}
+}
+
+// Bytes returns the encoded struct. The top-level struct must have been ended with endStruct.
+func (w *compactWriter) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+func (w *compactWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf.Write(b[:n])
+}
+
+func (w *compactWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+// field writes the header of the field whose ID is id.
+func (w *compactWriter) field(id int16, typ byte) {
+	top := len(w.last) - 1
+	if delta := id - w.last[top]; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	w.last[top] = id
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.field(id, compactI32)
+	w.zigzag(int64(v))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.field(id, compactI64)
+	w.zigzag(v)
+}
+
+func (w *compactWriter) str(id int16, s string) {
+	w.field(id, compactBinary)
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// list writes the header of a list field of n elements of typ. The elements must follow it,
+// which are written with elemI32, elemString or beginElem for structs.
+func (w *compactWriter) list(id int16, typ byte, n int) {
+	w.field(id, compactList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | typ)
+	} else {
+		w.buf.WriteByte(0xf0 | typ)
+		w.varint(uint64(n))
+	}
+}
+
+func (w *compactWriter) elemI32(v int32) {
+	w.zigzag(int64(v))
+}
+
+func (w *compactWriter) elemString(s string) {
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// beginStruct begins a struct field. Its fields must follow it, and then endStruct.
+func (w *compactWriter) beginStruct(id int16) {
+	w.field(id, compactStruct)
+	w.last = append(w.last, 0)
+}
+
+// beginElem begins a struct as an element of a list. Its fields must follow it, and then endStruct.
+func (w *compactWriter) beginElem() {
+	w.last = append(w.last, 0)
+}
+
+// endStruct ends the struct being written.
+func (w *compactWriter) endStruct() {
+	w.buf.WriteByte(0) // Stop field
+	w.last = w.last[:len(w.last)-1]
+}
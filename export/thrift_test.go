@@ -0,0 +1,173 @@
+package export
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// compactStructValue is a struct decoded from Thrift compact protocol, keyed by field ID.
+type compactStructValue map[int16]interface{}
+
+// compactReader decodes Thrift compact protocol independently of compactWriter to check its
+// output. Integers are decoded as int64, binaries as string, lists as []interface{} and structs
+// as compactStructValue.
+type compactReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *compactReader) byte() (byte, error) {
+	if r.pos >= len(r.b) {
+		return 0, errors.New("unexpected end of data")
+	}
+	r.pos++
+	return r.b[r.pos-1], nil
+}
+
+func (r *compactReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	if n <= 0 {
+		return 0, errors.New("invalid varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *compactReader) zigzag() (int64, error) {
+	v, err := r.varint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *compactReader) readStruct() (compactStructValue, error) {
+	s := compactStructValue{}
+	var last int16
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 { // Stop field
+			return s, nil
+		}
+
+		typ, delta := b&0x0f, int16(b>>4)
+		id := last + delta
+		if delta == 0 {
+			v, err := r.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		last = id
+
+		switch typ {
+		case 1, 2: // Booleans are encoded in the type
+			s[id] = typ == 1
+			continue
+		}
+		if s[id], err = r.value(typ); err != nil {
+			return nil, errors.Wrapf(err, "field %d", id)
+		}
+	}
+}
+
+func (r *compactReader) value(typ byte) (interface{}, error) {
+	switch typ {
+	case 3:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case 4, compactI32, compactI64:
+		return r.zigzag()
+	case 7:
+		if r.pos+8 > len(r.b) {
+			return nil, errors.New("unexpected end of data")
+		}
+		r.pos += 8
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.b[r.pos-8:])), nil
+	case compactBinary:
+		n, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		if r.pos+int(n) > len(r.b) {
+			return nil, errors.New("unexpected end of data")
+		}
+		r.pos += int(n)
+		return string(r.b[r.pos-int(n) : r.pos]), nil
+	case compactList:
+		h, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		n, elem := uint64(h>>4), h&0x0f
+		if n == 15 {
+			if n, err = r.varint(); err != nil {
+				return nil, err
+			}
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = r.value(elem); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case compactStruct:
+		return r.readStruct()
+	}
+	return nil, errors.Errorf("unknown type %d", typ)
+}
+
+func TestCompactWriter(t *testing.T) {
+	w := newCompactWriter()
+	w.i32(1, 1)
+	w.i32(3, -2)
+	w.str(4, "ab")
+	w.i64(20, 1<<40) // Long form of the field header
+	w.list(21, compactI32, 2)
+	w.elemI32(0)
+	w.elemI32(3)
+	w.list(22, compactBinary, 16) // Long form of the list header
+	for i := 0; i < 16; i++ {
+		w.elemString("x")
+	}
+	w.beginStruct(23)
+	w.i32(1, 7)
+	w.endStruct()
+	w.list(24, compactStruct, 1)
+	w.beginElem()
+	w.str(2, "y")
+	w.endStruct()
+	w.i32(25, 8) // Delta from the field before the nested structs
+	w.endStruct()
+
+	b := w.Bytes()
+	assert.Equal(t, []byte{0x15, 0x02, 0x25, 0x03, 0x18, 0x02, 'a', 'b', 0x06, 0x28}, b[:10])
+
+	r := &compactReader{b: b}
+	got, err := r.readStruct()
+	assert.NoError(t, err)
+	assert.Equal(t, len(b), r.pos, "All the bytes are read")
+
+	strs := make([]interface{}, 16)
+	for i := range strs {
+		strs[i] = "x"
+	}
+	want := compactStructValue{
+		1:  int64(1),
+		3:  int64(-2),
+		4:  "ab",
+		20: int64(1 << 40),
+		21: []interface{}{int64(0), int64(3)},
+		22: strs,
+		23: compactStructValue{1: int64(7)},
+		24: []interface{}{compactStructValue{2: "y"}},
+		25: int64(8),
+	}
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,195 @@
+package filter
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the supported finders.
+const (
+	FinderPeco = "peco"
+	FinderFzf  = "fzf"
+	FinderSkim = "sk"
+)
+
+// Exit statuses of fzf and sk.
+const (
+	exitNoMatch     = 1
+	exitInterrupted = 130
+)
+
+// Previewer is implemented by filters that can show a preview of the item under the cursor.
+type Previewer interface {
+	// SetPreview sets fn, which returns the text to preview for an item.
+	SetPreview(fn func(item string) string)
+}
+
+// NewFinder creates a new Filter to filter input with finder, which is either the built-in peco
+// or an external command reading items from stdin and writing selected ones to stdout line by line.
+// fzf and sk are run with options to select multiple items with Ctrl-Space and to show a preview
+// pane, and arguments in finder are passed to them as well, e.g. "fzf --height 40%".
+func NewFinder(finder string) (Filter, error) {
+	fields := strings.Fields(finder)
+	if len(fields) == 0 || fields[0] == FinderPeco {
+		return New(), nil
+	}
+
+	path, err := exec.LookPath(fields[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "finder %q is not found", fields[0])
+	}
+	log.Printf("Using %s as a finder\n", path)
+	return &commandFilter{path: path, args: fields[1:]}, nil
+}
+
+type commandFilter struct {
+	path     string
+	args     []string
+	items    []string
+	preview  func(item string) string
+	selected []string
+}
+
+// SetInput sets input to f.
+func (f *commandFilter) SetInput(input string) {
+	f.items = nil
+	if input != "" {
+		f.items = strings.Split(input, "\n")
+	}
+}
+
+// SetPreview sets fn, which returns the text to preview for an item.
+func (f *commandFilter) SetPreview(fn func(item string) string) {
+	f.preview = fn
+}
+
+// fuzzy returns true if f runs fzf or sk, which support multi-select, field indexes and previews.
+func (f *commandFilter) fuzzy() bool {
+	name := strings.TrimSuffix(filepath.Base(f.path), filepath.Ext(f.path))
+	return name == FinderFzf || name == FinderSkim
+}
+
+// Run performs filtering.
+func (f *commandFilter) Run(ctx context.Context) error {
+	f.selected = nil
+
+	var (
+		args  = f.args
+		input bytes.Buffer
+	)
+	if f.fuzzy() {
+		// Prefix each item with its index, which is hidden from the finder, in order to find the item
+		// and its preview from the output regardless of how the finder transforms lines
+		for i, item := range f.items {
+			input.WriteString(strconv.Itoa(i) + "\t" + item + "\n")
+		}
+		args = append([]string{"--multi", "--bind", "ctrl-space:toggle", "--delimiter", "\t", "--with-nth", "2.."}, args...)
+
+		if f.preview != nil {
+			dir, err := f.writePreviews()
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			// The finder quotes the field replacing {1} by itself
+			preview := "cat " + shellQuote(dir+string(filepath.Separator)) + "{1}"
+			args = append(args, "--preview", preview, "--preview-window", "down:40%:wrap")
+		}
+	} else {
+		for _, item := range f.items {
+			input.WriteString(item + "\n")
+		}
+	}
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, f.path, args...)
+	cmd.Stdin = &input
+	cmd.Stdout = &output
+	cmd.Stderr = os.Stderr // Finders draw their UI on the terminal directly or via stderr
+	log.Printf("Running finder: %s %q\n", f.path, args)
+
+	if err := cmd.Run(); err != nil {
+		switch exitStatus(err) {
+		case exitNoMatch:
+			log.Println("No item is selected in finder")
+			return nil
+		case exitInterrupted:
+			return errors.New("filtering canceled")
+		}
+		return errors.Wrap(err, "error filtering entries")
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(output.String(), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if !f.fuzzy() {
+			f.selected = append(f.selected, line)
+			continue
+		}
+		idx := strings.SplitN(line, "\t", 2)[0]
+		i, err := strconv.Atoi(idx)
+		if err != nil || i < 0 || i >= len(f.items) {
+			log.Printf("Ignoring unknown line from finder: %q\n", line)
+			continue
+		}
+		f.selected = append(f.selected, f.items[i])
+	}
+	return nil
+}
+
+// writePreviews writes the preview of each item to a file named after its index in a new
+// temporary directory, and returns the directory.
+func (f *commandFilter) writePreviews() (string, error) {
+	dir, err := ioutil.TempDir("", "athenai-preview")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create a directory for previews")
+	}
+	for i, item := range f.items {
+		path := filepath.Join(dir, strconv.Itoa(i))
+		if err := ioutil.WriteFile(path, []byte(f.preview(item)), 0600); err != nil {
+			os.RemoveAll(dir)
+			return "", errors.Wrap(err, "failed to write a preview")
+		}
+	}
+	return dir, nil
+}
+
+// shellQuote quotes s with single quotes so that a shell reads it as a single word as it is.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// exitStatus returns the exit status of a command if err is caused by its exit, otherwise -1.
+func exitStatus(err error) int {
+	if ee, ok := err.(*exec.ExitError); ok {
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok {
+			return ws.ExitStatus()
+		}
+	}
+	return -1
+}
+
+// Len returns the length of selected items filtered by f.
+func (f *commandFilter) Len() int {
+	return len(f.selected)
+}
+
+// Each iterates over selected items and call fn with them.
+func (f *commandFilter) Each(fn func(item string) bool) {
+	for _, item := range f.selected {
+		if !fn(item) {
+			return
+		}
+	}
+}
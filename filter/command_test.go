@@ -0,0 +1,190 @@
+package filter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// installFakeFinder installs a shell script named name running script into a new directory,
+// and returns the path to the script.
+func installFakeFinder(t *testing.T, name, script string) string {
+	dir, err := ioutil.TempDir("", "athenai-finder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewFinder(t *testing.T) {
+	for _, finder := range []string{"", "peco"} {
+		f, err := NewFinder(finder)
+		assert.NoError(t, err)
+		assert.IsType(t, &pecoFilter{}, f, "Finder: %q", finder)
+	}
+
+	f, err := NewFinder("cat --unused-arg")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"--unused-arg"}, f.(*commandFilter).args)
+	}
+
+	_, err = NewFinder("athenai-no-such-finder")
+	assert.Error(t, err)
+}
+
+func TestCommandFilter(t *testing.T) {
+	items := []string{
+		"2017-07-01\tSHOW TABLES\tSUCCEEDED",
+		"2017-07-01\tSELECT * FROM elb_logs\tSUCCEEDED",
+		"2017-07-01\tSHOW DATABASES\tSUCCEEDED",
+	}
+	input := strings.Join(items, "\n")
+
+	tests := []struct {
+		name    string
+		script  string
+		preview bool
+		want    []string
+		wantErr string
+	}{
+		{
+			// A plain command just reads and writes items line by line
+			name:   "plain",
+			script: "grep SHOW",
+			want:   []string{items[0], items[2]},
+		},
+		{
+			// fzf and sk receive items prefixed with their indexes, and preview files of them
+			name:    "fzf",
+			script:  `while read -r line; do case "$line" in *SELECT*) printf '%s\n' "$line";; esac; done; echo "$@" | grep -q -- "--multi.*--preview cat .*{1}" || exit 2`,
+			preview: true,
+			want:    []string{items[1]},
+		},
+		{
+			name:   "sk",
+			script: "echo 2; echo 9; echo bogus",
+			want:   []string{items[2]},
+		},
+		{
+			name:   "sk",
+			script: "exit 1",
+		},
+		{
+			name:    "fzf",
+			script:  "exit 130",
+			wantErr: "canceled",
+		},
+		{
+			name:    "fzf",
+			script:  "exit 2",
+			wantErr: "error filtering entries",
+		},
+	}
+
+	for _, tt := range tests {
+		path := installFakeFinder(t, tt.name, tt.script)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		f, err := NewFinder(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.SetInput(input)
+		if tt.preview {
+			f.(Previewer).SetPreview(func(item string) string { return item })
+		}
+		err = f.Run(context.Background())
+
+		if tt.wantErr != "" {
+			if assert.Error(t, err, "Script: %s", tt.script) {
+				assert.Contains(t, err.Error(), tt.wantErr, "Script: %s", tt.script)
+			}
+			continue
+		}
+		assert.NoError(t, err, "Script: %s", tt.script)
+		var got []string
+		f.Each(func(item string) bool {
+			got = append(got, item)
+			return true
+		})
+		assert.Equal(t, tt.want, got, "Script: %s", tt.script)
+		assert.Equal(t, len(tt.want), f.Len(), "Script: %s", tt.script)
+	}
+}
+
+func TestCommandFilterPreviewQuoted(t *testing.T) {
+	// The finder runs the preview command with {1} replaced with the quoted index of the item,
+	// and selects the item if its preview is shown
+	script := `while [ $# -gt 0 ]; do [ "$1" = --preview ] && preview=$2; shift; done
+cmd=$(printf '%s' "$preview" | sed "s/{1}/'1'/")
+sh -c "$cmd" | grep -q SELECT || exit 2
+echo 1`
+	path := installFakeFinder(t, "fzf", script)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	// Previews are written in a directory whose path has a space and a quote
+	tmp, err := ioutil.TempDir("", "athenai test's")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	defer os.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	os.Setenv("TMPDIR", tmp)
+
+	f, err := NewFinder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetInput("SHOW TABLES\nSELECT * FROM elb_logs")
+	f.(Previewer).SetPreview(func(item string) string { return item })
+
+	if assert.NoError(t, f.Run(context.Background())) {
+		var got []string
+		f.Each(func(item string) bool {
+			got = append(got, item)
+			return true
+		})
+		assert.Equal(t, []string{"SELECT * FROM elb_logs"}, got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"/tmp/athenai-preview1/", "'/tmp/athenai-preview1/'"},
+		{"/tmp/my dir/", "'/tmp/my dir/'"},
+		{"/tmp/it's/", `'/tmp/it'\''s/'`},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, shellQuote(tt.s), "String: %q", tt.s)
+	}
+}
+
+func TestCommandFilterPreviews(t *testing.T) {
+	f := &commandFilter{items: []string{"a", "b"}}
+	f.SetPreview(strings.ToUpper)
+
+	dir, err := f.writePreviews()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	for i, want := range []string{"A", "B"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, string('0'+rune(i))))
+		assert.NoError(t, err)
+		assert.Equal(t, want, string(b))
+	}
+}
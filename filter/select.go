@@ -0,0 +1,140 @@
+package filter
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is an entry parsed from an input line, which is matched against Criteria.
+type Entry struct {
+	ID             string
+	Query          string
+	State          string
+	SubmissionTime time.Time
+}
+
+// ParseFunc parses an input line into an Entry.
+type ParseFunc func(line string) (*Entry, error)
+
+// Criteria are conditions to select entries without user interaction.
+// Zero values of the fields mean no condition, and entries matching all the conditions are selected.
+type Criteria struct {
+	IDs    []string       // IDs to select
+	Match  *regexp.Regexp // Regular expression matched against queries
+	Since  time.Time      // Lower bound (inclusive) of submission times
+	Until  time.Time      // Upper bound (exclusive) of submission times
+	Latest int            // Maximum number of the newest entries to select
+}
+
+// IsZero returns true if c has no condition, otherwise false.
+func (c Criteria) IsZero() bool {
+	return len(c.IDs) == 0 && c.Match == nil && c.Since.IsZero() && c.Until.IsZero() && c.Latest == 0
+}
+
+// Matches returns true if e satisfies all the conditions of c except for Latest.
+func (c Criteria) Matches(e *Entry) bool {
+	if len(c.IDs) > 0 && !contains(c.IDs, e.ID) {
+		return false
+	}
+	if c.Match != nil && !c.Match.MatchString(e.Query) {
+		return false
+	}
+	if !c.Since.IsZero() && e.SubmissionTime.Before(c.Since) {
+		return false
+	}
+	if !c.Until.IsZero() && !e.SubmissionTime.Before(c.Until) {
+		return false
+	}
+	return true
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+type selectFilter struct {
+	criteria Criteria
+	parse    ParseFunc
+	lines    []string
+	selected []string
+}
+
+// NewSelector creates a new Filter which selects entries matching c non-interactively.
+// Each input line is parsed with parse, and lines failing to be parsed are skipped.
+func NewSelector(c Criteria, parse ParseFunc) Filter {
+	return &selectFilter{criteria: c, parse: parse}
+}
+
+// SetInput sets input to f.
+func (f *selectFilter) SetInput(input string) {
+	f.lines = nil
+	if input != "" {
+		f.lines = strings.Split(input, "\n")
+	}
+}
+
+// Run performs filtering.
+func (f *selectFilter) Run(ctx context.Context) error {
+	type match struct {
+		idx   int
+		line  string
+		entry *Entry
+	}
+
+	var matched []match
+	for i, line := range f.lines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		e, err := f.parse(line)
+		if err != nil {
+			log.Printf("Skipping line %d: %s\n", i+1, err)
+			continue
+		}
+		if f.criteria.Matches(e) {
+			matched = append(matched, match{idx: i, line: line, entry: e})
+		}
+	}
+
+	if n := f.criteria.Latest; n > 0 && n < len(matched) {
+		// Keep the newest n entries, and then restore the input order
+		sort.SliceStable(matched, func(i, j int) bool {
+			return matched[i].entry.SubmissionTime.After(matched[j].entry.SubmissionTime)
+		})
+		matched = matched[:n]
+		sort.Slice(matched, func(i, j int) bool {
+			return matched[i].idx < matched[j].idx
+		})
+	}
+
+	f.selected = make([]string, len(matched))
+	for i, m := range matched {
+		f.selected[i] = m.line
+	}
+	log.Printf("%d of %d entries match the criteria\n", len(f.selected), len(f.lines))
+	return nil
+}
+
+// Len returns the length of selected items filtered by f.
+func (f *selectFilter) Len() int {
+	return len(f.selected)
+}
+
+// Each iterates over selected items and call fn with them.
+func (f *selectFilter) Each(fn func(item string) bool) {
+	for _, item := range f.selected {
+		if !fn(item) {
+			return
+		}
+	}
+}
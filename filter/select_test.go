@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// parseTestLine parses a line in the format of "<ID> <hour> <state> <query>".
+func parseTestLine(line string) (*Entry, error) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) != 4 {
+		return nil, errors.Errorf("invalid line %q", line)
+	}
+	t, err := time.Parse("15", fields[1])
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{ID: fields[0], SubmissionTime: t, State: fields[2], Query: fields[3]}, nil
+}
+
+func TestSelector(t *testing.T) {
+	input := strings.Join([]string{
+		"id4 04 SUCCEEDED SELECT * FROM elb_logs",
+		"id3 03 FAILED SELECT * FROM cloudfront_logs",
+		"broken line",
+		"id2 02 SUCCEEDED SHOW TABLES",
+		"id1 01 SUCCEEDED SELECT count(*) FROM cloudfront_logs",
+	}, "\n")
+	hour := func(h int) time.Time { return time.Date(0, 1, 1, h, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		criteria Criteria
+		want     []string
+	}{
+		{
+			criteria: Criteria{IDs: []string{"id1", "id3", "id9"}},
+			want:     []string{"id3", "id1"},
+		},
+		{
+			criteria: Criteria{Match: regexp.MustCompile(`cloudfront_logs`)},
+			want:     []string{"id3", "id1"},
+		},
+		{
+			criteria: Criteria{Since: hour(2), Until: hour(4)},
+			want:     []string{"id3", "id2"},
+		},
+		{
+			criteria: Criteria{Latest: 2},
+			want:     []string{"id4", "id3"},
+		},
+		{
+			criteria: Criteria{Match: regexp.MustCompile(`^SELECT`), Until: hour(4)},
+			want:     []string{"id3", "id1"},
+		},
+		{
+			criteria: Criteria{IDs: []string{"ID1"}},
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		f := NewSelector(tt.criteria, parseTestLine)
+		f.SetInput(input)
+		err := f.Run(context.Background())
+
+		assert.NoError(t, err)
+		var got []string
+		f.Each(func(item string) bool {
+			got = append(got, strings.Fields(item)[0])
+			return true
+		})
+		assert.Equal(t, tt.want, got, "Criteria: %#v", tt.criteria)
+		assert.Equal(t, len(tt.want), f.Len(), "Criteria: %#v", tt.criteria)
+	}
+}
+
+func TestSelectorCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	f := NewSelector(Criteria{Latest: 1}, parseTestLine)
+	f.SetInput("id1 01 SUCCEEDED SHOW TABLES")
+	assert.Error(t, f.Run(ctx))
+}
+
+func TestCriteriaIsZero(t *testing.T) {
+	assert.True(t, Criteria{}.IsZero())
+	assert.False(t, Criteria{Latest: 1}.IsZero())
+	assert.False(t, Criteria{Since: time.Now()}.IsZero())
+}
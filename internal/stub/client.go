@@ -2,6 +2,7 @@ package stub
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -36,6 +37,11 @@ type Result struct {
 	SubmitTime   time.Time
 	ExecTime     int64
 	ScannedBytes int64
+	RunningTime  time.Duration // Keeps RUNNING state at least for the duration
+	Reason       string        // StateChangeReason of FAILED state
+	Running      bool          // Listed in RUNNING state by BatchGetQueryExecution
+	// OutputLocation is the location of the results in S3 (default: s3://samplebucket/)
+	OutputLocation string
 	athena.ResultSet
 	ErrMsg string
 }
@@ -169,6 +175,7 @@ type GetQueryExecutionStub struct {
 	results    map[string]*Result   // map[id]*Result
 	stateFlows map[string]stateFlow // map[id]stateFlow
 	stateCnts  map[string]int       // map[id]stateCounter(int)
+	startTimes map[string]time.Time // map[id]time of the first call
 }
 
 // NewGetQueryExecutionStub creates a new GetQueryExecutionStub which returns stub responses
@@ -187,6 +194,7 @@ func NewGetQueryExecutionStub(rs ...*Result) *GetQueryExecutionStub {
 		results:    results,
 		stateFlows: stateFlows,
 		stateCnts:  stateCnts,
+		startTimes: make(map[string]time.Time, l),
 	}
 }
 
@@ -207,6 +215,11 @@ func (s *GetQueryExecutionStub) GetQueryExecution(input *athena.GetQueryExecutio
 	}
 	flow := s.stateFlows[id]
 	cnt := s.stateCnts[id]
+	start, ok := s.startTimes[id]
+	if !ok {
+		start = time.Now()
+		s.startTimes[id] = start
+	}
 	s.mu.Unlock()
 
 	l := len(flow)
@@ -215,17 +228,24 @@ func (s *GetQueryExecutionStub) GetQueryExecution(input *athena.GetQueryExecutio
 		state = flow[cnt]
 	}
 
-	s.mu.Lock()
-	s.stateCnts[id]++
-	s.mu.Unlock()
+	running := state == athena.QueryExecutionStateRunning && r.FinalState != Cancelled
+	if !running || time.Since(start) >= r.RunningTime {
+		s.mu.Lock()
+		s.stateCnts[id]++
+		s.mu.Unlock()
+	}
 
+	status := &athena.QueryExecutionStatus{State: &state}
+	if state == athena.QueryExecutionStateFailed && r.Reason != "" {
+		status.StateChangeReason = &r.Reason
+	}
 	resp := &athena.GetQueryExecutionOutput{
 		QueryExecution: &athena.QueryExecution{
 			QueryExecutionId:    &r.ID,
 			Query:               &r.Query,
-			ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+			ResultConfiguration: testhelper.CreateResultConfig(r.outputLocation()),
 			Statistics:          testhelper.CreateStats(r.ExecTime, r.ScannedBytes),
-			Status:              &athena.QueryExecutionStatus{State: &state},
+			Status:              status,
 		},
 	}
 	return resp, nil
@@ -257,27 +277,46 @@ func NewBatchGetQueryExecutionStub(rs ...*Result) *BatchGetQueryExecutionStub {
 // a list of up to 50 query executions, which you provide as an array of query execution ID strings.
 func (s *BatchGetQueryExecutionStub) BatchGetQueryExecution(input *athena.BatchGetQueryExecutionInput) (*athena.BatchGetQueryExecutionOutput, error) {
 	ids := input.QueryExecutionIds
-	qxs := make([]*athena.QueryExecution, len(ids))
-	for i, id := range ids {
-		r := s.results[aws.StringValue(id)]
+	qxs := make([]*athena.QueryExecution, 0, len(ids))
+	var unprocessed []*athena.UnprocessedQueryExecutionId
+	for _, id := range ids {
+		r, ok := s.results[aws.StringValue(id)]
+		if !ok {
+			unprocessed = append(unprocessed, &athena.UnprocessedQueryExecutionId{
+				QueryExecutionId: id,
+				ErrorCode:        aws.String(athena.ErrCodeInvalidRequestException),
+				ErrorMessage:     aws.String(fmt.Sprintf("QueryExecution %s was not found", aws.StringValue(id))),
+			})
+			continue
+		}
 		if r.ErrMsg != "" {
 			return nil, errors.New(r.ErrMsg)
 		}
 		stateFlow := finalStateFlowMap[r.FinalState]
 		l := len(stateFlow)
 		state := stateFlow[l-1]
-		qxs[i] = &athena.QueryExecution{
+		if r.Running {
+			state = athena.QueryExecutionStateRunning
+		}
+		status := &athena.QueryExecutionStatus{
+			SubmissionDateTime: &r.SubmitTime,
+			State:              &state,
+		}
+		if !r.Running {
+			status.CompletionDateTime = aws.Time(r.SubmitTime.Add(time.Duration(r.ExecTime) * time.Millisecond))
+		}
+		if state == athena.QueryExecutionStateFailed && r.Reason != "" {
+			status.StateChangeReason = &r.Reason
+		}
+		qxs = append(qxs, &athena.QueryExecution{
 			QueryExecutionId:    &r.ID,
 			Query:               &r.Query,
-			ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+			ResultConfiguration: testhelper.CreateResultConfig(r.outputLocation()),
 			Statistics:          testhelper.CreateStats(r.ExecTime, r.ScannedBytes),
-			Status: &athena.QueryExecutionStatus{
-				SubmissionDateTime: &r.SubmitTime,
-				State:              &state,
-			},
-		}
+			Status:              status,
+		})
 	}
-	resp := &athena.BatchGetQueryExecutionOutput{QueryExecutions: qxs}
+	resp := &athena.BatchGetQueryExecutionOutput{QueryExecutions: qxs, UnprocessedQueryExecutionIds: unprocessed}
 	return resp, nil
 }
 
@@ -290,7 +329,10 @@ func (s *BatchGetQueryExecutionStub) BatchGetQueryExecutionWithContext(ctx aws.C
 // ListQueryExecutionsStub simulates ListQueryExecutions API.
 type ListQueryExecutionsStub struct {
 	athenaiface.AthenaAPI
-	rs []*Result
+	// PageSize is the maximum number of query execution IDs in a page. Zero means all of them
+	// are returned in a single page.
+	PageSize int
+	rs       []*Result
 }
 
 // NewListQueryExecutionsStub creates a new ListQueryExecutionsStub which returns stub responses
@@ -299,16 +341,39 @@ func NewListQueryExecutionsStub(rs ...*Result) *ListQueryExecutionsStub {
 	return &ListQueryExecutionsStub{rs: rs}
 }
 
-// ListQueryExecutions provides a list of all available query execution IDs.
+// ListQueryExecutions provides a list of all available query execution IDs, from the newest
+// to the oldest like the real API.
 func (s *ListQueryExecutionsStub) ListQueryExecutions(input *athena.ListQueryExecutionsInput) (*athena.ListQueryExecutionsOutput, error) {
-	ids := make([]*string, len(s.rs))
+	rs := make([]*Result, len(s.rs))
 	for i, r := range s.rs {
 		if r.ErrMsg != "" {
 			return nil, errors.New(r.ErrMsg)
 		}
-		ids[i] = &r.ID
+		rs[i] = r
 	}
-	return &athena.ListQueryExecutionsOutput{QueryExecutionIds: ids}, nil
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].SubmitTime.After(rs[j].SubmitTime)
+	})
+
+	start := 0
+	if token := aws.StringValue(input.NextToken); token != "" {
+		if _, err := fmt.Sscanf(token, "NextToken%d", &start); err != nil {
+			return nil, errors.Errorf("invalid NextToken %q", token)
+		}
+	}
+	end := len(rs)
+	if s.PageSize > 0 && start+s.PageSize < end {
+		end = start + s.PageSize
+	}
+
+	resp := &athena.ListQueryExecutionsOutput{QueryExecutionIds: make([]*string, 0, end-start)}
+	for _, r := range rs[start:end] {
+		resp.QueryExecutionIds = append(resp.QueryExecutionIds, aws.String(r.ID))
+	}
+	if end < len(rs) {
+		resp.SetNextToken(fmt.Sprintf("NextToken%d", end))
+	}
+	return resp, nil
 }
 
 // ListQueryExecutionsWithContext is the same as ListQueryExecutions with the addition of
@@ -321,15 +386,17 @@ func (s *ListQueryExecutionsStub) ListQueryExecutionsWithContext(ctx aws.Context
 // calling the "fn" function with the response data for each page. To stop
 // iterating, return false from the fn function.
 func (s *ListQueryExecutionsStub) ListQueryExecutionsPages(input *athena.ListQueryExecutionsInput, fn func(*athena.ListQueryExecutionsOutput, bool) bool) error {
+	in := *input
 	cont := true
 	for cont {
-		lqx, err := s.ListQueryExecutions(input)
+		lqx, err := s.ListQueryExecutions(&in)
 		if err != nil {
 			return err
 		}
 		lastPage := lqx.NextToken == nil
 		cont = fn(lqx, lastPage)
 		cont = cont && !lastPage
+		in.NextToken = lqx.NextToken
 	}
 	return nil
 }
@@ -340,6 +407,13 @@ func (s *ListQueryExecutionsStub) ListQueryExecutionsPagesWithContext(ctx aws.Co
 	return s.ListQueryExecutionsPages(input, fn)
 }
 
+func (r *Result) outputLocation() string {
+	if r.OutputLocation == "" {
+		return outputLocation
+	}
+	return r.OutputLocation
+}
+
 // GetQueryResultsStub simulates GetQueryResults and GetQueryResultsPages API.
 type GetQueryResultsStub struct {
 	athenaiface.AthenaAPI
@@ -416,6 +490,158 @@ func (s *GetQueryResultsStub) GetQueryResultsPagesWithContext(ctx aws.Context, i
 	return s.GetQueryResultsPages(input, fn)
 }
 
+// NamedQueryStub simulates the APIs of named queries, which are stored in memory.
+type NamedQueryStub struct {
+	athenaiface.AthenaAPI
+	mu      sync.Mutex
+	queries map[string]*athena.NamedQuery // map[id]*athena.NamedQuery
+	ids     []string                      // IDs in the order of creation
+	nextID  int
+}
+
+// NewNamedQueryStub creates a new NamedQueryStub which has nqs as existing named queries.
+// IDs are generated for the ones without IDs.
+func NewNamedQueryStub(nqs ...*athena.NamedQuery) *NamedQueryStub {
+	s := &NamedQueryStub{queries: make(map[string]*athena.NamedQuery, len(nqs))}
+	for _, nq := range nqs {
+		s.add(nq)
+	}
+	return s
+}
+
+// add stores nq, and generates its ID if it has no ID. s.mu must be held or s must not be shared.
+func (s *NamedQueryStub) add(nq *athena.NamedQuery) string {
+	if aws.StringValue(nq.NamedQueryId) == "" {
+		s.nextID++
+		nq.NamedQueryId = aws.String(fmt.Sprintf("named-query-%d", s.nextID))
+	}
+	id := aws.StringValue(nq.NamedQueryId)
+	s.queries[id] = nq
+	s.ids = append(s.ids, id)
+	return id
+}
+
+// CreateNamedQuery creates a named query.
+func (s *NamedQueryStub) CreateNamedQuery(input *athena.CreateNamedQueryInput) (*athena.CreateNamedQueryOutput, error) {
+	if aws.StringValue(input.Name) == "" || aws.StringValue(input.Database) == "" || aws.StringValue(input.QueryString) == "" {
+		return nil, errors.Errorf("%s: Name, Database and QueryString are required", athena.ErrCodeInvalidRequestException)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.add(&athena.NamedQuery{
+		Name:        input.Name,
+		Database:    input.Database,
+		Description: input.Description,
+		QueryString: input.QueryString,
+	})
+	return &athena.CreateNamedQueryOutput{NamedQueryId: &id}, nil
+}
+
+// CreateNamedQueryWithContext is the same as CreateNamedQuery with the addition of
+// the ability to pass a context and additional request options.
+func (s *NamedQueryStub) CreateNamedQueryWithContext(ctx aws.Context, input *athena.CreateNamedQueryInput, opts ...request.Option) (*athena.CreateNamedQueryOutput, error) {
+	return s.CreateNamedQuery(input)
+}
+
+// GetNamedQuery returns information about a single named query.
+func (s *NamedQueryStub) GetNamedQuery(input *athena.GetNamedQueryInput) (*athena.GetNamedQueryOutput, error) {
+	id := aws.StringValue(input.NamedQueryId)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nq, ok := s.queries[id]
+	if !ok {
+		return nil, errors.Errorf("%s: NamedQuery %s was not found", athena.ErrCodeInvalidRequestException, id)
+	}
+	return &athena.GetNamedQueryOutput{NamedQuery: nq}, nil
+}
+
+// GetNamedQueryWithContext is the same as GetNamedQuery with the addition of
+// the ability to pass a context and additional request options.
+func (s *NamedQueryStub) GetNamedQueryWithContext(ctx aws.Context, input *athena.GetNamedQueryInput, opts ...request.Option) (*athena.GetNamedQueryOutput, error) {
+	return s.GetNamedQuery(input)
+}
+
+// BatchGetNamedQuery returns the details of up to 50 named queries.
+func (s *NamedQueryStub) BatchGetNamedQuery(input *athena.BatchGetNamedQueryInput) (*athena.BatchGetNamedQueryOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := &athena.BatchGetNamedQueryOutput{}
+	for _, id := range input.NamedQueryIds {
+		nq, ok := s.queries[aws.StringValue(id)]
+		if !ok {
+			out.UnprocessedNamedQueryIds = append(out.UnprocessedNamedQueryIds, &athena.UnprocessedNamedQueryId{
+				NamedQueryId: id,
+				ErrorCode:    aws.String(athena.ErrCodeInvalidRequestException),
+				ErrorMessage: aws.String(fmt.Sprintf("NamedQuery %s was not found", aws.StringValue(id))),
+			})
+			continue
+		}
+		out.NamedQueries = append(out.NamedQueries, nq)
+	}
+	return out, nil
+}
+
+// BatchGetNamedQueryWithContext is the same as BatchGetNamedQuery with the addition of
+// the ability to pass a context and additional request options.
+func (s *NamedQueryStub) BatchGetNamedQueryWithContext(ctx aws.Context, input *athena.BatchGetNamedQueryInput, opts ...request.Option) (*athena.BatchGetNamedQueryOutput, error) {
+	return s.BatchGetNamedQuery(input)
+}
+
+// ListNamedQueries provides a list of available named query IDs. It returns all of them at once.
+func (s *NamedQueryStub) ListNamedQueries(input *athena.ListNamedQueriesInput) (*athena.ListNamedQueriesOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &athena.ListNamedQueriesOutput{NamedQueryIds: aws.StringSlice(s.ids)}, nil
+}
+
+// ListNamedQueriesWithContext is the same as ListNamedQueries with the addition of
+// the ability to pass a context and additional request options.
+func (s *NamedQueryStub) ListNamedQueriesWithContext(ctx aws.Context, input *athena.ListNamedQueriesInput, opts ...request.Option) (*athena.ListNamedQueriesOutput, error) {
+	return s.ListNamedQueries(input)
+}
+
+// ListNamedQueriesPages iterates over the pages of a ListNamedQueries operation, calling fn
+// with the response data for each page.
+func (s *NamedQueryStub) ListNamedQueriesPages(input *athena.ListNamedQueriesInput, fn func(*athena.ListNamedQueriesOutput, bool) bool) error {
+	out, err := s.ListNamedQueries(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+// ListNamedQueriesPagesWithContext same as ListNamedQueriesPages except
+// it takes a Context and allows setting request options on the pages.
+func (s *NamedQueryStub) ListNamedQueriesPagesWithContext(ctx aws.Context, input *athena.ListNamedQueriesInput, fn func(*athena.ListNamedQueriesOutput, bool) bool, opts ...request.Option) error {
+	return s.ListNamedQueriesPages(input, fn)
+}
+
+// DeleteNamedQuery deletes a named query.
+func (s *NamedQueryStub) DeleteNamedQuery(input *athena.DeleteNamedQueryInput) (*athena.DeleteNamedQueryOutput, error) {
+	id := aws.StringValue(input.NamedQueryId)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.queries[id]; !ok {
+		return nil, errors.Errorf("%s: NamedQuery %s was not found", athena.ErrCodeInvalidRequestException, id)
+	}
+	delete(s.queries, id)
+	for i, x := range s.ids {
+		if x == id {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+	return &athena.DeleteNamedQueryOutput{}, nil
+}
+
+// DeleteNamedQueryWithContext is the same as DeleteNamedQuery with the addition of
+// the ability to pass a context and additional request options.
+func (s *NamedQueryStub) DeleteNamedQueryWithContext(ctx aws.Context, input *athena.DeleteNamedQueryInput, opts ...request.Option) (*athena.DeleteNamedQueryOutput, error) {
+	return s.DeleteNamedQuery(input)
+}
+
 // Client is a stub of Athena client.
 type Client struct {
 	athenaiface.AthenaAPI
@@ -425,9 +651,11 @@ type Client struct {
 	*BatchGetQueryExecutionStub
 	*ListQueryExecutionsStub
 	*GetQueryResultsStub
+	*NamedQueryStub
 }
 
 // NewClient returns a new Athena client which returns stub API responses based on rs.
+// It has no named queries. Use WithNamedQueries to add them.
 func NewClient(rs ...*Result) *Client {
 	return &Client{
 		StartQueryExecutionStub:    NewStartQueryExecutionStub(rs...),
@@ -436,9 +664,16 @@ func NewClient(rs ...*Result) *Client {
 		BatchGetQueryExecutionStub: NewBatchGetQueryExecutionStub(rs...),
 		ListQueryExecutionsStub:    NewListQueryExecutionsStub(rs...),
 		GetQueryResultsStub:        NewGetQueryResultsStub(rs...),
+		NamedQueryStub:             NewNamedQueryStub(),
 	}
 }
 
+// WithNamedQueries makes s have nqs as existing named queries.
+func (s *Client) WithNamedQueries(nqs ...*athena.NamedQuery) *Client {
+	s.NamedQueryStub = NewNamedQueryStub(nqs...)
+	return s
+}
+
 // StartQueryExecution runs the SQL query statements contained in the Query string.
 func (s *Client) StartQueryExecution(input *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
 	return s.StartQueryExecutionStub.StartQueryExecution(input)
@@ -529,3 +764,70 @@ func (s *Client) GetQueryResultsPages(input *athena.GetQueryResultsInput, fn fun
 func (s *Client) GetQueryResultsPagesWithContext(ctx aws.Context, input *athena.GetQueryResultsInput, fn func(*athena.GetQueryResultsOutput, bool) bool, opts ...request.Option) error {
 	return s.GetQueryResultsStub.GetQueryResultsPagesWithContext(ctx, input, fn, opts...)
 }
+
+// CreateNamedQuery creates a named query.
+func (s *Client) CreateNamedQuery(input *athena.CreateNamedQueryInput) (*athena.CreateNamedQueryOutput, error) {
+	return s.NamedQueryStub.CreateNamedQuery(input)
+}
+
+// CreateNamedQueryWithContext is the same as CreateNamedQuery with the addition of
+// the ability to pass a context and additional request options.
+func (s *Client) CreateNamedQueryWithContext(ctx aws.Context, input *athena.CreateNamedQueryInput, opts ...request.Option) (*athena.CreateNamedQueryOutput, error) {
+	return s.NamedQueryStub.CreateNamedQueryWithContext(ctx, input, opts...)
+}
+
+// GetNamedQuery returns information about a single named query.
+func (s *Client) GetNamedQuery(input *athena.GetNamedQueryInput) (*athena.GetNamedQueryOutput, error) {
+	return s.NamedQueryStub.GetNamedQuery(input)
+}
+
+// GetNamedQueryWithContext is the same as GetNamedQuery with the addition of
+// the ability to pass a context and additional request options.
+func (s *Client) GetNamedQueryWithContext(ctx aws.Context, input *athena.GetNamedQueryInput, opts ...request.Option) (*athena.GetNamedQueryOutput, error) {
+	return s.NamedQueryStub.GetNamedQueryWithContext(ctx, input, opts...)
+}
+
+// BatchGetNamedQuery returns the details of up to 50 named queries.
+func (s *Client) BatchGetNamedQuery(input *athena.BatchGetNamedQueryInput) (*athena.BatchGetNamedQueryOutput, error) {
+	return s.NamedQueryStub.BatchGetNamedQuery(input)
+}
+
+// BatchGetNamedQueryWithContext is the same as BatchGetNamedQuery with the addition of
+// the ability to pass a context and additional request options.
+func (s *Client) BatchGetNamedQueryWithContext(ctx aws.Context, input *athena.BatchGetNamedQueryInput, opts ...request.Option) (*athena.BatchGetNamedQueryOutput, error) {
+	return s.NamedQueryStub.BatchGetNamedQueryWithContext(ctx, input, opts...)
+}
+
+// ListNamedQueries provides a list of available named query IDs.
+func (s *Client) ListNamedQueries(input *athena.ListNamedQueriesInput) (*athena.ListNamedQueriesOutput, error) {
+	return s.NamedQueryStub.ListNamedQueries(input)
+}
+
+// ListNamedQueriesWithContext is the same as ListNamedQueries with the addition of
+// the ability to pass a context and additional request options.
+func (s *Client) ListNamedQueriesWithContext(ctx aws.Context, input *athena.ListNamedQueriesInput, opts ...request.Option) (*athena.ListNamedQueriesOutput, error) {
+	return s.NamedQueryStub.ListNamedQueriesWithContext(ctx, input, opts...)
+}
+
+// ListNamedQueriesPages iterates over the pages of a ListNamedQueries operation, calling fn
+// with the response data for each page.
+func (s *Client) ListNamedQueriesPages(input *athena.ListNamedQueriesInput, fn func(*athena.ListNamedQueriesOutput, bool) bool) error {
+	return s.NamedQueryStub.ListNamedQueriesPages(input, fn)
+}
+
+// ListNamedQueriesPagesWithContext same as ListNamedQueriesPages except
+// it takes a Context and allows setting request options on the pages.
+func (s *Client) ListNamedQueriesPagesWithContext(ctx aws.Context, input *athena.ListNamedQueriesInput, fn func(*athena.ListNamedQueriesOutput, bool) bool, opts ...request.Option) error {
+	return s.NamedQueryStub.ListNamedQueriesPagesWithContext(ctx, input, fn, opts...)
+}
+
+// DeleteNamedQuery deletes a named query.
+func (s *Client) DeleteNamedQuery(input *athena.DeleteNamedQueryInput) (*athena.DeleteNamedQueryOutput, error) {
+	return s.NamedQueryStub.DeleteNamedQuery(input)
+}
+
+// DeleteNamedQueryWithContext is the same as DeleteNamedQuery with the addition of
+// the ability to pass a context and additional request options.
+func (s *Client) DeleteNamedQueryWithContext(ctx aws.Context, input *athena.DeleteNamedQueryInput, opts ...request.Option) (*athena.DeleteNamedQueryOutput, error) {
+	return s.NamedQueryStub.DeleteNamedQueryWithContext(ctx, input, opts...)
+}
@@ -0,0 +1,78 @@
+package stub
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// S3Server is an in-memory stand-in for Amazon S3, which serves objects with GetObject API at
+// path-style URLs such as `http://127.0.0.1:12345/bucket/key`.
+type S3Server struct {
+	*httptest.Server
+	mu      sync.Mutex
+	objects map[string]string // map[bucket/key]content
+	gets    int
+}
+
+// NewS3Server starts a new S3Server. It should be closed after use.
+func NewS3Server() *S3Server {
+	s := &S3Server{objects: make(map[string]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// PutObject stores content as an object at s3URL such as `s3://bucket/key`.
+func (s *S3Server) PutObject(s3URL, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[strings.TrimPrefix(s3URL, "s3://")] = content
+}
+
+// Gets returns the number of GetObject requests s has served.
+func (s *S3Server) Gets() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gets
+}
+
+func (s *S3Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not supported")
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		writeS3Error(w, http.StatusForbidden, "AccessDenied", "Access Denied")
+		return
+	}
+
+	path, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/"))
+	if err != nil {
+		writeS3Error(w, http.StatusBadRequest, "InvalidURI", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.gets++
+	content, ok := s.objects[path]
+	s.mu.Unlock()
+	if !ok {
+		writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+	io.WriteString(w, content)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, msg)
+}
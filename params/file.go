@@ -0,0 +1,200 @@
+package params
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+)
+
+// tagPrefix is a prefix of YAML tags which specify the types of variables, e.g. `!date`.
+const tagPrefix = "!"
+
+// yamlTags maps the standard YAML tags to the types of variables.
+var yamlTags = map[string]Type{
+	"!!str":       TypeString,
+	"!!int":       TypeNumber,
+	"!!float":     TypeNumber,
+	"!!bool":      TypeBoolean,
+	"!!timestamp": TypeTimestamp,
+}
+
+// LoadFile loads variables from a YAML file at path. See ParseYAML for the supported syntax.
+func LoadFile(path string) (Vars, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to expand path")
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open variables file")
+	}
+	defer f.Close()
+
+	vs, err := ParseYAML(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse variables file %s", path)
+	}
+	return vs, nil
+}
+
+// ParseYAML parses variables from r in a subset of YAML, which is a flat mapping of variable
+// names to scalar values:
+//
+//	# Comments are ignored
+//	table: !ident elb_logs
+//	start: !date 2017-07-01
+//	status: 'OK'
+//	limit: 100
+//
+// The type of a value is specified with a tag, i.e. `!string`, `!number`, `!boolean`, `!date`,
+// `!timestamp` or `!ident` (or their standard YAML equivalents such as `!!str`).
+// Untagged plain values are numbers or booleans if they look like them, and strings otherwise.
+// Quoted values are always strings.
+func ParseYAML(r io.Reader) (Vars, error) {
+	vs := make(Vars)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, errors.Errorf("line %d: nested values are not supported", n)
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, errors.Errorf("line %d: expected `name: value`", n)
+		}
+		name := strings.TrimSpace(line[:i])
+		t, raw, err := parseYAMLValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		if err := vs.add(name, t, raw); err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read variables")
+	}
+	return vs, nil
+}
+
+// parseYAMLValue parses a scalar value optionally preceded by a tag and followed by a comment.
+func parseYAMLValue(s string) (Type, string, error) {
+	var t Type
+	if strings.HasPrefix(s, tagPrefix) {
+		tag := s
+		if i := strings.IndexAny(s, " \t"); i >= 0 {
+			tag, s = s[:i], strings.TrimSpace(s[i:])
+		} else {
+			s = ""
+		}
+		var err error
+		if t, err = parseTag(tag); err != nil {
+			return "", "", err
+		}
+	}
+
+	if s == "" || strings.HasPrefix(s, "#") {
+		return "", "", errors.New("empty values are not supported")
+	}
+
+	var raw string
+	switch s[0] {
+	case '\'':
+		end := -1
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				i++ // Escaped quote
+				continue
+			}
+			end = i
+			break
+		}
+		if end < 0 {
+			return "", "", errors.New("unterminated single-quoted value")
+		}
+		raw, s = strings.Replace(s[1:end], "''", "'", -1), s[end+1:]
+	case '"':
+		end := -1
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return "", "", errors.New("unterminated double-quoted value")
+		}
+		var err error
+		if raw, err = strconv.Unquote(s[:end+1]); err != nil {
+			return "", "", errors.Wrap(err, "invalid double-quoted value")
+		}
+		s = s[end+1:]
+	case '[', '{', '|', '>', '&', '*':
+		return "", "", errors.New("only scalar values are supported")
+	default:
+		raw = s
+		if i := strings.Index(s, " #"); i >= 0 {
+			raw = s[:i]
+		}
+		raw, s = strings.TrimSpace(raw), ""
+		if t == "" && isNull(raw) {
+			return "", "", errors.New("null values are not supported")
+		}
+		if t == "" {
+			t = resolvePlain(raw)
+		}
+	}
+
+	if rest := strings.TrimSpace(s); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", "", errors.Errorf("unexpected %q after quoted value", rest)
+	}
+	if t == "" {
+		t = TypeString
+	}
+	return t, raw, nil
+}
+
+// parseTag parses a YAML tag into a type.
+func parseTag(tag string) (Type, error) {
+	if t, ok := yamlTags[tag]; ok {
+		return t, nil
+	}
+	return ParseType(strings.TrimPrefix(tag, tagPrefix))
+}
+
+// isNull reports whether an untagged plain value is null.
+func isNull(raw string) bool {
+	switch raw {
+	case "~", "null", "Null", "NULL":
+		return true
+	}
+	return false
+}
+
+// resolvePlain resolves the type of an untagged plain value.
+func resolvePlain(raw string) Type {
+	switch {
+	case numberRegexp.MatchString(raw):
+		return TypeNumber
+	case raw == "true" || raw == "false" || raw == "True" || raw == "False" || raw == "TRUE" || raw == "FALSE":
+		return TypeBoolean
+	}
+	return TypeString
+}
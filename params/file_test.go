@@ -0,0 +1,94 @@
+package params
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYAML(t *testing.T) {
+	src := `---
+# Variables for the daily report
+table: !ident sampledb.elb_logs
+start: !date 2017-07-01   # inclusive
+at: !!timestamp 2017-07-01 09:00:00
+status: 'it''s OK'
+message: "tab\tand \"quotes\""
+limit: 100
+ratio: -0.5
+enabled: true
+label: plain text # comment
+code: !string 200
+`
+	want := map[string]string{
+		"table":   "sampledb.elb_logs",
+		"start":   "DATE '2017-07-01'",
+		"at":      "TIMESTAMP '2017-07-01 09:00:00.000'",
+		"status":  "'it''s OK'",
+		"message": "'tab\tand \"quotes\"'",
+		"limit":   "100",
+		"ratio":   "(-0.5)",
+		"enabled": "TRUE",
+		"label":   "'plain text'",
+		"code":    "'200'",
+	}
+
+	vs, err := ParseYAML(strings.NewReader(src))
+
+	assert.NoError(t, err)
+	got := make(map[string]string, len(vs))
+	for name := range vs {
+		got[name], _ = vs.Lookup(name)
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestParseYAMLError(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"table elb_logs", "line 1: expected `name: value`"},
+		{"a: 1\ntables:\n  - elb_logs", "line 2: empty values are not supported"},
+		{"  a: 1", "line 1: nested values are not supported"},
+		{"a: [1, 2]", "only scalar values are supported"},
+		{"a: ~", "null values are not supported"},
+		{"a: 'unterminated", "unterminated single-quoted value"},
+		{`a: "unterminated`, "unterminated double-quoted value"},
+		{"a: 'x' y", "unexpected"},
+		{"a: !date today", "invalid date"},
+		{"a: !raw 1", "unknown type"},
+		{"a-b: 1", "invalid variable name"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseYAML(strings.NewReader(tt.src))
+
+		if assert.Error(t, err, "Src: %q", tt.src) {
+			assert.Contains(t, err.Error(), tt.want, "Src: %q", tt.src)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "athenai-vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("day: !date 2017-07-01\n")
+	f.Close()
+
+	vs, err := LoadFile(f.Name())
+
+	assert.NoError(t, err)
+	got, ok := vs.Lookup("day")
+	assert.True(t, ok)
+	assert.Equal(t, "DATE '2017-07-01'", got)
+
+	_, err = LoadFile("/path/to/nonexistent.yaml")
+	assert.Error(t, err)
+}
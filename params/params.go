@@ -0,0 +1,223 @@
+// Package params binds typed variables to placeholders in SQL statements.
+//
+// Each variable has a type which determines how its value is validated and written as an SQL
+// literal, e.g. a string value is quoted with its single quotes escaped and a date value is
+// checked to be a valid date, so that values given at run time cannot inject SQL.
+package params
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Type represents a type of a variable.
+type Type string
+
+// Supported types of variables.
+const (
+	TypeString    Type = "string"
+	TypeNumber    Type = "number"
+	TypeBoolean   Type = "boolean"
+	TypeDate      Type = "date"
+	TypeTimestamp Type = "timestamp"
+	TypeIdent     Type = "ident"
+)
+
+// Types is a list of the supported types.
+var Types = []string{
+	string(TypeString),
+	string(TypeNumber),
+	string(TypeBoolean),
+	string(TypeDate),
+	string(TypeTimestamp),
+	string(TypeIdent),
+}
+
+// typeAliases maps alternative names to the supported types.
+var typeAliases = map[string]Type{
+	"str":        TypeString,
+	"int":        TypeNumber,
+	"float":      TypeNumber,
+	"bool":       TypeBoolean,
+	"identifier": TypeIdent,
+}
+
+const (
+	dateLayout      = "2006-01-02"
+	timestampLayout = "2006-01-02 15:04:05.000"
+)
+
+// timestampLayouts are the layouts accepted as timestamp values.
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	dateLayout,
+}
+
+var (
+	nameRegexp   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	numberRegexp = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+)
+
+// ParseType parses s as a type of a variable. It is case-insensitive.
+func ParseType(s string) (Type, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, t := range Types {
+		if s == t {
+			return Type(s), nil
+		}
+	}
+	if t, ok := typeAliases[s]; ok {
+		return t, nil
+	}
+	return "", errors.Errorf("unknown type %q. Valid types: %s", s, strings.Join(Types, ", "))
+}
+
+// Value represents a typed value of a variable.
+type Value struct {
+	Type    Type
+	Raw     string
+	literal string
+}
+
+// NewValue validates raw as a value of type t and creates a new Value.
+func NewValue(t Type, raw string) (Value, error) {
+	lit, err := literal(t, raw)
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Type: t, Raw: raw, literal: lit}, nil
+}
+
+// Literal returns v written as an SQL literal, or as an identifier if v is of TypeIdent.
+func (v Value) Literal() string {
+	return v.literal
+}
+
+func (v Value) String() string {
+	return fmt.Sprintf("%s(%s)", v.Type, v.Raw)
+}
+
+// literal validates raw as a value of type t and writes it in SQL.
+func literal(t Type, raw string) (string, error) {
+	switch t {
+	case TypeString:
+		return quote(raw), nil
+	case TypeNumber:
+		n := strings.TrimPrefix(raw, "+")
+		if !numberRegexp.MatchString(n) {
+			return "", errors.Errorf("invalid number %q", raw)
+		}
+		if strings.HasPrefix(n, "-") {
+			// Parenthesize it so that it never forms a line comment such as `x --1`
+			return "(" + n + ")", nil
+		}
+		return n, nil
+	case TypeBoolean:
+		switch strings.ToLower(raw) {
+		case "true":
+			return "TRUE", nil
+		case "false":
+			return "FALSE", nil
+		}
+		return "", errors.Errorf("invalid boolean %q. Valid values: true, false", raw)
+	case TypeDate:
+		d, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			return "", errors.Errorf("invalid date %q. Dates must be in YYYY-MM-DD format", raw)
+		}
+		return "DATE " + quote(d.Format(dateLayout)), nil
+	case TypeTimestamp:
+		for _, layout := range timestampLayouts {
+			if ts, err := time.Parse(layout, raw); err == nil {
+				return "TIMESTAMP " + quote(ts.Format(timestampLayout)), nil
+			}
+		}
+		return "", errors.Errorf("invalid timestamp %q. Timestamps must be in YYYY-MM-DD hh:mm:ss[.fff] format", raw)
+	case TypeIdent:
+		// Identifiers are not quoted since Athena quotes them differently in DDL and DML,
+		// so allow only the characters which need no quotes
+		for _, part := range strings.Split(raw, ".") {
+			if !nameRegexp.MatchString(part) {
+				return "", errors.Errorf("invalid identifier %q. Identifiers must consist of letters, digits and underscores, "+
+					"optionally qualified with dots", raw)
+			}
+		}
+		return raw, nil
+	}
+	return "", errors.Errorf("unknown type %q", t)
+}
+
+// quote quotes s as a string literal.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// Vars is a set of variables keyed by their names.
+type Vars map[string]Value
+
+// Set parses spec in `name=value` or `name:type=value` format and sets the variable to vs.
+// The type defaults to TypeString.
+func (vs Vars) Set(spec string) error {
+	i := strings.IndexByte(spec, '=')
+	if i < 0 {
+		return errors.Errorf("invalid variable %q. Variables must be in name=value or name:type=value format", spec)
+	}
+	name, raw := strings.TrimSpace(spec[:i]), spec[i+1:]
+
+	t := TypeString
+	if j := strings.IndexByte(name, ':'); j >= 0 {
+		var err error
+		if t, err = ParseType(name[j+1:]); err != nil {
+			return errors.Wrapf(err, "invalid variable %q", spec)
+		}
+		name = strings.TrimSpace(name[:j])
+	}
+	return vs.add(name, t, raw)
+}
+
+// add validates and sets the variable name of type t to vs.
+func (vs Vars) add(name string, t Type, raw string) error {
+	if !nameRegexp.MatchString(name) {
+		return errors.Errorf("invalid variable name %q. Names must consist of letters, digits and underscores", name)
+	}
+	v, err := NewValue(t, raw)
+	if err != nil {
+		return errors.Wrapf(err, "invalid value of variable %q", name)
+	}
+	vs[name] = v
+	return nil
+}
+
+// Merge sets all the variables in other to vs, overriding the ones with the same names.
+func (vs Vars) Merge(other Vars) {
+	for name, v := range other {
+		vs[name] = v
+	}
+}
+
+// Lookup returns the literal of the variable name and true if it exists, otherwise false.
+func (vs Vars) Lookup(name string) (string, bool) {
+	v, ok := vs[name]
+	if !ok {
+		return "", false
+	}
+	return v.Literal(), true
+}
+
+// Names returns the sorted names of the variables.
+func (vs Vars) Names() []string {
+	names := make([]string, 0, len(vs))
+	for name := range vs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
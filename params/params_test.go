@@ -0,0 +1,128 @@
+package params
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Type
+		wantErr bool
+	}{
+		{s: "string", want: TypeString},
+		{s: "DATE", want: TypeDate},
+		{s: " timestamp ", want: TypeTimestamp},
+		{s: "identifier", want: TypeIdent},
+		{s: "int", want: TypeNumber},
+		{s: "bool", want: TypeBoolean},
+		{s: "raw", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseType(tt.s)
+		if tt.wantErr {
+			assert.Error(t, err, "Type: %q", tt.s)
+			continue
+		}
+		assert.NoError(t, err, "Type: %q", tt.s)
+		assert.Equal(t, tt.want, got, "Type: %q", tt.s)
+	}
+}
+
+func TestNewValue(t *testing.T) {
+	tests := []struct {
+		typ     Type
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{typ: TypeString, raw: "abc", want: "'abc'"},
+		{typ: TypeString, raw: "x' OR '1'='1", want: "'x'' OR ''1''=''1'"},
+		{typ: TypeString, raw: `a\'b`, want: `'a\''b'`},
+		{typ: TypeString, raw: "", want: "''"},
+		{typ: TypeNumber, raw: "2017", want: "2017"},
+		{typ: TypeNumber, raw: "+1.5e3", want: "1.5e3"},
+		{typ: TypeNumber, raw: "-1", want: "(-1)"},
+		{typ: TypeNumber, raw: "1; DROP TABLE t", wantErr: true},
+		{typ: TypeNumber, raw: "0x10", wantErr: true},
+		{typ: TypeBoolean, raw: "True", want: "TRUE"},
+		{typ: TypeBoolean, raw: "false", want: "FALSE"},
+		{typ: TypeBoolean, raw: "yes", wantErr: true},
+		{typ: TypeDate, raw: "2017-07-01", want: "DATE '2017-07-01'"},
+		{typ: TypeDate, raw: "2017-02-30", wantErr: true},
+		{typ: TypeDate, raw: "2017-07-01' OR '1'='1", wantErr: true},
+		{typ: TypeTimestamp, raw: "2017-07-01 09:30:00", want: "TIMESTAMP '2017-07-01 09:30:00.000'"},
+		{typ: TypeTimestamp, raw: "2017-07-01T09:30:00.123", want: "TIMESTAMP '2017-07-01 09:30:00.123'"},
+		{typ: TypeTimestamp, raw: "2017-07-01", want: "TIMESTAMP '2017-07-01 00:00:00.000'"},
+		{typ: TypeTimestamp, raw: "yesterday", wantErr: true},
+		{typ: TypeIdent, raw: "elb_logs", want: "elb_logs"},
+		{typ: TypeIdent, raw: "sampledb.elb_logs", want: "sampledb.elb_logs"},
+		{typ: TypeIdent, raw: "t; DROP TABLE t", wantErr: true},
+		{typ: TypeIdent, raw: `t"`, wantErr: true},
+		{typ: TypeIdent, raw: "db.", wantErr: true},
+		{typ: Type("raw"), raw: "1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NewValue(tt.typ, tt.raw)
+		if tt.wantErr {
+			assert.Error(t, err, "Type: %s, Raw: %q", tt.typ, tt.raw)
+			continue
+		}
+		assert.NoError(t, err, "Type: %s, Raw: %q", tt.typ, tt.raw)
+		assert.Equal(t, tt.want, got.Literal(), "Type: %s, Raw: %q", tt.typ, tt.raw)
+	}
+}
+
+func TestVarsSet(t *testing.T) {
+	tests := []struct {
+		spec    string
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "status=OK", name: "status", want: "'OK'"},
+		{spec: "query=a=b", name: "query", want: "'a=b'"},
+		{spec: "day:date=2017-07-01", name: "day", want: "DATE '2017-07-01'"},
+		{spec: "table:ident=elb_logs", name: "table", want: "elb_logs"},
+		{spec: "status", wantErr: true},
+		{spec: "1st=a", wantErr: true},
+		{spec: "day:unknown=1", wantErr: true},
+		{spec: "day:date=today", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		vs := make(Vars)
+		err := vs.Set(tt.spec)
+		if tt.wantErr {
+			assert.Error(t, err, "Spec: %q", tt.spec)
+			continue
+		}
+		got, ok := vs.Lookup(tt.name)
+		assert.NoError(t, err, "Spec: %q", tt.spec)
+		assert.True(t, ok, "Spec: %q", tt.spec)
+		assert.Equal(t, tt.want, got, "Spec: %q", tt.spec)
+	}
+}
+
+func TestVarsMerge(t *testing.T) {
+	vs := Vars{}
+	assert.NoError(t, vs.Set("a=1"))
+	assert.NoError(t, vs.Set("b=2"))
+	other := Vars{}
+	assert.NoError(t, other.Set("b:number=3"))
+	assert.NoError(t, other.Set("c=4"))
+
+	vs.Merge(other)
+
+	assert.Equal(t, []string{"a", "b", "c"}, vs.Names())
+	got, _ := vs.Lookup("b")
+	assert.Equal(t, "3", got)
+
+	var nilVars Vars
+	_, ok := nilVars.Lookup("a")
+	assert.False(t, ok)
+}
@@ -0,0 +1,42 @@
+package print
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/exec"
+)
+
+// columns returns columns based on the result set metadata. If the metadata is not available,
+// it names columns `_col0`, `_col1`, ... in the same way as Athena does.
+func columns(infos []*athena.ColumnInfo, rows [][]string) []exec.Column {
+	if len(infos) > 0 {
+		cols := make([]exec.Column, len(infos))
+		for i, info := range infos {
+			cols[i] = exec.Column{Name: aws.StringValue(info.Name), Type: aws.StringValue(info.Type)}
+		}
+		return cols
+	}
+
+	n := 0
+	for _, row := range rows {
+		if len(row) > n {
+			n = len(row)
+		}
+	}
+	cols := make([]exec.Column, n)
+	for i := range cols {
+		cols[i] = exec.Column{Name: fmt.Sprintf("_col%d", i)}
+	}
+	return cols
+}
+
+// isNumeric returns true if col is of a numeric type, otherwise false.
+func isNumeric(col exec.Column) bool {
+	switch exec.BaseType(col.Type) {
+	case "tinyint", "smallint", "integer", "int", "bigint", "float", "real", "double", "decimal":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package print
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPricePerTB is the default price in US dollars per TB of data scanned by Athena.
+	// https://aws.amazon.com/athena/pricing/
+	DefaultPricePerTB = 5.0
+
+	// Athena charges data scanned rounded up to the nearest megabyte, with a 10 MB minimum per query.
+	billingUnit    = 1 << 20
+	minBilledBytes = 10 * billingUnit
+	bytesPerTB     = 1 << 40 // TB in pricing is 2^40 bytes
+)
+
+// Cost returns the estimated cost in US dollars of a query execution which has scanned
+// scannedBytes, at pricePerTB. Query executions scanning no data, such as DDL statements,
+// cost nothing.
+func Cost(scannedBytes int64, pricePerTB float64) float64 {
+	if scannedBytes <= 0 || pricePerTB <= 0 {
+		return 0
+	}
+	billed := int64(math.Ceil(float64(scannedBytes)/billingUnit)) * billingUnit
+	if billed < minBilledBytes {
+		billed = minBilledBytes
+	}
+	return float64(billed) / bytesPerTB * pricePerTB
+}
+
+// FormatCost produces a human readable representation of a cost in US dollars.
+func FormatCost(cost float64) string {
+	switch {
+	case cost == 0:
+		return "$0"
+	case cost < 0.0001:
+		return "< $0.0001"
+	case cost < 1:
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+// byteUnits maps units of sizes, in lower case, to the numbers of bytes.
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+// ParseBytes parses a human readable size such as "10GB", "1.5 TB" or "500MiB" into the number of
+// bytes. Units without "i" are SI ones like FormatBytes, e.g. 1 KB = 1000 bytes.
+func ParseBytes(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid size %q", s)
+	}
+	mul, ok := byteUnits[unit]
+	if !ok {
+		return 0, errors.Errorf("invalid unit %q in size %q", unit, s)
+	}
+	return int64(f * mul), nil
+}
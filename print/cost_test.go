@@ -0,0 +1,67 @@
+package print
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		scanned int64
+		price   float64
+		want    float64
+	}{
+		{scanned: 0, price: 5, want: 0},
+		{scanned: 1, price: 5, want: 5.0 * 10 / (1 << 20)},          // 10 MB minimum
+		{scanned: 10<<20 + 1, price: 5, want: 5.0 * 11 / (1 << 20)}, // Rounded up to MB
+		{scanned: 1 << 40, price: 5, want: 5},                       // 1 TB
+		{scanned: 3 << 39, price: 2.5, want: 3.75},                  // 1.5 TB
+		{scanned: 1 << 40, price: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		assert.InDelta(t, tt.want, Cost(tt.scanned, tt.price), 1e-12, "Scanned: %d, Price: %f", tt.scanned, tt.price)
+	}
+}
+
+func TestFormatCost(t *testing.T) {
+	tests := []struct {
+		cost float64
+		want string
+	}{
+		{cost: 0, want: "$0"},
+		{cost: 0.00004768, want: "< $0.0001"},
+		{cost: 0.0045, want: "$0.0045"},
+		{cost: 12.345, want: "$12.35"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FormatCost(tt.cost), "Cost: %f", tt.cost)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+	}{
+		{s: "123", want: 123},
+		{s: "10GB", want: 10e9},
+		{s: "1.5 TB", want: 1.5e12},
+		{s: "500mb", want: 500e6},
+		{s: "2GiB", want: 2 << 30},
+		{s: " 1 KiB ", want: 1024},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBytes(tt.s)
+		assert.NoError(t, err, "Input: %q", tt.s)
+		assert.Equal(t, tt.want, got, "Input: %q", tt.s)
+	}
+
+	for _, s := range []string{"", "GB", "10 XB", "1.2.3GB"} {
+		_, err := ParseBytes(s)
+		assert.Error(t, err, "Input: %q", s)
+	}
+}
@@ -0,0 +1,137 @@
+package print
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+)
+
+// historyTimeLayout is a layout of times in a history in table format.
+const historyTimeLayout = "2006-01-02 15:04:05 MST"
+
+// historyDocument is a JSON document which represents a query execution in a history.
+type historyDocument struct {
+	QueryExecutionID            string     `json:"QueryExecutionId"`
+	SubmissionDateTime          *time.Time `json:"SubmissionDateTime,omitempty"`
+	State                       string     `json:"State"`
+	EngineExecutionTimeInMillis int64      `json:"EngineExecutionTimeInMillis"`
+	DataScannedInBytes          int64      `json:"DataScannedInBytes"`
+	Database                    string     `json:"Database,omitempty"`
+	OutputLocation              string     `json:"OutputLocation,omitempty"`
+}
+
+func newHistoryDocument(qx *athena.QueryExecution) *historyDocument {
+	doc := &historyDocument{QueryExecutionID: aws.StringValue(qx.QueryExecutionId)}
+	if st := qx.Status; st != nil {
+		doc.SubmissionDateTime = st.SubmissionDateTime
+		doc.State = aws.StringValue(st.State)
+	}
+	if stats := qx.Statistics; stats != nil {
+		doc.EngineExecutionTimeInMillis = aws.Int64Value(stats.EngineExecutionTimeInMillis)
+		doc.DataScannedInBytes = aws.Int64Value(stats.DataScannedInBytes)
+	}
+	if ctx := qx.QueryExecutionContext; ctx != nil {
+		doc.Database = aws.StringValue(ctx.Database)
+	}
+	if rc := qx.ResultConfiguration; rc != nil {
+		doc.OutputLocation = aws.StringValue(rc.OutputLocation)
+	}
+	return doc
+}
+
+// PrintHistory prints a history of query executions qxs to out in format.
+// Times and sizes are human-readable in table, vertical, markdown and html formats, and raw values in the other formats.
+func PrintHistory(out io.Writer, format string, qxs []*athena.QueryExecution) error {
+	docs := make([]*historyDocument, len(qxs))
+	for i, qx := range qxs {
+		docs[i] = newHistoryDocument(qx)
+	}
+
+	switch format {
+	case FormatTable, FormatAuto:
+		tw := tablewriter.NewWriter(out)
+		tw.AppendBulk(historyRows(docs))
+		tw.Render()
+	case FormatVertical:
+		printVerticalRows(out, historyRows(docs))
+	case FormatMarkdown:
+		printMarkdownRows(out, historyRows(docs))
+	case FormatHTML:
+		printHTMLRows(out, historyRows(docs))
+	case FormatCSV:
+		w := csv.NewWriter(out)
+		w.WriteAll(historyRawRows(docs))
+		return errors.Wrap(w.Error(), "failed to write history in CSV")
+	case FormatTSV:
+		for _, row := range historyRawRows(docs) {
+			if err := writeTSVRow(out, row); err != nil {
+				return errors.Wrap(err, "failed to write history in TSV")
+			}
+		}
+	case FormatJSON:
+		return errors.Wrap(json.NewEncoder(out).Encode(docs), "failed to encode history into JSON")
+	case FormatNDJSON:
+		enc := json.NewEncoder(out)
+		for _, doc := range docs {
+			if err := enc.Encode(doc); err != nil {
+				return errors.Wrap(err, "failed to encode history into JSON")
+			}
+		}
+	default:
+		return errors.Errorf("unknown format %q", format)
+	}
+	return nil
+}
+
+// historyRows returns rows of docs to print in text formats, whose first row is the header.
+func historyRows(docs []*historyDocument) [][]string {
+	rows := make([][]string, 0, len(docs)+1)
+	rows = append(rows, []string{"QueryExecutionId", "Submitted", "State", "Run time", "Data scanned", "Database", "Location"})
+	for _, doc := range docs {
+		submitted := ""
+		if doc.SubmissionDateTime != nil {
+			submitted = doc.SubmissionDateTime.Format(historyTimeLayout)
+		}
+		rows = append(rows, []string{
+			doc.QueryExecutionID,
+			submitted,
+			doc.State,
+			fmt.Sprintf("%.2f seconds", float64(doc.EngineExecutionTimeInMillis)/1000),
+			FormatBytes(doc.DataScannedInBytes),
+			doc.Database,
+			doc.OutputLocation,
+		})
+	}
+	return rows
+}
+
+// historyRawRows returns rows of docs with raw values to print in CSV and TSV formats,
+// whose first row is the header.
+func historyRawRows(docs []*historyDocument) [][]string {
+	rows := make([][]string, 0, len(docs)+1)
+	rows = append(rows, []string{"QueryExecutionId", "SubmissionDateTime", "State", "EngineExecutionTimeInMillis", "DataScannedInBytes", "Database", "OutputLocation"})
+	for _, doc := range docs {
+		submitted := ""
+		if doc.SubmissionDateTime != nil {
+			submitted = doc.SubmissionDateTime.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			doc.QueryExecutionID,
+			submitted,
+			doc.State,
+			strconv.FormatInt(doc.EngineExecutionTimeInMillis, 10),
+			strconv.FormatInt(doc.DataScannedInBytes, 10),
+			doc.Database,
+			doc.OutputLocation,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,125 @@
+package print
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintHistory(t *testing.T) {
+	submitted := time.Date(2017, 7, 1, 9, 30, 0, 0, time.UTC)
+	qxs := []*athena.QueryExecution{
+		{
+			QueryExecutionId:      aws.String("TestPrintHistory_Succeeded"),
+			QueryExecutionContext: &athena.QueryExecutionContext{Database: aws.String("sampledb")},
+			ResultConfiguration:   testhelper.CreateResultConfig(outputLocation),
+			Statistics:            testhelper.CreateStats(1234, 56789),
+			Status: &athena.QueryExecutionStatus{
+				SubmissionDateTime: &submitted,
+				State:              aws.String(athena.QueryExecutionStateSucceeded),
+			},
+		},
+		{
+			QueryExecutionId: aws.String("TestPrintHistory_Failed"),
+			Status:           &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateFailed)},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatTable,
+			want: `+----------------------------+-------------------------+-----------+--------------+--------------+----------+--------------------+
+| QueryExecutionId           | Submitted               | State     | Run time     | Data scanned | Database | Location           |
+| TestPrintHistory_Succeeded | 2017-07-01 09:30:00 UTC | SUCCEEDED | 1.23 seconds | 56.79 KB     | sampledb | s3://samplebucket/ |
+| TestPrintHistory_Failed    |                         | FAILED    | 0.00 seconds | 0 B          |          |                    |
++----------------------------+-------------------------+-----------+--------------+--------------+----------+--------------------+
+`,
+		},
+		{
+			format: FormatVertical,
+			want: `-[ RECORD 1 ]----+---------------------------
+QueryExecutionId | TestPrintHistory_Succeeded
+Submitted        | 2017-07-01 09:30:00 UTC
+State            | SUCCEEDED
+Run time         | 1.23 seconds
+Data scanned     | 56.79 KB
+Database         | sampledb
+Location         | s3://samplebucket/
+-[ RECORD 2 ]----+------------------------
+QueryExecutionId | TestPrintHistory_Failed
+Submitted        |
+State            | FAILED
+Run time         | 0.00 seconds
+Data scanned     | 0 B
+Database         |
+Location         |
+`,
+		},
+		{
+			format: FormatCSV,
+			want: `QueryExecutionId,SubmissionDateTime,State,EngineExecutionTimeInMillis,DataScannedInBytes,Database,OutputLocation
+TestPrintHistory_Succeeded,2017-07-01T09:30:00Z,SUCCEEDED,1234,56789,sampledb,s3://samplebucket/
+TestPrintHistory_Failed,,FAILED,0,0,,
+`,
+		},
+		{
+			format: FormatTSV,
+			want: "QueryExecutionId\tSubmissionDateTime\tState\tEngineExecutionTimeInMillis\tDataScannedInBytes\tDatabase\tOutputLocation\n" +
+				"TestPrintHistory_Succeeded\t2017-07-01T09:30:00Z\tSUCCEEDED\t1234\t56789\tsampledb\ts3://samplebucket/\n" +
+				"TestPrintHistory_Failed\t\tFAILED\t0\t0\t\t\n",
+		},
+		{
+			format: FormatMarkdown,
+			want: `| QueryExecutionId | Submitted | State | Run time | Data scanned | Database | Location |
+| --- | --- | --- | --- | --- | --- | --- |
+| TestPrintHistory_Succeeded | 2017-07-01 09:30:00 UTC | SUCCEEDED | 1.23 seconds | 56.79 KB | sampledb | s3://samplebucket/ |
+| TestPrintHistory_Failed |  | FAILED | 0.00 seconds | 0 B |  |  |
+`,
+		},
+		{
+			format: FormatHTML,
+			want: `<table>
+<thead>
+<tr><th>QueryExecutionId</th><th>Submitted</th><th>State</th><th>Run time</th><th>Data scanned</th><th>Database</th><th>Location</th></tr>
+</thead>
+<tbody>
+<tr><td>TestPrintHistory_Succeeded</td><td>2017-07-01 09:30:00 UTC</td><td>SUCCEEDED</td><td>1.23 seconds</td><td>56.79 KB</td><td>sampledb</td><td>s3://samplebucket/</td></tr>
+<tr><td>TestPrintHistory_Failed</td><td></td><td>FAILED</td><td>0.00 seconds</td><td>0 B</td><td></td><td></td></tr>
+</tbody>
+</table>
+`,
+		},
+		{
+			format: FormatJSON,
+			want: `[{"QueryExecutionId":"TestPrintHistory_Succeeded","SubmissionDateTime":"2017-07-01T09:30:00Z","State":"SUCCEEDED",` +
+				`"EngineExecutionTimeInMillis":1234,"DataScannedInBytes":56789,"Database":"sampledb","OutputLocation":"s3://samplebucket/"},` +
+				`{"QueryExecutionId":"TestPrintHistory_Failed","State":"FAILED","EngineExecutionTimeInMillis":0,"DataScannedInBytes":0}]
+`,
+		},
+		{
+			format: FormatNDJSON,
+			want: `{"QueryExecutionId":"TestPrintHistory_Succeeded","SubmissionDateTime":"2017-07-01T09:30:00Z","State":"SUCCEEDED",` +
+				`"EngineExecutionTimeInMillis":1234,"DataScannedInBytes":56789,"Database":"sampledb","OutputLocation":"s3://samplebucket/"}
+{"QueryExecutionId":"TestPrintHistory_Failed","State":"FAILED","EngineExecutionTimeInMillis":0,"DataScannedInBytes":0}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := PrintHistory(&out, tt.format, qxs)
+
+		assert.NoError(t, err, "Format: %s", tt.format)
+		assert.Equal(t, tt.want, out.String(), "Format: %s", tt.format)
+	}
+
+	assert.Error(t, PrintHistory(&bytes.Buffer{}, "xml", qxs))
+}
@@ -0,0 +1,91 @@
+package print
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/skatsuta/athenai/exec"
+)
+
+// htmlStyle prints the query in a preformatted block, and the other information as paragraphs
+// whose lines are separated by `<br>`.
+var htmlStyle = &style{
+	header: func(w io.Writer, query string) {
+		fmt.Fprintf(w, "<pre><code>%s;</code></pre>\n", html.EscapeString(query))
+	},
+	text: func(w io.Writer, text string) {
+		lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(line)
+		}
+		fmt.Fprintf(w, "<p>%s</p>\n", strings.Join(lines, "<br>\n"))
+	},
+}
+
+// printHTML prints the results as a standalone HTML table, writing each row as it is read.
+// The column names are put in the header of the table if they are known. Values are escaped,
+// with newlines rendered as `<br>`. NULL is rendered as `NULL`, and values in numeric columns
+// are aligned to the right.
+func printHTML(out io.Writer, r Result) int {
+	infos := r.ColumnInfo()
+	var cols []exec.Column
+	n := 0
+	eachRow(r, func(row []string, nulls []bool) bool {
+		if n == 0 {
+			io.WriteString(out, "<table>\n")
+			cols = columns(infos, nil)
+			if len(cols) > 0 {
+				io.WriteString(out, "<thead>\n")
+				writeHTMLRow(out, "th", nil, columnNames(cols))
+				io.WriteString(out, "</thead>\n")
+			}
+			io.WriteString(out, "<tbody>\n")
+			if exec.IsHeader(cols, row) {
+				n++
+				return true
+			}
+		}
+		writeHTMLRow(out, "td", cols, replaceNulls(row, nulls, nullString))
+		n++
+		return true
+	})
+	if n > 0 {
+		io.WriteString(out, "</tbody>\n</table>\n")
+	}
+	return n
+}
+
+// printHTMLRows prints rows whose first row is the header as a standalone HTML table.
+func printHTMLRows(out io.Writer, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	io.WriteString(out, "<table>\n<thead>\n")
+	writeHTMLRow(out, "th", nil, rows[0])
+	io.WriteString(out, "</thead>\n<tbody>\n")
+	for _, row := range rows[1:] {
+		writeHTMLRow(out, "td", nil, row)
+	}
+	io.WriteString(out, "</tbody>\n</table>\n")
+}
+
+// writeHTMLRow writes row with cells of tag. Cells in numeric columns of cols are aligned to
+// the right.
+func writeHTMLRow(w io.Writer, tag string, cols []exec.Column, row []string) {
+	var buf bytes.Buffer
+	buf.WriteString("<tr>")
+	for j, v := range row {
+		buf.WriteString("<" + tag)
+		if j < len(cols) && isNumeric(cols[j]) {
+			buf.WriteString(` align="right"`)
+		}
+		buf.WriteString(">")
+		buf.WriteString(strings.Replace(html.EscapeString(v), "\n", "<br>", -1))
+		buf.WriteString("</" + tag + ">")
+	}
+	buf.WriteString("</tr>\n")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,96 @@
+package print
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLPrinter(t *testing.T) {
+	tests := []struct {
+		r    Result
+		want string
+	}{
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestHTMLPrint_Select"),
+					Query:               aws.String("SELECT name, count FROM t WHERE name <> 'a&b'"),
+					Statistics:          testhelper.CreateStats(1234, 56789),
+					ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+				},
+				cols: []*athena.ColumnInfo{
+					{Name: aws.String("name"), Type: aws.String("varchar")},
+					{Name: aws.String("count"), Type: aws.String("bigint")},
+				},
+				data: [][]string{
+					{"name", "count"},
+					{"<b>bold</b>", "42"},
+					{"line1\nline2", ""},
+				},
+				nulls: [][2]int{{2, 1}},
+			},
+			want: `<pre><code>SELECT name, count FROM t WHERE name &lt;&gt; &#39;a&amp;b&#39;;</code></pre>
+<table>
+<thead>
+<tr><th>name</th><th>count</th></tr>
+</thead>
+<tbody>
+<tr><td>&lt;b&gt;bold&lt;/b&gt;</td><td align="right">42</td></tr>
+<tr><td>line1<br>line2</td><td align="right">NULL</td></tr>
+</tbody>
+</table>
+<p>Run time: 1.23 seconds | Data scanned: 56.79 KB<br>
+Location: s3://samplebucket/</p>
+`,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestHTMLPrint_ShowDatabases"),
+					Query:               aws.String("SHOW DATABASES"),
+					Statistics:          testhelper.CreateStats(123, 0),
+					ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+				},
+				data: [][]string{{"cloudfront_logs"}, {"elb_logs"}},
+			},
+			want: `<pre><code>SHOW DATABASES;</code></pre>
+<table>
+<tbody>
+<tr><td>cloudfront_logs</td></tr>
+<tr><td>elb_logs</td></tr>
+</tbody>
+</table>
+<p>Run time: 0.12 seconds | Data scanned: 0 B<br>
+Location: s3://samplebucket/</p>
+`,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestHTMLPrint_CreateDatabase"),
+					Query:               aws.String("CREATE DATABASE test"),
+					Statistics:          testhelper.CreateStats(1234, 0),
+					ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+				},
+				data: [][]string{},
+			},
+			want: `<pre><code>CREATE DATABASE test;</code></pre>
+<p>(No output)</p>
+<p>Run time: 1.23 seconds | Data scanned: 0 B<br>
+Location: s3://samplebucket/</p>
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		New(&out, FormatHTML).Print(tt.r)
+
+		assert.Equal(t, tt.want, out.String(), "Result: %#v", tt.r)
+	}
+}
@@ -0,0 +1,264 @@
+package print
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/exec"
+)
+
+// queryDocument is a JSON document which represents a query execution and its results.
+type queryDocument struct {
+	QueryExecutionID   string           `json:"QueryExecutionId"`
+	Query              string           `json:"Query"`
+	Database           string           `json:"Database,omitempty"`
+	State              string           `json:"State,omitempty"`
+	StateChangeReason  string           `json:"StateChangeReason,omitempty"`
+	SubmissionDateTime *time.Time       `json:"SubmissionDateTime,omitempty"`
+	CompletionDateTime *time.Time       `json:"CompletionDateTime,omitempty"`
+	Statistics         *statsDocument   `json:"Statistics,omitempty"`
+	OutputLocation     string           `json:"OutputLocation,omitempty"`
+	AttemptIDs         []string         `json:"AttemptIds,omitempty"`
+	Columns            []columnDocument `json:"Columns"`
+	// Rows must be the last field, since the rows are written after the other fields one by one.
+	Rows json.RawMessage `json:"Rows"`
+}
+
+// statsDocument is a JSON document which represents statistics of a query execution.
+type statsDocument struct {
+	EngineExecutionTimeInMillis int64 `json:"EngineExecutionTimeInMillis"`
+	DataScannedInBytes          int64 `json:"DataScannedInBytes"`
+}
+
+// columnDocument is a JSON document which represents a column in results.
+type columnDocument struct {
+	Name string `json:"Name"`
+	Type string `json:"Type"`
+}
+
+// record represents a row of results which is encoded as a JSON object keeping column order.
+type record struct {
+	names  []string
+	values []interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range r.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonPrinter prints each result as a single JSON document.
+// The rows in the document are written one by one as they are read.
+type jsonPrinter struct {
+	out io.Writer
+}
+
+func (p *jsonPrinter) Print(r Result) {
+	info := r.Info()
+	if info == nil || (!hasRows(r) && !incomplete(info)) {
+		return
+	}
+
+	doc := &queryDocument{
+		QueryExecutionID: aws.StringValue(info.QueryExecutionId),
+		Query:            aws.StringValue(info.Query),
+		Rows:             json.RawMessage("[]"),
+	}
+	if ctx := info.QueryExecutionContext; ctx != nil {
+		doc.Database = aws.StringValue(ctx.Database)
+	}
+	if st := info.Status; st != nil {
+		doc.State = aws.StringValue(st.State)
+		doc.StateChangeReason = aws.StringValue(st.StateChangeReason)
+		doc.SubmissionDateTime = st.SubmissionDateTime
+		doc.CompletionDateTime = st.CompletionDateTime
+	}
+	if stats := info.Statistics; stats != nil {
+		doc.Statistics = &statsDocument{
+			EngineExecutionTimeInMillis: aws.Int64Value(stats.EngineExecutionTimeInMillis),
+			DataScannedInBytes:          aws.Int64Value(stats.DataScannedInBytes),
+		}
+	}
+	if rc := info.ResultConfiguration; rc != nil {
+		doc.OutputLocation = aws.StringValue(rc.OutputLocation)
+	}
+	if at, ok := r.(attempter); ok && len(at.AttemptIDs()) > 1 {
+		doc.AttemptIDs = at.AttemptIDs()
+	}
+
+	w := bufio.NewWriter(p.out)
+	defer w.Flush()
+	rc := newRecordConverter(r.ColumnInfo())
+	n := 0
+	started := false
+	// start writes the document up to the opening bracket of the rows, once the columns are known
+	start := func() bool {
+		started = true
+		doc.Columns = make([]columnDocument, len(rc.cols))
+		for i, col := range rc.cols {
+			doc.Columns[i] = columnDocument{Name: col.Name, Type: col.Type}
+		}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			log.Println("Error encoding results into JSON:", err)
+			return false
+		}
+		w.Write(b[:len(b)-len("]}")])
+		return true
+	}
+
+	ok := true
+	eachRow(r, func(row []string, nulls []bool) bool {
+		rec, isRecord := rc.convert(row, nulls)
+		if !isRecord {
+			return true
+		}
+		if !started && !start() {
+			ok = false
+			return false
+		}
+		b, err := json.Marshal(rec)
+		if err != nil {
+			log.Println("Error encoding a row into JSON:", err)
+			ok = false
+			return false
+		}
+		if n > 0 {
+			w.WriteByte(',')
+		}
+		w.Write(b)
+		n++
+		return true
+	})
+	if !ok || (!started && !start()) {
+		return
+	}
+	w.WriteString("]}\n")
+}
+
+// ndjsonPrinter prints each row of results as a JSON object per line as it is read.
+type ndjsonPrinter struct {
+	out io.Writer
+}
+
+func (p *ndjsonPrinter) Print(r Result) {
+	info := r.Info()
+	if info == nil || !hasRows(r) {
+		return
+	}
+
+	w := bufio.NewWriter(p.out)
+	defer w.Flush()
+	enc := json.NewEncoder(w)
+	rc := newRecordConverter(r.ColumnInfo())
+	eachRow(r, func(row []string, nulls []bool) bool {
+		rec, ok := rc.convert(row, nulls)
+		if !ok {
+			return true
+		}
+		if err := enc.Encode(rec); err != nil {
+			log.Println("Error encoding a row into JSON:", err)
+			return false
+		}
+		return true
+	})
+}
+
+// recordConverter converts rows to records whose values are typed according to the column types.
+type recordConverter struct {
+	cols  []exec.Column
+	names []string
+	first bool
+}
+
+// newRecordConverter creates a new recordConverter for the columns of infos.
+// If infos is empty, the columns are named after the first row.
+func newRecordConverter(infos []*athena.ColumnInfo) *recordConverter {
+	rc := &recordConverter{first: true}
+	if len(infos) > 0 {
+		rc.setColumns(columns(infos, nil))
+	}
+	return rc
+}
+
+func (rc *recordConverter) setColumns(cols []exec.Column) {
+	rc.cols = cols
+	rc.names = make([]string, len(cols))
+	for i, col := range cols {
+		rc.names[i] = col.Name
+	}
+}
+
+// convert converts row to a record. It returns false if row is the header, which is not a record.
+func (rc *recordConverter) convert(row []string, nulls []bool) (record, bool) {
+	if rc.first {
+		rc.first = false
+		if rc.cols == nil {
+			rc.setColumns(columns(nil, [][]string{row}))
+		}
+		if exec.IsHeader(rc.cols, row) {
+			return record{}, false
+		}
+	}
+
+	values := make([]interface{}, len(rc.cols))
+	for j, col := range rc.cols {
+		if j < len(row) && (j >= len(nulls) || !nulls[j]) {
+			values[j] = typedValue(col.Type, row[j])
+		}
+	}
+	return record{names: rc.names, values: values}, true
+}
+
+// typedValue converts a value v to a JSON value corresponding to the Athena column type typ.
+// If v cannot be converted to the type, it is returned as a string.
+func typedValue(typ, v string) interface{} {
+	switch exec.BaseType(typ) {
+	case "tinyint", "smallint", "integer", "int", "bigint":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "float", "real", "double":
+		// NaN and Infinity cannot be represented in JSON
+		if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	case "decimal":
+		// Keep the precision of decimal values as is
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return json.Number(v)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
@@ -0,0 +1,137 @@
+package print
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+var typedColumns = []*athena.ColumnInfo{
+	{Name: aws.String("name"), Type: aws.String("varchar")},
+	{Name: aws.String("count"), Type: aws.String("bigint")},
+	{Name: aws.String("ratio"), Type: aws.String("double")},
+	{Name: aws.String("price"), Type: aws.String("decimal")},
+	{Name: aws.String("active"), Type: aws.String("boolean")},
+	{Name: aws.String("day"), Type: aws.String("date")},
+}
+
+var typedRows = [][]string{
+	{"name", "count", "ratio", "price", "active", "day"},
+	{"foo", "42", "0.5", "12.30", "true", "2017-07-01"},
+	{"", "", "NaN", "", "false", ""},
+}
+
+var typedNulls = [][2]int{{2, 1}, {2, 3}, {2, 5}}
+
+const (
+	selectJSON = `{"QueryExecutionId":"TestJSONPrint_Select","Query":"SELECT * FROM typed","State":"SUCCEEDED",` +
+		`"Statistics":{"EngineExecutionTimeInMillis":1234,"DataScannedInBytes":56789},` +
+		`"OutputLocation":"s3://samplebucket/",` +
+		`"Columns":[{"Name":"name","Type":"varchar"},{"Name":"count","Type":"bigint"},{"Name":"ratio","Type":"double"},` +
+		`{"Name":"price","Type":"decimal"},{"Name":"active","Type":"boolean"},{"Name":"day","Type":"date"}],` +
+		`"Rows":[{"name":"foo","count":42,"ratio":0.5,"price":12.30,"active":true,"day":"2017-07-01"},` +
+		`{"name":"","count":null,"ratio":"NaN","price":null,"active":false,"day":null}]}
+`
+
+	showDatabasesJSON = `{"QueryExecutionId":"TestJSONPrint_ShowDatabases","Query":"SHOW DATABASES",` +
+		`"Statistics":{"EngineExecutionTimeInMillis":123,"DataScannedInBytes":0},` +
+		`"OutputLocation":"s3://samplebucket/",` +
+		`"Columns":[{"Name":"_col0","Type":""}],"Rows":[{"_col0":"cloudfront_logs"},{"_col0":"sampledb"}]}
+`
+
+	failedJSON = `{"QueryExecutionId":"TestJSONPrint_Failed","Query":"SELECT * FROM missing","State":"FAILED",` +
+		`"StateChangeReason":"Table not found","Columns":[],"Rows":[]}
+`
+
+	selectNDJSON = `{"name":"foo","count":42,"ratio":0.5,"price":12.30,"active":true,"day":"2017-07-01"}
+{"name":"","count":null,"ratio":"NaN","price":null,"active":false,"day":null}
+`
+)
+
+func TestJSONPrinter(t *testing.T) {
+	tests := []struct {
+		r    Result
+		want string
+	}{
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestJSONPrint_Select"),
+					Query:               aws.String("SELECT * FROM typed"),
+					Status:              &athena.QueryExecutionStatus{State: aws.String("SUCCEEDED")},
+					Statistics:          testhelper.CreateStats(1234, 56789),
+					ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+				},
+				cols:  typedColumns,
+				data:  typedRows,
+				nulls: typedNulls,
+			},
+			want: selectJSON,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestJSONPrint_ShowDatabases"),
+					Query:               aws.String("SHOW DATABASES"),
+					Statistics:          testhelper.CreateStats(123, 0),
+					ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+				},
+				data: [][]string{{"cloudfront_logs"}, {"sampledb"}},
+			},
+			want: showDatabasesJSON,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId: aws.String("TestJSONPrint_Failed"),
+					Query:            aws.String("SELECT * FROM missing"),
+					Status: &athena.QueryExecutionStatus{
+						State:             aws.String(athena.QueryExecutionStateFailed),
+						StateChangeReason: aws.String("Table not found"),
+					},
+				},
+			},
+			want: failedJSON,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId: aws.String("TestJSONPrint_Running"),
+					Query:            aws.String("SELECT * FROM running"),
+					Status:           &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateRunning)},
+				},
+			},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		p := New(&out, FormatJSON)
+		p.Print(tt.r)
+
+		assert.Equal(t, tt.want, out.String(), "Result: %#v", tt.r)
+	}
+}
+
+func TestNDJSONPrinter(t *testing.T) {
+	r := &stubResult{
+		info: &athena.QueryExecution{
+			QueryExecutionId: aws.String("TestNDJSONPrint_Select"),
+			Query:            aws.String("SELECT * FROM typed"),
+		},
+		cols:  typedColumns,
+		data:  typedRows,
+		nulls: typedNulls,
+	}
+
+	var out bytes.Buffer
+	p := New(&out, FormatNDJSON)
+	p.Print(r)
+
+	assert.Equal(t, selectNDJSON, out.String(), "Result: %#v", r)
+}
@@ -0,0 +1,99 @@
+package print
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/skatsuta/athenai/exec"
+)
+
+// markdownCellEscaper escapes characters which break cells of tables in GitHub Flavored Markdown.
+var markdownCellEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// markdownTextEscaper escapes characters which have special meanings in Markdown text.
+var markdownTextEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`)
+
+// markdownStyle prints the query in an SQL code block, and the other information as paragraphs
+// whose lines are separated by `<br>`.
+var markdownStyle = &style{
+	header: func(w io.Writer, query string) {
+		// The fence must be longer than any backticks in the query
+		fence := "```"
+		for strings.Contains(query, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(w, "%ssql\n%s;\n%s\n\n", fence, query, fence)
+	},
+	text: func(w io.Writer, text string) {
+		lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+		for i, line := range lines {
+			lines[i] = markdownTextEscaper.Replace(line)
+		}
+		fmt.Fprintf(w, "\n%s\n", strings.Join(lines, "<br>\n"))
+	},
+}
+
+// printMarkdown prints the results as a table of GitHub Flavored Markdown, writing each row as
+// it is read. Since Markdown tables need a header, the columns are named `_col0`, `_col1`, ...
+// if their names are unknown. NULL is rendered as `NULL`, and numeric columns are aligned to
+// the right.
+func printMarkdown(out io.Writer, r Result) int {
+	infos := r.ColumnInfo()
+	n := 0
+	eachRow(r, func(row []string, nulls []bool) bool {
+		if n == 0 {
+			cols := columns(infos, [][]string{row})
+			writeMarkdownRow(out, columnNames(cols))
+			writeMarkdownDelimiter(out, cols, len(infos) > 0)
+			n++
+			if exec.IsHeader(cols, row) {
+				return true
+			}
+		}
+		writeMarkdownRow(out, replaceNulls(row, nulls, nullString))
+		n++
+		return true
+	})
+	return n
+}
+
+// printMarkdownRows prints rows whose first row is the header as a table of GitHub Flavored Markdown.
+func printMarkdownRows(out io.Writer, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	writeMarkdownRow(out, rows[0])
+	writeMarkdownDelimiter(out, columns(nil, rows[:1]), false)
+	for _, row := range rows[1:] {
+		writeMarkdownRow(out, row)
+	}
+}
+
+func writeMarkdownRow(w io.Writer, row []string) {
+	var buf bytes.Buffer
+	buf.WriteString("|")
+	for _, v := range row {
+		buf.WriteString(" " + markdownCellEscaper.Replace(v) + " |")
+	}
+	buf.WriteString("\n")
+	w.Write(buf.Bytes())
+}
+
+// writeMarkdownDelimiter writes the row which delimits the header from the other rows.
+// If align is true, numeric columns are aligned to the right.
+func writeMarkdownDelimiter(w io.Writer, cols []exec.Column, align bool) {
+	var buf bytes.Buffer
+	buf.WriteString("|")
+	for _, col := range cols {
+		if align && isNumeric(col) {
+			buf.WriteString(" ---: |")
+		} else {
+			buf.WriteString(" --- |")
+		}
+	}
+	buf.WriteString("\n")
+	w.Write(buf.Bytes())
+}
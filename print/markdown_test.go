@@ -0,0 +1,111 @@
+package print
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownPrinter(t *testing.T) {
+	tests := []struct {
+		r    Result
+		want string
+	}{
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestMarkdownPrint_Select"),
+					Query:               aws.String("SELECT * FROM typed"),
+					Statistics:          testhelper.CreateStats(1234, 56789),
+					ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+				},
+				cols:  typedColumns,
+				data:  append(typedRows, []string{"a|b\nc", "1", "1.0", "1.00", "true", "2017-07-02"}),
+				nulls: typedNulls,
+			},
+			want: "```sql\n" + `SELECT * FROM typed;
+` + "```" + `
+
+| name | count | ratio | price | active | day |
+| --- | ---: | ---: | ---: | --- | --- |
+| foo | 42 | 0.5 | 12.30 | true | 2017-07-01 |
+|  | NULL | NaN | NULL | false | NULL |
+| a\|b<br>c | 1 | 1.0 | 1.00 | true | 2017-07-02 |
+
+Run time: 1.23 seconds | Data scanned: 56.79 KB<br>
+Location: s3://samplebucket/
+`,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestMarkdownPrint_ShowDatabases"),
+					Query:               aws.String("SHOW DATABASES LIKE '`*_logs`'"),
+					Statistics:          testhelper.CreateStats(123, 0),
+					ResultConfiguration: testhelper.CreateResultConfig("s3://sample_bucket/"),
+				},
+				data: [][]string{{"cloudfront_logs"}, {"elb_logs"}},
+			},
+			want: "```sql\n" + "SHOW DATABASES LIKE '`*_logs`';\n" + "```" + `
+
+| _col0 |
+| --- |
+| cloudfront_logs |
+| elb_logs |
+
+Run time: 0.12 seconds | Data scanned: 0 B<br>
+Location: s3://sample\_bucket/
+`,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestMarkdownPrint_CreateDatabase"),
+					Query:               aws.String("CREATE DATABASE test"),
+					Statistics:          testhelper.CreateStats(1234, 0),
+					ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+				},
+				data: [][]string{},
+			},
+			want: "```sql\n" + `CREATE DATABASE test;
+` + "```" + `
+
+
+(No output)
+
+Run time: 1.23 seconds | Data scanned: 0 B<br>
+Location: s3://samplebucket/
+`,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId: aws.String("TestMarkdownPrint_Failed"),
+					Query:            aws.String("SELECT ```weird```"),
+					Status: &athena.QueryExecutionStatus{
+						State:             aws.String(athena.QueryExecutionStateFailed),
+						StateChangeReason: aws.String("SYNTAX_ERROR: line 1:8: Column '<weird>' cannot be resolved"),
+					},
+				},
+			},
+			want: "````sql\n" + "SELECT ```weird```;\n" + "````" + `
+
+
+State: FAILED<br>
+Reason: SYNTAX\_ERROR: line 1:8: Column '\<weird\>' cannot be resolved<br>
+QueryExecutionId: TestMarkdownPrint\_Failed
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		New(&out, FormatMarkdown).Print(tt.r)
+
+		assert.Equal(t, tt.want, out.String(), "Result: %#v", tt.r)
+	}
+}
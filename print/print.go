@@ -1,23 +1,115 @@
 package print
 
 import (
+	"bytes"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"log"
 	"math"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/mattn/go-runewidth"
 	"github.com/olekukonko/tablewriter"
+	"github.com/skatsuta/athenai/exec"
 )
 
-const noOutput = "(No output)"
+const (
+	noOutput = "(No output)"
+
+	// nullString is a string to represent NULL in tabular form.
+	nullString = "NULL"
+
+	// maxPreviewRows is the maximum number of rows printed in tabular form, which needs all the
+	// rows in memory to align them.
+	maxPreviewRows = 1000
+)
+
+// Formatting styles of results.
+const (
+	FormatTable    = "table"
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatNDJSON   = "ndjson"
+	FormatVertical = "vertical"
+	FormatAuto     = "auto" // Table, or vertical if the table is wider than the terminal
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatTSV      = "tsv"
+)
+
+// Formats are all the available formatting styles.
+var Formats = []string{FormatTable, FormatCSV, FormatJSON, FormatNDJSON, FormatVertical, FormatAuto,
+	FormatMarkdown, FormatHTML, FormatTSV}
+
+// IsValid returns true if format is one of Formats, otherwise false.
+func IsValid(format string) bool {
+	for _, f := range Formats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
 
 // Result represents an interface that holds information of a query execution and its results.
 type Result interface {
 	Info() *athena.QueryExecution
+	ColumnInfo() []*athena.ColumnInfo
 	Rows() [][]string
+	IsNull(i, j int) bool
+}
+
+// streamer is implemented by results whose rows can be read one by one, which lets printers
+// write them as they arrive instead of holding all of them in memory.
+type streamer interface {
+	// HasRows returns true if the rows have been fetched, even if there are no rows.
+	HasRows() bool
+	// EachRow calls fn with each row and whether each value in it is NULL, in order until fn
+	// returns false. It returns an error if reading the rows has failed.
+	EachRow(fn func(row []string, nulls []bool) bool) error
+}
+
+// hasRows returns true if r has rows to print, even if there are no rows.
+func hasRows(r Result) bool {
+	if s, ok := r.(streamer); ok {
+		return s.HasRows()
+	}
+	return r.Rows() != nil
+}
+
+// eachRow calls fn with each row of r and whether each value in it is NULL, in order until fn
+// returns false.
+func eachRow(r Result, fn func(row []string, nulls []bool) bool) {
+	if s, ok := r.(streamer); ok {
+		if err := s.EachRow(fn); err != nil {
+			log.Println("Error reading rows:", err)
+		}
+		return
+	}
+
+	for i, row := range r.Rows() {
+		nulls := make([]bool, len(row))
+		for j := range row {
+			nulls[j] = r.IsNull(i, j)
+		}
+		if !fn(row, nulls) {
+			return
+		}
+	}
+}
+
+// retrier is implemented by results which know the number of retried API calls.
+type retrier interface {
+	Retries() int
+}
+
+// attempter is implemented by results which know the IDs of all the query executions submitted
+// for the query.
+type attempter interface {
+	AttemptIDs() []string
 }
 
 // Printer represents an interface that prints a result.
@@ -25,60 +117,284 @@ type Printer interface {
 	Print(Result)
 }
 
+// Option is an option of Printer.
+type Option func(*printer)
+
+// WithoutFooter makes Printer omit footers, which show statistics of query executions.
+func WithoutFooter() Option {
+	return func(p *printer) {
+		p.noFooter = true
+	}
+}
+
+// style renders the query and the other information around results in a text format.
+type style struct {
+	header func(w io.Writer, query string)
+	text   func(w io.Writer, text string) // Renders lines of plain text such as footers
+}
+
+// plainStyle prints the query and the other information as they are.
+var plainStyle = &style{
+	header: func(w io.Writer, query string) {
+		fmt.Fprintf(w, "Query: %s;\n", query)
+	},
+	text: func(w io.Writer, text string) {
+		io.WriteString(w, text)
+	},
+}
+
 // printer is a filter that formats its input as a table in the output.
 type printer struct {
-	out io.Writer
-	fn  func(w io.Writer, rows [][]string)
+	out      io.Writer
+	fn       func(w io.Writer, r Result) int // Returns the number of printed rows
+	style    *style
+	noFooter bool
+	price    float64    // Price per TB scanned to estimate costs. Zero means no costs are shown
+	width    func() int // Width of the terminal for auto format
+}
+
+// WithPrice makes a Printer show the estimated cost of each query execution in its footer,
+// based on pricePerTB US dollars per TB scanned.
+func WithPrice(pricePerTB float64) Option {
+	return func(p *printer) {
+		p.price = pricePerTB
+	}
+}
+
+// WithWidth makes a Printer in auto format print results as vertical records if their tables
+// would be wider than the width returned by width, which is called for each result.
+// Without this option, auto format always prints tables.
+func WithWidth(width func() int) Option {
+	return func(p *printer) {
+		p.width = width
+	}
 }
 
 // New returns a new Printer which prints to out corresponding to format.
-func New(out io.Writer, format string) Printer {
-	fn := printTable
-	if format == "csv" {
-		fn = printCSV
+// Options are applied only to text formats.
+func New(out io.Writer, format string, opts ...Option) Printer {
+	switch format {
+	case FormatJSON:
+		return &jsonPrinter{out: out}
+	case FormatNDJSON:
+		return &ndjsonPrinter{out: out}
 	}
 
-	return &printer{
-		out: out,
-		fn:  fn,
+	p := &printer{out: out, style: plainStyle}
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	switch format {
+	case FormatCSV:
+		p.fn = printCSV
+	case FormatTSV:
+		p.fn = printTSV
+	case FormatMarkdown:
+		p.fn, p.style = printMarkdown, markdownStyle
+	case FormatHTML:
+		p.fn, p.style = printHTML, htmlStyle
+	case FormatVertical:
+		p.fn = printVertical
+	case FormatAuto:
+		p.fn = func(w io.Writer, r Result) int {
+			width := 0
+			if p.width != nil {
+				width = p.width()
+			}
+			return printAuto(w, r, width)
+		}
+	default:
+		p.fn = printTable
+	}
+	return p
 }
 
 func (p *printer) Print(r Result) {
 	info := r.Info()
-	rows := r.Rows()
-	if info == nil || rows == nil {
+	if info == nil {
+		return
+	}
+	query := aws.StringValue(info.Query)
+	if !hasRows(r) {
+		if incomplete(info) {
+			p.style.header(p.out, query)
+			p.printText(func(w io.Writer) { printStatus(w, info) })
+		}
 		return
 	}
 
-	printHeader(p.out, info)
+	p.style.header(p.out, query)
 
-	if len(rows) == 0 {
-		fmt.Fprintln(p.out, noOutput)
-	} else {
-		p.fn(p.out, rows)
+	if p.fn(p.out, r) == 0 {
+		p.style.text(p.out, noOutput+"\n")
+	}
+
+	if p.noFooter {
+		return
 	}
 
-	printFooter(p.out, info)
+	retries := 0
+	if rt, ok := r.(retrier); ok {
+		retries = rt.Retries()
+	}
+	p.printText(func(w io.Writer) {
+		printFooter(w, info, retries, p.price)
+		if at, ok := r.(attempter); ok {
+			printAttempts(w, at.AttemptIDs())
+		}
+	})
+}
+
+// printText prints the text written by fn in the style of p.
+func (p *printer) printText(fn func(w io.Writer)) {
+	var buf bytes.Buffer
+	fn(&buf)
+	p.style.text(p.out, buf.String())
+}
+
+// IsText returns true if format is a human-readable text format whose results are separated by
+// blank lines, otherwise false.
+func IsText(format string) bool {
+	return format != FormatJSON && format != FormatNDJSON
 }
 
 // printTable prints the results in tabular form.
-func printTable(out io.Writer, rows [][]string) {
+// NULL is rendered as `NULL`, and values in numeric columns are aligned to the right.
+// Since all the rows to print are held in memory to align them, only the first maxPreviewRows
+// rows are printed, followed by a note if there are more.
+func printTable(out io.Writer, r Result) int {
+	cols, rows, more := tableRows(r)
+	if len(rows) == 0 {
+		return 0
+	}
+
+	renderTable(out, cols, len(r.ColumnInfo()) > 0, rows)
+	printPreviewNote(out, more)
+	return len(rows)
+}
+
+// tableRows reads the rows of r to print in tabular form with NULL rendered as `NULL`, up to
+// maxPreviewRows rows excluding the header. more is true if r has more rows than them.
+func tableRows(r Result) (cols []exec.Column, rows [][]string, more bool) {
+	cols = columns(r.ColumnInfo(), nil)
+	limit := maxPreviewRows
+	eachRow(r, func(row []string, nulls []bool) bool {
+		if len(rows) == 0 && exec.IsHeader(cols, row) {
+			limit++ // The header is not counted
+		} else if len(rows) >= limit {
+			more = true
+			return false
+		}
+		rows = append(rows, replaceNulls(row, nulls, nullString))
+		return true
+	})
+	return cols, rows, more
+}
+
+// renderTable renders rows in tabular form. If align is true, the values are aligned according
+// to the types of cols. rows are not modified.
+func renderTable(out io.Writer, cols []exec.Column, align bool, rows [][]string) {
+	if align {
+		aligned := make([][]string, len(rows))
+		for i, row := range rows {
+			aligned[i] = append([]string(nil), row...)
+		}
+		alignColumns(aligned, cols)
+		rows = aligned
+	}
+
 	tw := tablewriter.NewWriter(out)
 	tw.AppendBulk(rows)
 	tw.Render()
 }
 
-// printCSV prints the results in CSV format.
-func printCSV(out io.Writer, rows [][]string) {
-	w := csv.NewWriter(out)
-	w.WriteAll(rows)
-	w.Flush()
+// printPreviewNote prints a note that only the first maxPreviewRows rows have been printed if
+// more is true.
+func printPreviewNote(out io.Writer, more bool) {
+	if more {
+		fmt.Fprintf(out, "(Showing only the first %d rows. Use csv or ndjson format to print all the rows)\n", maxPreviewRows)
+	}
+}
+
+// replaceNulls returns a copy of row whose NULL values are replaced with null.
+func replaceNulls(row []string, nulls []bool, null string) []string {
+	replaced := make([]string, len(row))
+	for j, v := range row {
+		if j < len(nulls) && nulls[j] {
+			v = null
+		}
+		replaced[j] = v
+	}
+	return replaced
+}
+
+// alignColumns pads every value in rows to the width of its column, to the left for numeric
+// columns and to the right for the others.
+func alignColumns(rows [][]string, cols []exec.Column) {
+	widths := make([]int, len(cols))
+	for _, row := range rows {
+		for j := 0; j < len(row) && j < len(cols); j++ {
+			if w := runewidth.StringWidth(row[j]); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+
+	for _, row := range rows {
+		for j := 0; j < len(row) && j < len(cols); j++ {
+			pad := strings.Repeat(" ", widths[j]-runewidth.StringWidth(row[j]))
+			if isNumeric(cols[j]) {
+				row[j] = pad + row[j]
+			} else {
+				row[j] += pad
+			}
+		}
+	}
+}
+
+// tsvEscaper escapes characters which cannot be in values of TSV.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// printTSV prints the results in TSV format, writing each row as it is read.
+// Backslashes, tabs and newlines in values are escaped as `\\`, `\t` and `\n`.
+func printTSV(out io.Writer, r Result) int {
+	n := 0
+	eachRow(r, func(row []string, _ []bool) bool {
+		if err := writeTSVRow(out, row); err != nil {
+			log.Println("Error writing a row in TSV:", err)
+			return false
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+// writeTSVRow writes row as a line of TSV, escaping tabs, newlines and backslashes in values.
+func writeTSVRow(w io.Writer, row []string) error {
+	escaped := make([]string, len(row))
+	for j, v := range row {
+		escaped[j] = tsvEscaper.Replace(v)
+	}
+	_, err := io.WriteString(w, strings.Join(escaped, "\t")+"\n")
+	return err
 }
 
-// printHeader prints query information.
-func printHeader(w io.Writer, info *athena.QueryExecution) {
-	fmt.Fprintf(w, "Query: %s;\n", aws.StringValue(info.Query))
+// printCSV prints the results in CSV format, writing each row as it is read.
+func printCSV(out io.Writer, r Result) int {
+	w := csv.NewWriter(out)
+	n := 0
+	eachRow(r, func(row []string, _ []bool) bool {
+		if err := w.Write(row); err != nil {
+			log.Println("Error writing a row in CSV:", err)
+			return false
+		}
+		n++
+		return true
+	})
+	w.Flush()
+	return n
 }
 
 func logn(n, b float64) float64 {
@@ -104,8 +420,9 @@ func FormatBytes(s int64) string {
 	return humanateBytes(s, 1000, sizes)
 }
 
-// printFooter prints a footer for a query execution.
-func printFooter(w io.Writer, info *athena.QueryExecution) {
+// printFooter prints a footer for a query execution. The number of retries is printed only if
+// some API calls have been retried, and the estimated cost only if price is positive.
+func printFooter(w io.Writer, info *athena.QueryExecution, retries int, price float64) {
 	stats := info.Statistics
 	runTimeMs := aws.Int64Value(stats.EngineExecutionTimeInMillis)
 	scannedBytes := aws.Int64Value(stats.DataScannedInBytes)
@@ -113,6 +430,45 @@ func printFooter(w io.Writer, info *athena.QueryExecution) {
 	log.Printf("EngineExecutionTimeInMillis: %d milliseconds\n", runTimeMs)
 	log.Printf("DataScannedInBytes: %d bytes\n", scannedBytes)
 	log.Printf("OutputLocation: %s\n", loc)
-	fmt.Fprintf(w, "Run time: %.2f seconds | Data scanned: %s\nLocation: %s\n",
-		float64(runTimeMs)/1000, FormatBytes(scannedBytes), loc)
+	fmt.Fprintf(w, "Run time: %.2f seconds | Data scanned: %s", float64(runTimeMs)/1000, FormatBytes(scannedBytes))
+	if price > 0 {
+		fmt.Fprintf(w, " | Cost: %s", FormatCost(Cost(scannedBytes, price)))
+	}
+	if retries > 0 {
+		fmt.Fprintf(w, " | Retries: %d", retries)
+	}
+	fmt.Fprintf(w, "\nLocation: %s\n", loc)
+}
+
+// incomplete returns true if info shows the query execution has failed or been cancelled,
+// which has no results.
+func incomplete(info *athena.QueryExecution) bool {
+	if info.Status == nil {
+		return false
+	}
+	state := aws.StringValue(info.Status.State)
+	return state == athena.QueryExecutionStateFailed || state == athena.QueryExecutionStateCancelled
+}
+
+// printStatus prints the status of a query execution which has no results.
+func printStatus(w io.Writer, info *athena.QueryExecution) {
+	st := info.Status
+	fmt.Fprintf(w, "State: %s\n", aws.StringValue(st.State))
+	if reason := aws.StringValue(st.StateChangeReason); reason != "" {
+		fmt.Fprintf(w, "Reason: %s\n", reason)
+	}
+	if st.SubmissionDateTime != nil {
+		fmt.Fprintf(w, "Submitted: %s\n", st.SubmissionDateTime)
+	}
+	if st.CompletionDateTime != nil {
+		fmt.Fprintf(w, "Completed: %s\n", st.CompletionDateTime)
+	}
+	fmt.Fprintf(w, "QueryExecutionId: %s\n", aws.StringValue(info.QueryExecutionId))
+}
+
+// printAttempts prints the IDs of query executions if the query has been resubmitted.
+func printAttempts(w io.Writer, ids []string) {
+	if len(ids) > 1 {
+		fmt.Fprintf(w, "Attempts: %s\n", strings.Join(ids, ", "))
+	}
 }
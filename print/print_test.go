@@ -2,7 +2,9 @@ package print
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/athena"
@@ -35,6 +37,8 @@ func TestFormatBytes(t *testing.T) {
 func TestPrintFooter(t *testing.T) {
 	tests := []struct {
 		info     *athena.QueryExecution
+		retries  int
+		price    float64
 		expected string
 	}{
 		{
@@ -51,13 +55,48 @@ func TestPrintFooter(t *testing.T) {
 			},
 			expected: "Run time: 0.01 seconds | Data scanned: 10 B\nLocation: s3://samplebucket/\n",
 		},
+		{
+			info: &athena.QueryExecution{
+				Statistics:          testhelper.CreateStats(1234, 987654321),
+				ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+			},
+			retries:  2,
+			expected: "Run time: 1.23 seconds | Data scanned: 987.65 MB | Retries: 2\nLocation: s3://samplebucket/\n",
+		},
+		{
+			info: &athena.QueryExecution{
+				Statistics:          testhelper.CreateStats(1234, 987654321),
+				ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+			},
+			retries:  1,
+			price:    5,
+			expected: "Run time: 1.23 seconds | Data scanned: 987.65 MB | Cost: $0.0045 | Retries: 1\nLocation: s3://samplebucket/\n",
+		},
 	}
 
 	for _, tt := range tests {
 		var out bytes.Buffer
-		printFooter(&out, tt.info)
+		printFooter(&out, tt.info, tt.retries, tt.price)
 
-		assert.Equal(t, tt.expected, out.String(), "Info: %#v", tt.info)
+		assert.Equal(t, tt.expected, out.String(), "Info: %#v, Retries: %d, Price: %f", tt.info, tt.retries, tt.price)
+	}
+}
+
+func TestPrintAttempts(t *testing.T) {
+	tests := []struct {
+		ids      []string
+		expected string
+	}{
+		{ids: nil, expected: ""},
+		{ids: []string{"id1"}, expected: ""},
+		{ids: []string{"id1", "id2"}, expected: "Attempts: id1, id2\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		printAttempts(&out, tt.ids)
+
+		assert.Equal(t, tt.expected, out.String(), "IDs: %#v", tt.ids)
 	}
 }
 
@@ -95,18 +134,145 @@ Location: s3://samplebucket/
 
 // stubResult is a mock struct which implements Result interface for testing.
 type stubResult struct {
-	info *athena.QueryExecution
-	data [][]string
+	info  *athena.QueryExecution
+	cols  []*athena.ColumnInfo
+	data  [][]string
+	nulls [][2]int // Positions of NULL values
 }
 
 func (m *stubResult) Info() *athena.QueryExecution {
 	return m.info
 }
 
+func (m *stubResult) ColumnInfo() []*athena.ColumnInfo {
+	return m.cols
+}
+
 func (m *stubResult) Rows() [][]string {
 	return m.data
 }
 
+func (m *stubResult) IsNull(i, j int) bool {
+	for _, pos := range m.nulls {
+		if pos == [2]int{i, j} {
+			return true
+		}
+	}
+	return false
+}
+
+// stubStreamResult is a stubResult whose rows are read one by one with EachRow.
+type stubStreamResult struct {
+	*stubResult
+	err error // Returned by EachRow after reading all the rows
+}
+
+func (m *stubStreamResult) Rows() [][]string {
+	panic("Rows should not be called for streamed results")
+}
+
+func (m *stubStreamResult) HasRows() bool {
+	return m.data != nil
+}
+
+func (m *stubStreamResult) EachRow(fn func(row []string, nulls []bool) bool) error {
+	for i, row := range m.data {
+		nulls := make([]bool, len(row))
+		for j := range row {
+			nulls[j] = m.IsNull(i, j)
+		}
+		if !fn(row, nulls) {
+			return nil
+		}
+	}
+	return m.err
+}
+
+func TestPrinterStreamed(t *testing.T) {
+	results := []*stubResult{
+		{
+			info: &athena.QueryExecution{
+				QueryExecutionId:    aws.String("TestPrinterStreamed_Select"),
+				Query:               aws.String("SELECT * FROM typed"),
+				Statistics:          testhelper.CreateStats(1234, 56789),
+				ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+			},
+			cols:  typedColumns,
+			data:  typedRows,
+			nulls: typedNulls,
+		},
+		{
+			info: &athena.QueryExecution{
+				QueryExecutionId:    aws.String("TestPrinterStreamed_ShowDatabases"),
+				Query:               aws.String("SHOW DATABASES"),
+				Statistics:          testhelper.CreateStats(123, 0),
+				ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+			},
+			data: [][]string{{"cloudfront_logs"}, {"sampledb"}},
+		},
+		{
+			info: &athena.QueryExecution{
+				QueryExecutionId:    aws.String("TestPrinterStreamed_CreateDatabase"),
+				Query:               aws.String("CREATE DATABASE test"),
+				Statistics:          testhelper.CreateStats(1234, 0),
+				ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+			},
+			data: [][]string{},
+		},
+		{
+			info: &athena.QueryExecution{
+				QueryExecutionId: aws.String("TestPrinterStreamed_Failed"),
+				Query:            aws.String("SELECT * FROM missing"),
+				Status: &athena.QueryExecutionStatus{
+					State:             aws.String(athena.QueryExecutionStateFailed),
+					StateChangeReason: aws.String("Table not found"),
+				},
+			},
+		},
+	}
+
+	// Streamed results are printed in the same way as buffered ones
+	for _, format := range Formats {
+		for _, r := range results {
+			var want, got bytes.Buffer
+			New(&want, format).Print(r)
+			New(&got, format).Print(&stubStreamResult{stubResult: r})
+
+			assert.Equal(t, want.String(), got.String(), "Format: %s, Result: %#v", format, r)
+		}
+	}
+}
+
+func TestTablePrinterPreview(t *testing.T) {
+	data := [][]string{{"n"}}
+	for i := 0; i <= maxPreviewRows; i++ {
+		data = append(data, []string{fmt.Sprintf("row%d", i)})
+	}
+	r := &stubStreamResult{stubResult: &stubResult{
+		info: &athena.QueryExecution{
+			Query:               aws.String("SELECT n FROM large"),
+			Statistics:          testhelper.CreateStats(1234, 0),
+			ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+		},
+		cols: []*athena.ColumnInfo{{Name: aws.String("n"), Type: aws.String("varchar")}},
+		data: data,
+	}}
+
+	var out bytes.Buffer
+	New(&out, FormatTable).Print(r)
+	got := out.String()
+
+	assert.Contains(t, got, fmt.Sprintf("| row%d ", maxPreviewRows-1))
+	assert.NotContains(t, got, fmt.Sprintf("| row%d ", maxPreviewRows))
+	assert.Contains(t, got, "(Showing only the first 1000 rows. Use csv or ndjson format to print all the rows)\n")
+	assert.Contains(t, got, "Run time: 1.23 seconds")
+
+	// All the rows are printed in CSV
+	out.Reset()
+	New(&out, FormatCSV).Print(r)
+	assert.Contains(t, out.String(), fmt.Sprintf("\nrow%d\n", maxPreviewRows))
+}
+
 func TestTablePrinter(t *testing.T) {
 	tests := []struct {
 		r    Result
@@ -170,6 +336,119 @@ func TestTablePrinter(t *testing.T) {
 	}
 }
 
+const typedTable = `
+Query: SELECT * FROM typed;
++------+-------+-------+
+| name | count | price |
+| 123  |    42 | 12.30 |
+| NULL |     7 |  NULL |
+|      |  NULL |  1.00 |
++------+-------+-------+
+`
+
+func TestTablePrinterTyped(t *testing.T) {
+	r := &stubResult{
+		info: &athena.QueryExecution{
+			QueryExecutionId:    aws.String("TestTablePrint_Typed"),
+			Query:               aws.String("SELECT * FROM typed"),
+			Statistics:          testhelper.CreateStats(1234, 0),
+			ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+		},
+		cols: []*athena.ColumnInfo{
+			{Name: aws.String("name"), Type: aws.String("varchar")},
+			{Name: aws.String("count"), Type: aws.String("bigint")},
+			{Name: aws.String("price"), Type: aws.String("decimal(10,2)")},
+		},
+		data: [][]string{
+			{"name", "count", "price"},
+			{"123", "42", "12.30"},
+			{"", "7", ""},
+			{"", "", "1.00"},
+		},
+		nulls: [][2]int{{2, 0}, {2, 2}, {3, 1}},
+	}
+
+	var out bytes.Buffer
+	out.WriteString("\n")
+
+	p := New(&out, "table")
+	p.Print(r)
+
+	assert.Contains(t, out.String(), typedTable, "Result: %#v", r)
+}
+
+func TestPrinterWithoutFooter(t *testing.T) {
+	r := &stubResult{
+		info: &athena.QueryExecution{
+			Query:               aws.String("SHOW DATABASES"),
+			Statistics:          testhelper.CreateStats(123, 0),
+			ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+		},
+		data: [][]string{{"sampledb"}},
+	}
+
+	var out bytes.Buffer
+	p := New(&out, "csv", WithoutFooter())
+	p.Print(r)
+
+	assert.Equal(t, "Query: SHOW DATABASES;\nsampledb\n", out.String())
+}
+
+func TestPrinterIncomplete(t *testing.T) {
+	submitted := time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC)
+	completed := time.Date(2017, 7, 1, 0, 0, 5, 0, time.UTC)
+	tests := []struct {
+		status *athena.QueryExecutionStatus
+		want   string
+	}{
+		{
+			status: &athena.QueryExecutionStatus{
+				State:              aws.String(athena.QueryExecutionStateFailed),
+				StateChangeReason:  aws.String("Query exhausted resources at this scale factor"),
+				SubmissionDateTime: &submitted,
+				CompletionDateTime: &completed,
+			},
+			want: "Query: SELECT *\nFROM elb_logs;\n" +
+				"State: FAILED\n" +
+				"Reason: Query exhausted resources at this scale factor\n" +
+				"Submitted: 2017-07-01 00:00:00 +0000 UTC\n" +
+				"Completed: 2017-07-01 00:00:05 +0000 UTC\n" +
+				"QueryExecutionId: TestPrinterIncomplete\n",
+		},
+		{
+			status: &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateCancelled)},
+			want:   "Query: SELECT *\nFROM elb_logs;\nState: CANCELLED\nQueryExecutionId: TestPrinterIncomplete\n",
+		},
+		{
+			status: &athena.QueryExecutionStatus{State: aws.String(athena.QueryExecutionStateSucceeded)},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		r := &stubResult{
+			info: &athena.QueryExecution{
+				QueryExecutionId: aws.String("TestPrinterIncomplete"),
+				Query:            aws.String("SELECT *\nFROM elb_logs"),
+				Status:           tt.status,
+			},
+		}
+
+		var out bytes.Buffer
+		New(&out, FormatTable).Print(r)
+
+		assert.Equal(t, tt.want, out.String(), "Status: %#v", tt.status)
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	for _, f := range Formats {
+		assert.True(t, IsValid(f), "Format: %s", f)
+	}
+	assert.False(t, IsValid("xml"))
+	assert.False(t, IsValid(""))
+}
+
 const (
 	showDatabasesCSV = `
 Query: SHOW DATABASES;
@@ -260,3 +539,40 @@ func TestCSVPrinter(t *testing.T) {
 		assert.Contains(t, out.String(), tt.want, "Result: %#v", tt.r)
 	}
 }
+
+func TestTSVPrinter(t *testing.T) {
+	r := &stubResult{
+		info: &athena.QueryExecution{
+			QueryExecutionId:    aws.String("TestTSVPrint_Select"),
+			Query:               aws.String("SELECT id, memo FROM notes"),
+			Statistics:          testhelper.CreateStats(1234, 56789),
+			ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+		},
+		cols: []*athena.ColumnInfo{
+			{Name: aws.String("id"), Type: aws.String("integer")},
+			{Name: aws.String("memo"), Type: aws.String("varchar")},
+		},
+		data: [][]string{
+			{"id", "memo"},
+			{"1", "a\tb"},
+			{"2", "line1\nline2"},
+			{"3", `C:\path`},
+			{"4", ""},
+		},
+		nulls: [][2]int{{4, 1}},
+	}
+	want := `Query: SELECT id, memo FROM notes;
+id	memo
+1	a\tb
+2	line1\nline2
+3	C:\\path
+4	
+Run time: 1.23 seconds | Data scanned: 56.79 KB
+Location: s3://samplebucket/
+`
+
+	var out bytes.Buffer
+	New(&out, FormatTSV).Print(r)
+
+	assert.Equal(t, want, out.String())
+}
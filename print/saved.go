@@ -0,0 +1,118 @@
+package print
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+)
+
+// savedQueryDocument is a JSON document which represents a saved (named) query.
+type savedQueryDocument struct {
+	NamedQueryID string `json:"NamedQueryId"`
+	Name         string `json:"Name"`
+	Database     string `json:"Database"`
+	Description  string `json:"Description,omitempty"`
+	QueryString  string `json:"QueryString"`
+}
+
+func newSavedQueryDocument(nq *athena.NamedQuery) *savedQueryDocument {
+	return &savedQueryDocument{
+		NamedQueryID: aws.StringValue(nq.NamedQueryId),
+		Name:         aws.StringValue(nq.Name),
+		Database:     aws.StringValue(nq.Database),
+		Description:  aws.StringValue(nq.Description),
+		QueryString:  aws.StringValue(nq.QueryString),
+	}
+}
+
+// PrintSavedQueries prints a list of saved queries nqs to out in format.
+// Queries themselves are omitted in table, vertical, markdown and html formats.
+func PrintSavedQueries(out io.Writer, format string, nqs []*athena.NamedQuery) error {
+	docs := make([]*savedQueryDocument, len(nqs))
+	for i, nq := range nqs {
+		docs[i] = newSavedQueryDocument(nq)
+	}
+
+	rows := func() [][]string {
+		rows := make([][]string, 0, len(docs)+1)
+		rows = append(rows, []string{"Name", "Database", "Description", "NamedQueryId"})
+		for _, doc := range docs {
+			rows = append(rows, []string{doc.Name, doc.Database, doc.Description, doc.NamedQueryID})
+		}
+		return rows
+	}
+
+	switch format {
+	case FormatTable, FormatAuto:
+		tw := tablewriter.NewWriter(out)
+		tw.AppendBulk(rows())
+		tw.Render()
+	case FormatVertical:
+		printVerticalRows(out, rows())
+	case FormatMarkdown:
+		printMarkdownRows(out, rows())
+	case FormatHTML:
+		printHTMLRows(out, rows())
+	case FormatCSV:
+		w := csv.NewWriter(out)
+		w.WriteAll(savedQueryRawRows(docs))
+		return errors.Wrap(w.Error(), "failed to write saved queries in CSV")
+	case FormatTSV:
+		for _, row := range savedQueryRawRows(docs) {
+			if err := writeTSVRow(out, row); err != nil {
+				return errors.Wrap(err, "failed to write saved queries in TSV")
+			}
+		}
+	case FormatJSON:
+		return errors.Wrap(json.NewEncoder(out).Encode(docs), "failed to encode saved queries into JSON")
+	case FormatNDJSON:
+		enc := json.NewEncoder(out)
+		for _, doc := range docs {
+			if err := enc.Encode(doc); err != nil {
+				return errors.Wrap(err, "failed to encode saved queries into JSON")
+			}
+		}
+	default:
+		return errors.Errorf("unknown format %q", format)
+	}
+	return nil
+}
+
+// savedQueryRawRows returns rows of docs including queries themselves to print in CSV and TSV formats,
+// whose first row is the header.
+func savedQueryRawRows(docs []*savedQueryDocument) [][]string {
+	rows := make([][]string, 0, len(docs)+1)
+	rows = append(rows, []string{"Name", "Database", "Description", "NamedQueryId", "QueryString"})
+	for _, doc := range docs {
+		rows = append(rows, []string{doc.Name, doc.Database, doc.Description, doc.NamedQueryID, doc.QueryString})
+	}
+	return rows
+}
+
+// PrintSavedQuery prints a saved query nq to out in format. In text formats, it is printed as
+// SQL with its name, database and description in comments, so that it can be run as it is.
+func PrintSavedQuery(out io.Writer, format string, nq *athena.NamedQuery) error {
+	doc := newSavedQueryDocument(nq)
+
+	switch format {
+	case FormatTable, FormatCSV, FormatVertical, FormatAuto, FormatMarkdown, FormatHTML, FormatTSV:
+		fmt.Fprintf(out, "-- Name: %s\n", doc.Name)
+		fmt.Fprintf(out, "-- Database: %s\n", doc.Database)
+		if doc.Description != "" {
+			fmt.Fprintf(out, "-- Description: %s\n", strings.Replace(doc.Description, "\n", "\n-- ", -1))
+		}
+		fmt.Fprintln(out, strings.TrimRight(doc.QueryString, "\n"))
+	case FormatJSON, FormatNDJSON:
+		return errors.Wrap(json.NewEncoder(out).Encode(doc), "failed to encode saved query into JSON")
+	default:
+		return errors.Errorf("unknown format %q", format)
+	}
+	return nil
+}
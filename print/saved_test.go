@@ -0,0 +1,147 @@
+package print
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSavedQueries = []*athena.NamedQuery{
+	{
+		NamedQueryId: aws.String("nq-1"),
+		Name:         aws.String("daily_requests"),
+		Database:     aws.String("sampledb"),
+		Description:  aws.String("Requests per day"),
+		QueryString:  aws.String("SELECT date, count(*)\nFROM cloudfront_logs\nGROUP BY date"),
+	},
+	{
+		NamedQueryId: aws.String("nq-2"),
+		Name:         aws.String("databases"),
+		Database:     aws.String("default"),
+		QueryString:  aws.String("SHOW DATABASES"),
+	},
+}
+
+func TestPrintSavedQueries(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatTable,
+			want: `+----------------+----------+------------------+--------------+
+| Name           | Database | Description      | NamedQueryId |
+| daily_requests | sampledb | Requests per day | nq-1         |
+| databases      | default  |                  | nq-2         |
++----------------+----------+------------------+--------------+
+`,
+		},
+		{
+			format: FormatCSV,
+			want: `Name,Database,Description,NamedQueryId,QueryString
+daily_requests,sampledb,Requests per day,nq-1,"SELECT date, count(*)
+FROM cloudfront_logs
+GROUP BY date"
+databases,default,,nq-2,SHOW DATABASES
+`,
+		},
+		{
+			format: FormatVertical,
+			want: `-[ RECORD 1 ]------------------
+Name         | daily_requests
+Database     | sampledb
+Description  | Requests per day
+NamedQueryId | nq-1
+-[ RECORD 2 ]-----------
+Name         | databases
+Database     | default
+Description  |
+NamedQueryId | nq-2
+`,
+		},
+		{
+			format: FormatTSV,
+			want: "Name\tDatabase\tDescription\tNamedQueryId\tQueryString\n" +
+				"daily_requests\tsampledb\tRequests per day\tnq-1\tSELECT date, count(*)\\nFROM cloudfront_logs\\nGROUP BY date\n" +
+				"databases\tdefault\t\tnq-2\tSHOW DATABASES\n",
+		},
+		{
+			format: FormatMarkdown,
+			want: `| Name | Database | Description | NamedQueryId |
+| --- | --- | --- | --- |
+| daily_requests | sampledb | Requests per day | nq-1 |
+| databases | default |  | nq-2 |
+`,
+		},
+		{
+			format: FormatNDJSON,
+			want: `{"NamedQueryId":"nq-1","Name":"daily_requests","Database":"sampledb","Description":"Requests per day","QueryString":"SELECT date, count(*)\nFROM cloudfront_logs\nGROUP BY date"}
+{"NamedQueryId":"nq-2","Name":"databases","Database":"default","QueryString":"SHOW DATABASES"}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := PrintSavedQueries(&out, tt.format, testSavedQueries)
+
+		assert.NoError(t, err, "Format: %s", tt.format)
+		assert.Equal(t, tt.want, out.String(), "Format: %s", tt.format)
+	}
+
+	err := PrintSavedQueries(&bytes.Buffer{}, "xml", testSavedQueries)
+	assert.Error(t, err)
+}
+
+func TestPrintSavedQuery(t *testing.T) {
+	tests := []struct {
+		format string
+		nq     *athena.NamedQuery
+		want   string
+	}{
+		{
+			format: FormatTable,
+			nq:     testSavedQueries[0],
+			want: `-- Name: daily_requests
+-- Database: sampledb
+-- Description: Requests per day
+SELECT date, count(*)
+FROM cloudfront_logs
+GROUP BY date
+`,
+		},
+		{
+			format: FormatCSV,
+			nq:     testSavedQueries[1],
+			want: `-- Name: databases
+-- Database: default
+SHOW DATABASES
+`,
+		},
+		{
+			format: FormatMarkdown,
+			nq:     testSavedQueries[1],
+			want: `-- Name: databases
+-- Database: default
+SHOW DATABASES
+`,
+		},
+		{
+			format: FormatJSON,
+			nq:     testSavedQueries[1],
+			want: `{"NamedQueryId":"nq-2","Name":"databases","Database":"default","QueryString":"SHOW DATABASES"}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := PrintSavedQuery(&out, tt.format, tt.nq)
+
+		assert.NoError(t, err, "Format: %s", tt.format)
+		assert.Equal(t, tt.want, out.String(), "Format: %s", tt.format)
+	}
+}
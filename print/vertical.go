@@ -0,0 +1,158 @@
+package print
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/skatsuta/athenai/exec"
+)
+
+// verticalWriter writes rows as records of `column | value` lines, like the expanded display
+// of psql:
+//
+//	-[ RECORD 1 ]-----
+//	id   | 1
+//	name | alice
+//
+// Since the width of the values is taken from each record, records can be written as they are read.
+type verticalWriter struct {
+	out   io.Writer
+	names []string
+	width int // Width of the widest name
+	n     int // Number of records written
+}
+
+func newVerticalWriter(out io.Writer, names []string) *verticalWriter {
+	vw := &verticalWriter{out: out, names: names}
+	for _, name := range names {
+		if w := runewidth.StringWidth(name); w > vw.width {
+			vw.width = w
+		}
+	}
+	return vw
+}
+
+// write writes values as a record. Values of multiple lines are indented after the first line.
+func (vw *verticalWriter) write(values []string) {
+	vw.n++
+
+	valueWidth := 0
+	for _, v := range values {
+		for _, line := range strings.Split(v, "\n") {
+			if w := runewidth.StringWidth(line); w > valueWidth {
+				valueWidth = w
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	title := fmt.Sprintf("-[ RECORD %d ]", vw.n)
+	buf.WriteString(title)
+	// Mark the separator between names and values with `+` if the title fits before it
+	if sep := vw.width + 1; len(title) < sep {
+		buf.WriteString(strings.Repeat("-", sep-len(title)) + "+" + strings.Repeat("-", valueWidth+1))
+	} else if rest := vw.width + 3 + valueWidth - len(title); rest > 0 {
+		buf.WriteString(strings.Repeat("-", rest))
+	}
+	buf.WriteByte('\n')
+
+	indent := strings.Repeat(" ", vw.width) + " | "
+	for j, v := range values {
+		name := ""
+		if j < len(vw.names) {
+			name = vw.names[j]
+		}
+		buf.WriteString(name + strings.Repeat(" ", vw.width-runewidth.StringWidth(name)) + " |")
+		if v != "" {
+			buf.WriteString(" " + strings.Replace(v, "\n", "\n"+indent, -1))
+		}
+		buf.WriteByte('\n')
+	}
+	vw.out.Write(buf.Bytes())
+}
+
+// printVertical prints the results as vertical records, writing each of them as it is read.
+// NULL is rendered as `NULL`.
+func printVertical(out io.Writer, r Result) int {
+	infos := r.ColumnInfo()
+	var vw *verticalWriter
+	eachRow(r, func(row []string, nulls []bool) bool {
+		if vw == nil {
+			cols := columns(infos, [][]string{row})
+			vw = newVerticalWriter(out, columnNames(cols))
+			if exec.IsHeader(cols, row) {
+				return true
+			}
+		}
+		vw.write(replaceNulls(row, nulls, nullString))
+		return true
+	})
+	if vw == nil {
+		return 0
+	}
+	return vw.n
+}
+
+// printAuto prints the results in tabular form, or as vertical records if the table would be
+// wider than width. Zero width means the width is unknown, and the table is always printed.
+func printAuto(out io.Writer, r Result, width int) int {
+	infos := r.ColumnInfo()
+	cols, rows, more := tableRows(r)
+	if len(rows) == 0 {
+		return 0
+	}
+
+	var table bytes.Buffer
+	renderTable(&table, cols, len(infos) > 0, rows)
+	if width <= 0 || maxLineWidth(table.String()) <= width {
+		table.WriteTo(out)
+		printPreviewNote(out, more)
+		return len(rows)
+	}
+
+	if len(infos) == 0 {
+		cols = columns(nil, rows[:1])
+	}
+	if exec.IsHeader(cols, rows[0]) {
+		rows = rows[1:]
+	}
+	vw := newVerticalWriter(out, columnNames(cols))
+	for _, row := range rows {
+		vw.write(row)
+	}
+	printPreviewNote(out, more)
+	return vw.n
+}
+
+// printVerticalRows prints rows whose first row is the header as vertical records.
+func printVerticalRows(out io.Writer, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	vw := newVerticalWriter(out, rows[0])
+	for _, row := range rows[1:] {
+		vw.write(row)
+	}
+}
+
+func columnNames(cols []exec.Column) []string {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// maxLineWidth returns the width of the widest line in s.
+func maxLineWidth(s string) int {
+	max := 0
+	for _, line := range strings.Split(s, "\n") {
+		if w := runewidth.StringWidth(line); w > max {
+			max = w
+		}
+	}
+	return max
+}
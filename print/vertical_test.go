@@ -0,0 +1,170 @@
+package print
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/skatsuta/athenai/internal/testhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+var typedResult = &stubResult{
+	info: &athena.QueryExecution{
+		QueryExecutionId:    aws.String("TestVerticalPrint_Select"),
+		Query:               aws.String("SELECT * FROM typed"),
+		Statistics:          testhelper.CreateStats(1234, 56789),
+		ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+	},
+	cols:  typedColumns,
+	data:  typedRows,
+	nulls: typedNulls,
+}
+
+const selectVertical = `Query: SELECT * FROM typed;
+-[ RECORD 1 ]------
+name   | foo
+count  | 42
+ratio  | 0.5
+price  | 12.30
+active | true
+day    | 2017-07-01
+-[ RECORD 2 ]-
+name   |
+count  | NULL
+ratio  | NaN
+price  | NULL
+active | false
+day    | NULL
+Run time: 1.23 seconds | Data scanned: 56.79 KB
+Location: s3://samplebucket/
+`
+
+func TestVerticalPrinter(t *testing.T) {
+	tests := []struct {
+		r    Result
+		want string
+	}{
+		{
+			r:    typedResult,
+			want: selectVertical,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestVerticalPrint_ShowDatabases"),
+					Query:               aws.String("SHOW DATABASES"),
+					Statistics:          testhelper.CreateStats(123, 0),
+					ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+				},
+				data: [][]string{{"cloudfront_logs"}, {"sampledb"}},
+			},
+			want: `Query: SHOW DATABASES;
+-[ RECORD 1 ]----------
+_col0 | cloudfront_logs
+-[ RECORD 2 ]---
+_col0 | sampledb
+Run time: 0.12 seconds | Data scanned: 0 B
+Location: s3://samplebucket/
+`,
+		},
+		{
+			r: &stubResult{
+				info: &athena.QueryExecution{
+					QueryExecutionId:    aws.String("TestVerticalPrint_Empty"),
+					Query:               aws.String("SELECT * FROM typed LIMIT 0"),
+					Statistics:          testhelper.CreateStats(1234, 0),
+					ResultConfiguration: testhelper.CreateResultConfig(outputLocation),
+				},
+				cols: typedColumns,
+				data: typedRows[:1],
+			},
+			want: `Query: SELECT * FROM typed LIMIT 0;
+(No output)
+Run time: 1.23 seconds | Data scanned: 0 B
+Location: s3://samplebucket/
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		New(&out, FormatVertical).Print(tt.r)
+
+		assert.Equal(t, tt.want, out.String(), "Result: %#v", tt.r)
+	}
+}
+
+func TestVerticalWriter(t *testing.T) {
+	tests := []struct {
+		names  []string
+		values []string
+		want   string
+	}{
+		{
+			names:  []string{"id", "description"},
+			values: []string{"1", "line1\nlonger line2"},
+			want: `-[ RECORD 1 ]-------------
+id          | 1
+description | line1
+            | longer line2
+`,
+		},
+		{
+			names:  []string{"a_long_column_name", "b"},
+			values: []string{"x", "yy"},
+			want: `-[ RECORD 1 ]------+---
+a_long_column_name | x
+b                  | yy
+`,
+		},
+		{
+			names:  []string{"名前"},
+			values: []string{"値"},
+			want: `-[ RECORD 1 ]
+名前 | 値
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		newVerticalWriter(&out, tt.names).write(tt.values)
+
+		assert.Equal(t, tt.want, out.String(), "Names: %q, Values: %q", tt.names, tt.values)
+	}
+}
+
+func TestAutoPrinter(t *testing.T) {
+	var table bytes.Buffer
+	New(&table, FormatTable).Print(typedResult)
+	tableWidth := 0
+	for _, line := range strings.Split(table.String(), "\n") {
+		if strings.HasPrefix(line, "+") && len(line) > tableWidth {
+			tableWidth = len(line)
+		}
+	}
+
+	tests := []struct {
+		width func() int
+		want  string
+	}{
+		{width: nil, want: table.String()},
+		{width: func() int { return 0 }, want: table.String()},
+		{width: func() int { return tableWidth }, want: table.String()},
+		{width: func() int { return tableWidth - 1 }, want: selectVertical},
+	}
+
+	for _, tt := range tests {
+		var opts []Option
+		if tt.width != nil {
+			opts = append(opts, WithWidth(tt.width))
+		}
+		var out bytes.Buffer
+		New(&out, FormatAuto, opts...).Print(typedResult)
+
+		assert.Equal(t, tt.want, out.String())
+	}
+}
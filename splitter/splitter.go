@@ -0,0 +1,411 @@
+// Package splitter splits SQL scripts into single statements.
+//
+// Unlike a naive split on semicolons, it understands single-quoted string literals (including
+// doubled quotes as escapes), double-quoted and backtick-quoted identifiers, `--` line comments and `/* */`
+// block comments, so semicolons inside any of them do not terminate a statement.
+package splitter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Position represents a position in an SQL source.
+type Position struct {
+	Source string // File name, or empty if the source is not a file
+	Line   int    // 1-based line number
+	Column int    // 1-based column number in characters
+}
+
+func (p Position) String() string {
+	if p.Source == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Source, p.Line, p.Column)
+}
+
+// Statement represents a single SQL statement.
+type Statement struct {
+	// Text is the statement without its leading comments and the terminating semicolon.
+	Text string
+	// Comments are the comments preceding the statement, including their delimiters.
+	Comments []string
+	// Pos is the position where Text starts.
+	Pos Position
+}
+
+func (s *Statement) String() string {
+	return s.Text
+}
+
+// SyntaxError represents an error that the source cannot be split into statements,
+// such as an unterminated string literal or block comment.
+type SyntaxError struct {
+	Pos Position
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// tokenKind represents a kind of a token.
+type tokenKind int
+
+const (
+	tokenText tokenKind = iota
+	tokenSpace
+	tokenString
+	tokenQuotedIdent
+	tokenBacktickIdent
+	tokenLineComment
+	tokenBlockComment
+	tokenSemicolon
+)
+
+// token is a lexical unit in an SQL source.
+type token struct {
+	kind tokenKind
+	text string
+	pos  Position
+}
+
+func (t *token) isComment() bool {
+	return t.kind == tokenLineComment || t.kind == tokenBlockComment
+}
+
+// lexer splits an SQL source into tokens.
+type lexer struct {
+	src  []rune
+	off  int
+	line int
+	col  int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: []rune(src), line: 1, col: 1}
+}
+
+func (l *lexer) peek(n int) rune {
+	if l.off+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.off+n]
+}
+
+func (l *lexer) eof() bool {
+	return l.off >= len(l.src)
+}
+
+// advance moves the offset forward by a rune and returns it.
+func (l *lexer) advance() rune {
+	r := l.src[l.off]
+	l.off++
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *lexer) pos() Position {
+	return Position{Line: l.line, Column: l.col}
+}
+
+// next returns the next token. It returns nil when it reaches the end of the source.
+func (l *lexer) next() (*token, error) {
+	if l.eof() {
+		return nil, nil
+	}
+
+	start := l.off
+	pos := l.pos()
+	tok := &token{pos: pos}
+
+	r := l.peek(0)
+	switch {
+	case r == ';':
+		l.advance()
+		tok.kind = tokenSemicolon
+	case isSpace(r):
+		for !l.eof() && isSpace(l.peek(0)) {
+			l.advance()
+		}
+		tok.kind = tokenSpace
+	case r == '\'':
+		if !l.quoted('\'') {
+			return nil, &SyntaxError{Pos: pos, Msg: "unterminated string literal"}
+		}
+		tok.kind = tokenString
+	case r == '"':
+		if !l.quoted('"') {
+			return nil, &SyntaxError{Pos: pos, Msg: "unterminated quoted identifier"}
+		}
+		tok.kind = tokenQuotedIdent
+	case r == '`':
+		if !l.quoted('`') {
+			return nil, &SyntaxError{Pos: pos, Msg: "unterminated backtick identifier"}
+		}
+		tok.kind = tokenBacktickIdent
+	case r == '-' && l.peek(1) == '-':
+		for !l.eof() && l.peek(0) != '\n' {
+			l.advance()
+		}
+		tok.kind = tokenLineComment
+	case r == '/' && l.peek(1) == '*':
+		l.advance()
+		l.advance()
+		for {
+			if l.eof() {
+				return nil, &SyntaxError{Pos: pos, Msg: "unterminated block comment"}
+			}
+			if l.peek(0) == '*' && l.peek(1) == '/' {
+				l.advance()
+				l.advance()
+				break
+			}
+			l.advance()
+		}
+		tok.kind = tokenBlockComment
+	default:
+		for !l.eof() && !l.isTextEnd() {
+			l.advance()
+		}
+		tok.kind = tokenText
+	}
+
+	tok.text = string(l.src[start:l.off])
+	return tok, nil
+}
+
+// quoted consumes a quoted string starting and ending with q. Two consecutive q's are treated as
+// an escaped q. It returns false if the closing quote is not found.
+func (l *lexer) quoted(q rune) bool {
+	l.advance() // Opening quote
+	for !l.eof() {
+		if l.advance() != q {
+			continue
+		}
+		if l.peek(0) == q {
+			l.advance() // Escaped quote
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// isTextEnd reports whether the current rune starts a token other than text.
+func (l *lexer) isTextEnd() bool {
+	r := l.peek(0)
+	switch {
+	case r == ';', r == '\'', r == '"', r == '`', isSpace(r):
+		return true
+	case r == '-' && l.peek(1) == '-':
+		return true
+	case r == '/' && l.peek(1) == '*':
+		return true
+	}
+	return false
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' || r == '\v'
+}
+
+// Split splits src into SQL statements separated by semicolons.
+// Statements which consist only of whitespaces and comments are dropped.
+func Split(src string) ([]*Statement, error) {
+	stmts := make([]*Statement, 0, 1)
+	l := newLexer(src)
+
+	var comments []string
+	var toks []*token
+	flush := func() {
+		if stmt := newStatement(comments, toks); stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+		comments = nil
+		toks = nil
+	}
+
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok == nil {
+			break
+		}
+
+		switch {
+		case tok.kind == tokenSemicolon:
+			flush()
+		case len(toks) == 0 && tok.kind == tokenSpace:
+			// Skip whitespaces before a statement
+		case len(toks) == 0 && tok.isComment():
+			comments = append(comments, tok.text)
+		default:
+			toks = append(toks, tok)
+		}
+	}
+	flush()
+
+	return stmts, nil
+}
+
+// newStatement creates a new Statement from its leading comments and tokens.
+// It returns nil if there are no tokens.
+func newStatement(comments []string, toks []*token) *Statement {
+	if len(toks) == 0 {
+		return nil
+	}
+
+	var b bytes.Buffer
+	for _, tok := range toks {
+		b.WriteString(tok.text)
+	}
+	return &Statement{
+		Text:     strings.TrimRightFunc(b.String(), isSpace),
+		Comments: comments,
+		Pos:      toks[0].pos,
+	}
+}
+
+// Terminated reports whether src ends with a semicolon which terminates a statement, ignoring
+// trailing whitespaces and comments. It returns false if src ends inside a string literal,
+// a quoted identifier or a block comment.
+func Terminated(src string) bool {
+	l := newLexer(src)
+	terminated := false
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return false
+		}
+		if tok == nil {
+			return terminated
+		}
+
+		switch {
+		case tok.kind == tokenSemicolon:
+			terminated = true
+		case tok.kind == tokenSpace, tok.isComment():
+			// Whitespaces and comments do not affect whether src is terminated
+		default:
+			terminated = false
+		}
+	}
+}
+
+// OneLine rewrites src into a single line keeping its meaning, so that it can be saved as
+// a line in a history file. Whitespaces including newlines are collapsed into a space and
+// line comments are rewritten into block comments. Newlines in string literals and quoted
+// identifiers are kept as they are.
+func OneLine(src string) string {
+	var b bytes.Buffer
+	l := newLexer(src)
+	for {
+		start := l.off
+		tok, err := l.next()
+		if err != nil {
+			// Keep the rest as it is
+			b.WriteString(string(l.src[start:]))
+			break
+		}
+		if tok == nil {
+			break
+		}
+
+		switch tok.kind {
+		case tokenSpace:
+			b.WriteByte(' ')
+		case tokenLineComment:
+			body := strings.Replace(strings.TrimPrefix(tok.text, "--"), "*/", "* /", -1)
+			b.WriteString("/*" + body + " */")
+		case tokenBlockComment:
+			b.WriteString(strings.Replace(tok.text, "\n", " ", -1))
+		default:
+			b.WriteString(tok.text)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// placeholderRegexp matches a placeholder `${name}` or `:name`.
+var placeholderRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|:([A-Za-z_][A-Za-z0-9_]*)`)
+
+// UndefinedError represents an error that placeholders refer to undefined variables.
+type UndefinedError struct {
+	Names []string // Names of the undefined variables in order of appearance
+}
+
+func (e *UndefinedError) Error() string {
+	return "undefined variables: " + strings.Join(e.Names, ", ")
+}
+
+// Expand replaces placeholders `${name}` and `:name` in src with the values returned by lookup.
+// Placeholders in string literals, quoted identifiers and comments are left as they are,
+// and so is `:name` following a letter, a digit, an underscore or another colon such as
+// `struct<a:int>`. If lookup returns false for any of them, Expand returns an *UndefinedError
+// listing all of them.
+func Expand(src string, lookup func(name string) (string, bool)) (string, error) {
+	var b bytes.Buffer
+	var undefined []string
+	seen := make(map[string]bool)
+
+	l := newLexer(src)
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return "", err
+		}
+		if tok == nil {
+			break
+		}
+		if tok.kind != tokenText {
+			b.WriteString(tok.text)
+			continue
+		}
+
+		text, last := tok.text, 0
+		for _, m := range placeholderRegexp.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			name := ""
+			if m[2] >= 0 {
+				name = text[m[2]:m[3]]
+			} else {
+				if start > 0 && isWordOrColon(text[start-1]) {
+					continue
+				}
+				name = text[m[4]:m[5]]
+			}
+
+			value, ok := lookup(name)
+			if !ok {
+				if !seen[name] {
+					seen[name] = true
+					undefined = append(undefined, name)
+				}
+				continue
+			}
+			b.WriteString(text[last:start])
+			b.WriteString(value)
+			last = end
+		}
+		b.WriteString(text[last:])
+	}
+
+	if len(undefined) > 0 {
+		return "", &UndefinedError{Names: undefined}
+	}
+	return b.String(), nil
+}
+
+func isWordOrColon(c byte) bool {
+	return c == '_' || c == ':' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
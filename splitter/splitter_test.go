@@ -0,0 +1,216 @@
+package splitter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"", []string{}},
+		{";", []string{}},
+		{"; ; \n \t \r   ;", []string{}},
+		{"   ; SELECT;   ; ", []string{"SELECT"}},
+		{"SELECT 1; SHOW TABLES", []string{"SELECT 1", "SHOW TABLES"}},
+		{
+			"SELECT * FROM logs WHERE msg LIKE '%;%'; SHOW TABLES;",
+			[]string{"SELECT * FROM logs WHERE msg LIKE '%;%'", "SHOW TABLES"},
+		},
+		{
+			"SELECT 'it''s; ok' AS s;",
+			[]string{"SELECT 'it''s; ok' AS s"},
+		},
+		{
+			`SELECT "col;umn", ` + "`tab;le`" + ` FROM t;`,
+			[]string{`SELECT "col;umn", ` + "`tab;le`" + ` FROM t`},
+		},
+		{
+			"SELECT 1 -- comment; not a separator\n;SELECT 2",
+			[]string{"SELECT 1 -- comment; not a separator", "SELECT 2"},
+		},
+		{
+			"SELECT /* a; b */ 1; /* c; d */",
+			[]string{"SELECT /* a; b */ 1"},
+		},
+		{
+			"-- only a comment;\n/* and another; */",
+			[]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		stmts, err := Split(tt.src)
+		got := make([]string, len(stmts))
+		for i, stmt := range stmts {
+			got[i] = stmt.Text
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, "Src: %q", tt.src)
+	}
+}
+
+func TestSplitComments(t *testing.T) {
+	src := `-- @barrier
+/* block */
+SELECT 1;
+SELECT 2 -- trailing
+`
+	stmts, err := Split(src)
+
+	assert.NoError(t, err)
+	if assert.Len(t, stmts, 2) {
+		assert.Equal(t, []string{"-- @barrier", "/* block */"}, stmts[0].Comments)
+		assert.Equal(t, "SELECT 1", stmts[0].Text)
+		assert.Empty(t, stmts[1].Comments)
+		assert.Equal(t, "SELECT 2 -- trailing", stmts[1].Text)
+	}
+}
+
+func TestSplitPosition(t *testing.T) {
+	src := "SELECT 1;\n\n  SELECT 'a\nb';   SHOW\nTABLES"
+	want := []Position{
+		{Line: 1, Column: 1},
+		{Line: 3, Column: 3},
+		{Line: 4, Column: 7},
+	}
+
+	stmts, err := Split(src)
+	got := make([]Position, len(stmts))
+	for i, stmt := range stmts {
+		got[i] = stmt.Pos
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, want, got, "Src: %q", src)
+}
+
+func TestSplitError(t *testing.T) {
+	tests := []struct {
+		src  string
+		pos  Position
+		want string
+	}{
+		{"SELECT 'abc", Position{Line: 1, Column: 8}, "unterminated string literal"},
+		{"SELECT 1;\nSELECT \"abc", Position{Line: 2, Column: 8}, "unterminated quoted identifier"},
+		{"SELECT `abc", Position{Line: 1, Column: 8}, "unterminated backtick identifier"},
+		{"SELECT 1 /* abc;", Position{Line: 1, Column: 10}, "unterminated block comment"},
+	}
+
+	for _, tt := range tests {
+		_, err := Split(tt.src)
+
+		if assert.Error(t, err) {
+			serr, ok := err.(*SyntaxError)
+			if assert.True(t, ok, "Src: %q", tt.src) {
+				assert.Equal(t, tt.pos, serr.Pos, "Src: %q", tt.src)
+				assert.Contains(t, serr.Error(), tt.want, "Src: %q", tt.src)
+			}
+		}
+	}
+}
+
+func TestPositionString(t *testing.T) {
+	tests := []struct {
+		pos  Position
+		want string
+	}{
+		{Position{Line: 1, Column: 2}, "1:2"},
+		{Position{Source: "sample.sql", Line: 3, Column: 4}, "sample.sql:3:4"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.pos.String(), "Position: %#v", tt.pos)
+	}
+}
+
+func TestTerminated(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"", false},
+		{"SELECT 1", false},
+		{"SELECT 1;", true},
+		{"SELECT 1 ;  \n", true},
+		{"SELECT 1; -- done", true},
+		{"SELECT 1; /* done */", true},
+		{"SELECT ';", false},
+		{"SELECT ';'", false},
+		{"SELECT ';';", true},
+		{"SELECT 1 /* ; */", false},
+		{"SELECT 1 /* ;", false},
+		{"SELECT 1 -- ;", false},
+		{"SELECT 1; SELECT 2", false},
+		{";", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Terminated(tt.src), "Source: %q", tt.src)
+	}
+}
+
+func TestOneLine(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"SELECT 1;", "SELECT 1;"},
+		{"SELECT *\n  FROM t\n  WHERE x = 1;\n", "SELECT * FROM t WHERE x = 1;"},
+		{"SELECT 1 -- one\n, 2;", "SELECT 1 /* one */ , 2;"},
+		{"SELECT 'a\nb';", "SELECT 'a\nb';"},
+		{"/* multi\nline */ SELECT 1;", "/* multi line */ SELECT 1;"},
+		{"SELECT 'unterminated\nstring", "SELECT 'unterminated\nstring"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, OneLine(tt.src), "Source: %q", tt.src)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"table": "elb_logs", "day": "DATE '2017-07-01'", "n": "10"}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM ${table} LIMIT :n", "SELECT * FROM elb_logs LIMIT 10"},
+		{"SELECT * FROM t WHERE day=:day AND x IN (:n,${n})", "SELECT * FROM t WHERE day=DATE '2017-07-01' AND x IN (10,10)"},
+		{"SELECT ':n', \"${n}\", `:n` -- :n\n/* ${n} */", "SELECT ':n', \"${n}\", `:n` -- :n\n/* ${n} */"},
+		{"CREATE TABLE t (s struct<a:int, b::n>)", "CREATE TABLE t (s struct<a:int, b::n>)"},
+		{"SELECT ${1st}, $n", "SELECT ${1st}, $n"},
+	}
+
+	for _, tt := range tests {
+		got, err := Expand(tt.src, lookup)
+
+		assert.NoError(t, err, "Src: %q", tt.src)
+		assert.Equal(t, tt.want, got, "Src: %q", tt.src)
+	}
+}
+
+func TestExpandError(t *testing.T) {
+	lookup := func(name string) (string, bool) { return "", name == "n" }
+
+	_, err := Expand("SELECT :a, ${b}, :n, :a", lookup)
+	if assert.Error(t, err) {
+		uerr, ok := err.(*UndefinedError)
+		if assert.True(t, ok) {
+			assert.Equal(t, []string{"a", "b"}, uerr.Names)
+			assert.Equal(t, "undefined variables: a, b", uerr.Error())
+		}
+	}
+
+	_, err = Expand("SELECT ':n", lookup)
+	assert.IsType(t, &SyntaxError{}, err)
+}